@@ -0,0 +1,691 @@
+package beadslite
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIServer serves a Store as a JSON HTTP API for editor plugins and
+// dashboards. Issues are returned as IssueExport records. Every issue
+// response carries an ETag holding the issue's version, which every write to
+// the issue bumps. Sending it back in If-Match (or the updated_at in the
+// body) makes a change fail with 412 (or 409) if someone else changed the
+// issue in between; PATCH also takes the version itself as if_version.
+//
+//	GET    /issues                       list, filtered by status, priority, type, resolution, q
+//	POST   /issues                       create
+//	GET    /issues/{id}                  show
+//	PATCH  /issues/{id}                  update the given fields
+//	POST   /issues/{id}/close            close, with an optional resolution
+//	POST   /issues/{id}/reopen           reopen
+//	GET    /issues/{id}/dependencies     list blockers
+//	POST   /issues/{id}/dependencies     add a blocker
+//	DELETE /issues/{id}/dependencies/{blocker}  remove a blocker
+//	GET    /ready                        unblocked work, filtered by priority and type
+//	GET    /events                       Server-Sent Events stream of changes, from any process
+//	GET    /export                       JSONL export, with bl export's filters
+//	POST   /import                       JSONL import, with remap and continue_on_error
+type APIServer struct {
+	store *Store
+	mux   *http.ServeMux
+
+	// writeMu serializes changes, so that checking a precondition and
+	// applying the change cannot interleave with another request's change.
+	writeMu sync.Mutex
+
+	// AfterChange, if set, runs after every request that modifies the
+	// store. bl serve uses it to keep sync.auto exports current.
+	AfterChange func() error
+}
+
+// NewAPIServer returns an HTTP handler for store's API.
+func NewAPIServer(store *Store) *APIServer {
+	s := &APIServer{store: store, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /issues", s.listIssues)
+	s.mux.HandleFunc("POST /issues", s.change(s.createIssue))
+	s.mux.HandleFunc("GET /issues/{id}", s.showIssue)
+	s.mux.HandleFunc("PATCH /issues/{id}", s.change(s.updateIssue))
+	s.mux.HandleFunc("POST /issues/{id}/close", s.change(s.closeIssue))
+	s.mux.HandleFunc("POST /issues/{id}/reopen", s.change(s.reopenIssue))
+	s.mux.HandleFunc("GET /issues/{id}/dependencies", s.listDependencies)
+	s.mux.HandleFunc("POST /issues/{id}/dependencies", s.change(s.addDependency))
+	s.mux.HandleFunc("DELETE /issues/{id}/dependencies/{blocker}", s.change(s.removeDependency))
+	s.mux.HandleFunc("GET /ready", s.readyWork)
+	s.mux.HandleFunc("GET /events", s.events)
+	s.mux.HandleFunc("GET /export", s.export)
+	s.mux.HandleFunc("POST /import", s.change(s.importIssues))
+	return s
+}
+
+func (s *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// apiError is an error with the HTTP status it should be reported with.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+func (e *apiError) Unwrap() error { return e.err }
+
+// badRequest marks err as the client's fault.
+func badRequest(err error) error {
+	return &apiError{status: http.StatusBadRequest, err: err}
+}
+
+// errorStatus picks the HTTP status for an error from the store.
+func errorStatus(err error) int {
+	var apiErr *apiError
+	var ambiguous *AmbiguousIDError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.status
+	case errors.Is(err, ErrIssueNotFound), errors.Is(err, ErrWorkspaceNotFound):
+		return http.StatusNotFound
+	case errors.As(err, &ambiguous), errors.Is(err, ErrSelfDependency), errors.Is(err, ErrDependencyExists):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidTransition), errors.Is(err, ErrOpenBlockers), errors.Is(err, ErrAlreadyClaimed),
+		errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError reports err as a JSON {"error": "..."} body.
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, errorStatus(err), map[string]string{"error": err.Error()})
+}
+
+// change wraps a handler that modifies the store: it runs under writeMu,
+// reports errors, and runs AfterChange if the handler succeeded.
+func (s *APIServer) change(handler func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.writeMu.Lock()
+		defer s.writeMu.Unlock()
+
+		// Buffer the response so that an AfterChange failure can still be
+		// reported instead of the handler's success.
+		rec := &bufferedResponse{header: w.Header()}
+		if err := handler(rec, r); err != nil {
+			writeError(w, err)
+			return
+		}
+		if s.AfterChange != nil {
+			if err := s.AfterChange(); err != nil {
+				writeError(w, err)
+				return
+			}
+		}
+		rec.flush(w)
+	}
+}
+
+// bufferedResponse holds a response until it is known to be final.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   strings.Builder
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) flush(w http.ResponseWriter) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	io.WriteString(w, b.body.String())
+}
+
+// etag returns the entity tag for an issue's current state, which changes
+// with every write to it.
+func etag(issue *Issue) string {
+	return `"` + strconv.FormatInt(issue.Version, 10) + `"`
+}
+
+// checkPreconditions fails the request if the client's view of issue is
+// stale: If-Match must list the ETag of the issue's current version (or be
+// "*"), and an updated_at given in the body must equal the stored one. The
+// change itself is then pinned to that version by pinnedVersion.
+func checkPreconditions(r *http.Request, issue *Issue, updatedAt *time.Time) error {
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		current := etag(issue)
+		matched := false
+		for _, tag := range strings.Split(ifMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == current {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &apiError{status: http.StatusPreconditionFailed,
+				err: fmt.Errorf("issue %s was modified: ETag is %s", issue.ID, current)}
+		}
+	}
+	if updatedAt != nil && !updatedAt.Equal(issue.UpdatedAt) {
+		return &apiError{status: http.StatusConflict,
+			err: fmt.Errorf("issue %s was modified at %s", issue.ID, issue.UpdatedAt.UTC().Format(time.RFC3339Nano))}
+	}
+	return nil
+}
+
+// pinnedVersion returns the version a change must still find the issue at:
+// the one its preconditions were just checked against, so that a write by
+// another process in between fails too, or 0 if the request had none.
+func pinnedVersion(r *http.Request, issue *Issue, updatedAt *time.Time) int64 {
+	if r.Header.Get("If-Match") == "" && updatedAt == nil {
+		return 0
+	}
+	return issue.Version
+}
+
+// staleVersion reports a change that lost a race with another writer the
+// way checkPreconditions would have reported it, had it run a moment later.
+func staleVersion(r *http.Request, err error) error {
+	if errors.Is(err, ErrConflict) && r.Header.Get("If-Match") != "" {
+		return &apiError{status: http.StatusPreconditionFailed, err: err}
+	}
+	return err
+}
+
+// decodeBody decodes a JSON request body into v, rejecting unknown fields.
+// An empty body leaves v unchanged.
+func decodeBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		return badRequest(fmt.Errorf("invalid request body: %w", err))
+	}
+	return nil
+}
+
+// resolve resolves the {id} path value, which may be a partial ID.
+func (s *APIServer) resolve(r *http.Request) (*Issue, error) {
+	ref := r.PathValue("id")
+	id, err := s.store.ResolveID(ref)
+	if err != nil {
+		return nil, fmt.Errorf("issue %s: %w", ref, err)
+	}
+	issue, err := s.store.GetIssue(id)
+	if err != nil {
+		return nil, fmt.Errorf("issue %s: %w", id, err)
+	}
+	return issue, nil
+}
+
+// writeIssue responds with an issue, its blockers, and its ETag.
+func (s *APIServer) writeIssue(w http.ResponseWriter, status int, id string) error {
+	issue, err := s.store.GetIssue(id)
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", id, err)
+	}
+	deps, err := s.store.GetDependencies(id)
+	if err != nil {
+		return fmt.Errorf("get dependencies: %w", err)
+	}
+	w.Header().Set("ETag", etag(issue))
+	writeJSON(w, status, toIssueExport(issue, deps))
+	return nil
+}
+
+// writeIssues responds with a JSON array of issues and their blockers.
+func (s *APIServer) writeIssues(w http.ResponseWriter, issues []*Issue) {
+	allDeps, err := s.store.GetAllDependencies()
+	if err != nil {
+		writeError(w, fmt.Errorf("get all dependencies: %w", err))
+		return
+	}
+	exports := make([]IssueExport, len(issues))
+	for i, issue := range issues {
+		exports[i] = toIssueExport(issue, allDeps[issue.ID])
+	}
+	writeJSON(w, http.StatusOK, exports)
+}
+
+// queryFilters reads list filters from the query string and validates them
+// against the workspace vocabulary.
+func (s *APIServer) queryFilters(query url.Values) (IssueFilter, error) {
+	filter := IssueFilter{
+		Status:     Status(query.Get("status")),
+		Type:       IssueType(query.Get("type")),
+		Resolution: Resolution(query.Get("resolution")),
+		Query:      query.Get("q"),
+	}
+	if p := query.Get("priority"); p != "" {
+		priority, err := strconv.Atoi(p)
+		if err != nil || priority < 0 {
+			return IssueFilter{}, badRequest(fmt.Errorf("invalid priority: %q (valid: 0-4)", p))
+		}
+		filter.Priority = &priority
+	}
+	vocab, err := s.store.Vocabulary()
+	if err != nil {
+		return IssueFilter{}, err
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return IssueFilter{}, badRequest(err)
+	}
+	return filter, nil
+}
+
+func (s *APIServer) listIssues(w http.ResponseWriter, r *http.Request) {
+	filter, err := s.queryFilters(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	issues, err := s.store.FindIssues(filter)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to list issues: %w", err))
+		return
+	}
+	s.writeIssues(w, issues)
+}
+
+func (s *APIServer) readyWork(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Has("status") || query.Has("resolution") {
+		writeError(w, badRequest(errors.New("ready work cannot be filtered by status or resolution")))
+		return
+	}
+	filter, err := s.queryFilters(query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	issues, err := s.store.ReadyIssues(filter)
+	if err != nil {
+		writeError(w, fmt.Errorf("failed to get ready work: %w", err))
+		return
+	}
+	s.writeIssues(w, issues)
+}
+
+func (s *APIServer) showIssue(w http.ResponseWriter, r *http.Request) {
+	issue, err := s.resolve(r)
+	if err == nil {
+		err = s.writeIssue(w, http.StatusOK, issue.ID)
+	}
+	if err != nil {
+		writeError(w, err)
+	}
+}
+
+func (s *APIServer) createIssue(w http.ResponseWriter, r *http.Request) error {
+	var req CreateRequest
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	if err := s.validate(IssueFilter{Type: req.Type, Priority: req.Priority}); err != nil {
+		return err
+	}
+	if strings.TrimSpace(req.Title) == "" {
+		return badRequest(errors.New("title is required"))
+	}
+
+	issue, err := Create(s.store, req)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", "/issues/"+issue.ID)
+	return s.writeIssue(w, http.StatusCreated, issue.ID)
+}
+
+func (s *APIServer) updateIssue(w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		UpdateRequest
+		UpdatedAt *time.Time `json:"updated_at"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	issue, err := s.resolve(r)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, issue, req.UpdatedAt); err != nil {
+		return err
+	}
+	if version := pinnedVersion(r, issue, req.UpdatedAt); req.IfVersion == nil && version != 0 {
+		req.IfVersion = &version
+	}
+
+	if err := s.validate(IssueFilter{Status: req.Status, Type: req.Type, Priority: req.Priority}); err != nil {
+		return err
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		return badRequest(errors.New("title cannot be empty"))
+	}
+	if req.IfVersion != nil && *req.IfVersion < 1 {
+		return badRequest(fmt.Errorf("invalid if_version %d: versions start at 1", *req.IfVersion))
+	}
+
+	if _, err := Update(s.store, issue.ID, req.UpdateRequest); err != nil {
+		return staleVersion(r, err)
+	}
+	return s.writeIssue(w, http.StatusOK, issue.ID)
+}
+
+// validate checks request values against the workspace vocabulary, so that
+// bad input is reported as a client error.
+func (s *APIServer) validate(filter IssueFilter) error {
+	vocab, err := s.store.Vocabulary()
+	if err != nil {
+		return err
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return badRequest(err)
+	}
+	return nil
+}
+
+func (s *APIServer) closeIssue(w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		Resolution Resolution `json:"resolution"`
+		UpdatedAt  *time.Time `json:"updated_at"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	issue, err := s.resolve(r)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, issue, req.UpdatedAt); err != nil {
+		return err
+	}
+
+	resolution := req.Resolution
+	if resolution == "" {
+		settings, err := s.store.Settings()
+		if err != nil {
+			return err
+		}
+		resolution = settings.DefaultResolution
+	} else if !resolution.Valid() {
+		return badRequest(fmt.Errorf("invalid resolution: %q (must be done, wontfix, or duplicate)", resolution))
+	}
+
+	if err := s.store.closeIssue(r.Context(), issue.ID, resolution, pinnedVersion(r, issue, req.UpdatedAt)); err != nil {
+		return staleVersion(r, fmt.Errorf("failed to close: %w", err))
+	}
+	return s.writeIssue(w, http.StatusOK, issue.ID)
+}
+
+func (s *APIServer) reopenIssue(w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		UpdatedAt *time.Time `json:"updated_at"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	issue, err := s.resolve(r)
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(r, issue, req.UpdatedAt); err != nil {
+		return err
+	}
+
+	if err := s.store.reopenIssue(r.Context(), issue.ID, pinnedVersion(r, issue, req.UpdatedAt)); err != nil {
+		return staleVersion(r, fmt.Errorf("failed to reopen: %w", err))
+	}
+	return s.writeIssue(w, http.StatusOK, issue.ID)
+}
+
+// dependencyExports returns an issue's dependencies in export form.
+func (s *APIServer) dependencyExports(id string) ([]DependencyExport, error) {
+	deps, err := s.store.GetDependencies(id)
+	if err != nil {
+		return nil, fmt.Errorf("get dependencies: %w", err)
+	}
+	return toIssueExport(&Issue{}, deps).Dependencies, nil
+}
+
+func (s *APIServer) listDependencies(w http.ResponseWriter, r *http.Request) {
+	issue, err := s.resolve(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	deps, err := s.dependencyExports(issue.ID)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+func (s *APIServer) addDependency(w http.ResponseWriter, r *http.Request) error {
+	var req struct {
+		DependsOn string `json:"depends_on"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		return err
+	}
+	if req.DependsOn == "" {
+		return badRequest(errors.New("depends_on is required"))
+	}
+	issue, err := s.resolve(r)
+	if err != nil {
+		return err
+	}
+	if err := s.store.AddBlocker(issue.ID, req.DependsOn); err != nil {
+		return err
+	}
+
+	deps, err := s.dependencyExports(issue.ID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, deps)
+	return nil
+}
+
+func (s *APIServer) removeDependency(w http.ResponseWriter, r *http.Request) error {
+	issue, err := s.resolve(r)
+	if err != nil {
+		return err
+	}
+	if err := s.store.RemoveBlocker(issue.ID, r.PathValue("blocker")); err != nil {
+		return err
+	}
+
+	deps, err := s.dependencyExports(issue.ID)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, deps)
+	return nil
+}
+
+// sseHeartbeat is how often /events writes a comment to an idle stream, so
+// that proxies and clients do not time it out.
+const sseHeartbeat = 15 * time.Second
+
+// events streams changes to the store as Server-Sent Events named by event
+// type, each carrying the Event as JSON, until the client disconnects.
+func (s *APIServer) events(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	watcher, err := s.store.NewWatcher(ctx)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	defer watcher.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, ": watching\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(watchInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			io.WriteString(w, ": ping\n\n")
+		case <-poll.C:
+			events, err := watcher.Poll(ctx)
+			if err != nil {
+				return
+			}
+			for _, event := range events {
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *APIServer) export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := s.queryFilters(query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	priority := -1
+	if filter.Priority != nil {
+		priority = *filter.Priority
+	}
+	opts := ExportOptions{
+		Status:       string(filter.Status),
+		Type:         string(filter.Type),
+		Priority:     priority,
+		Resolution:   string(filter.Resolution),
+		IDs:          query["id"],
+		WithBlockers: queryBool(query, "with_blockers"),
+		IncludeTrash: queryBool(query, "include_trash"),
+		Header:       queryBool(query, "header"),
+	}
+	if since := query.Get("since"); since != "" {
+		if opts.Since, err = parseTimeOrAge(since, time.Now()); err != nil {
+			writeError(w, badRequest(fmt.Errorf("invalid since: %w", err)))
+			return
+		}
+	}
+
+	// Export into a buffer so a failure can still be reported as an error
+	var buf strings.Builder
+	if err := ExportToJSONLContext(r.Context(), s.store, &buf, opts); err != nil {
+		writeError(w, fmt.Errorf("export failed: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	io.WriteString(w, buf.String())
+}
+
+// importResult is the response to POST /import.
+type importResult struct {
+	Created    int                `json:"created"`
+	Updated    int                `json:"updated"`
+	Collisions []importCollision  `json:"collisions"`
+	Rejected   []importRejectLine `json:"rejected"`
+}
+
+type importCollision struct {
+	OldID string `json:"old_id"`
+	NewID string `json:"new_id"`
+	Title string `json:"title"`
+}
+
+type importRejectLine struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+func (s *APIServer) importIssues(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	opts := ImportOptions{
+		RemapCollisions: queryBool(query, "remap"),
+		ContinueOnError: queryBool(query, "continue_on_error"),
+	}
+	stats, err := ImportFromJSONLContext(r.Context(), s.store, r.Body, opts)
+	if err != nil {
+		// Only a problem with the input is the client's fault
+		var rejected *ImportError
+		if errors.As(err, &rejected) {
+			return badRequest(fmt.Errorf("import failed: %w", err))
+		}
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	result := importResult{
+		Created:    stats.Created,
+		Updated:    stats.Updated,
+		Collisions: make([]importCollision, len(stats.Collisions)),
+		Rejected:   make([]importRejectLine, len(stats.Rejected)),
+	}
+	for i, c := range stats.Collisions {
+		result.Collisions[i] = importCollision{OldID: c.OldID, NewID: c.NewID, Title: c.Title}
+	}
+	for i, e := range stats.Rejected {
+		result.Rejected[i] = importRejectLine{Line: e.Line, ID: e.ID, Error: e.Err.Error()}
+	}
+	writeJSON(w, http.StatusOK, result)
+	return nil
+}
+
+// queryBool reports whether a boolean query parameter is set: present with
+// no value, or with a value strconv.ParseBool accepts as true.
+func queryBool(query url.Values, name string) bool {
+	if !query.Has(name) {
+		return false
+	}
+	value := query.Get(name)
+	if value == "" {
+		return true
+	}
+	b, _ := strconv.ParseBool(value)
+	return b
+}
@@ -0,0 +1,516 @@
+package beadslite
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// apiClient sends requests to an APIServer running under httptest.
+type apiClient struct {
+	t   *testing.T
+	srv *httptest.Server
+}
+
+// newTestAPI starts an APIServer for a fresh store.
+func newTestAPI(t *testing.T) (*Store, *APIServer, *apiClient) {
+	t.Helper()
+	return newTestAPIFor(t, newTestStore(t))
+}
+
+// newTestAPIFor starts an APIServer for store.
+func newTestAPIFor(t *testing.T, store *Store) (*Store, *APIServer, *apiClient) {
+	t.Helper()
+	api := NewAPIServer(store)
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	return store, api, &apiClient{t: t, srv: srv}
+}
+
+// do sends a request with an optional body and headers ("Name", "value"
+// pairs) and returns the response with its body read.
+func (c *apiClient) do(method, path, body string, headers ...string) (*http.Response, string) {
+	c.t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.srv.URL+path, reader)
+	if err != nil {
+		c.t.Fatalf("new request: %v", err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := c.srv.Client().Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("read body: %v", err)
+	}
+	return resp, string(data)
+}
+
+// issue sends a request expecting status and decodes the issue in the body.
+func (c *apiClient) issue(status int, method, path, body string, headers ...string) (IssueExport, *http.Response) {
+	c.t.Helper()
+	resp, data := c.do(method, path, body, headers...)
+	if resp.StatusCode != status {
+		c.t.Fatalf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	var issue IssueExport
+	if err := json.Unmarshal([]byte(data), &issue); err != nil {
+		c.t.Fatalf("decode issue %s: %v", data, err)
+	}
+	if resp.Header.Get("ETag") == "" {
+		c.t.Errorf("%s %s: issue response without ETag", method, path)
+	}
+	return issue, resp
+}
+
+// ids sends a GET expecting a JSON array of issues and returns their IDs.
+func (c *apiClient) ids(path string) []string {
+	c.t.Helper()
+	resp, data := c.do("GET", path, "")
+	if resp.StatusCode != http.StatusOK {
+		c.t.Fatalf("GET %s: status %d: %s", path, resp.StatusCode, data)
+	}
+	var issues []IssueExport
+	if err := json.Unmarshal([]byte(data), &issues); err != nil {
+		c.t.Fatalf("decode issues %s: %v", data, err)
+	}
+	if issues == nil {
+		c.t.Errorf("GET %s should return an array, got %s", path, data)
+	}
+	ids := make([]string, len(issues))
+	for i, issue := range issues {
+		ids[i] = issue.ID
+	}
+	return ids
+}
+
+// expectStatus sends a request and checks only its status.
+func (c *apiClient) expectStatus(status int, method, path, body string, headers ...string) string {
+	c.t.Helper()
+	resp, data := c.do(method, path, body, headers...)
+	if resp.StatusCode != status {
+		c.t.Errorf("%s %s: status %d, want %d: %s", method, path, resp.StatusCode, status, data)
+	}
+	return data
+}
+
+func TestAPI_CreateShowList(t *testing.T) {
+	_, _, client := newTestAPI(t)
+
+	created, resp := client.issue(http.StatusCreated, "POST", "/issues",
+		`{"title":"Fix login bug","description":"Session expires","priority":1,"issue_type":"bug"}`)
+	if created.Title != "Fix login bug" || created.Priority != 1 || created.Type != IssueTypeBug || created.Status != StatusOpen {
+		t.Errorf("unexpected created issue: %+v", created)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/issues/"+created.ID {
+		t.Errorf("Location = %q", loc)
+	}
+	other, _ := client.issue(http.StatusCreated, "POST", "/issues", `{"title":"Write docs"}`)
+	if other.Priority != 2 || other.Type != IssueTypeTask {
+		t.Errorf("defaults should come from settings: %+v", other)
+	}
+
+	shown, resp := client.issue(http.StatusOK, "GET", "/issues/"+strings.TrimPrefix(created.ID, "bl-"), "")
+	if shown.ID != created.ID || shown.Description != "Session expires" {
+		t.Errorf("show by partial ID = %+v", shown)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	if ids := client.ids("/issues"); len(ids) != 2 || ids[0] != created.ID {
+		t.Errorf("list should sort by priority: %v", ids)
+	}
+	if ids := client.ids("/issues?type=bug"); len(ids) != 1 || ids[0] != created.ID {
+		t.Errorf("type filter = %v", ids)
+	}
+	if ids := client.ids("/issues?q=DOCS"); len(ids) != 1 || ids[0] != other.ID {
+		t.Errorf("search = %v", ids)
+	}
+	if ids := client.ids("/issues?status=closed"); len(ids) != 0 {
+		t.Errorf("status filter = %v", ids)
+	}
+}
+
+func TestAPI_Update_OptimisticConcurrency(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	issue := NewIssue("Original")
+	store.CreateIssue(issue)
+
+	_, resp := client.issue(http.StatusOK, "GET", "/issues/"+issue.ID, "")
+	tag := resp.Header.Get("ETag")
+
+	updated, resp := client.issue(http.StatusOK, "PATCH", "/issues/"+issue.ID,
+		`{"title":"First edit","priority":0}`, "If-Match", tag)
+	if updated.Title != "First edit" || updated.Priority != 0 {
+		t.Errorf("unexpected update: %+v", updated)
+	}
+	newTag := resp.Header.Get("ETag")
+	if newTag == tag {
+		t.Error("ETag should change when the issue changes")
+	}
+
+	// A client still holding the old ETag must not overwrite the edit
+	client.expectStatus(http.StatusPreconditionFailed, "PATCH", "/issues/"+issue.ID,
+		`{"title":"Stale edit"}`, "If-Match", tag)
+	got, _ := store.GetIssue(issue.ID)
+	if got.Title != "First edit" {
+		t.Errorf("stale update should not apply, title is %q", got.Title)
+	}
+
+	// The same check works with updated_at in the body
+	staleBody := `{"title":"Stale edit","updated_at":"` + issue.UpdatedAt.Format("2006-01-02T15:04:05.999999999Z07:00") + `"}`
+	client.expectStatus(http.StatusConflict, "PATCH", "/issues/"+issue.ID, staleBody)
+
+	body, _ := json.Marshal(map[string]any{"description": "Fresh", "updated_at": updated.UpdatedAt})
+	fresh, _ := client.issue(http.StatusOK, "PATCH", "/issues/"+issue.ID, string(body))
+	if fresh.Description != "Fresh" || fresh.Title != "First edit" {
+		t.Errorf("only the given fields should change: %+v", fresh)
+	}
+
+	// Without a precondition the update is unconditional
+	client.issue(http.StatusOK, "PATCH", "/issues/"+issue.ID, `{"title":"Last write"}`, "If-Match", "*")
+	client.issue(http.StatusOK, "PATCH", "/issues/"+issue.ID, `{"title":"Last write wins"}`)
+}
+
+func TestAPI_Update_IfVersion(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	issue := NewIssue("Versioned")
+	store.CreateIssue(issue)
+
+	got, resp := client.issue(http.StatusOK, "GET", "/issues/"+issue.ID, "")
+	if got.Version != 1 || resp.Header.Get("ETag") != `"1"` {
+		t.Errorf("version = %d, ETag %s; want 1, \"1\"", got.Version, resp.Header.Get("ETag"))
+	}
+
+	// Writes that leave updated_at alone still change the ETag
+	store.db.Exec(`UPDATE issues SET version = version + 1 WHERE id = ?`, issue.ID)
+	client.expectStatus(http.StatusPreconditionFailed, "PATCH", "/issues/"+issue.ID,
+		`{"title":"Stale"}`, "If-Match", resp.Header.Get("ETag"))
+	store.db.Exec(`UPDATE issues SET version = 1 WHERE id = ?`, issue.ID)
+
+	updated, _ := client.issue(http.StatusOK, "PATCH", "/issues/"+issue.ID, `{"priority":0,"if_version":1}`)
+	if updated.Version != 2 {
+		t.Errorf("version after update = %d, want 2", updated.Version)
+	}
+	client.expectStatus(http.StatusConflict, "PATCH", "/issues/"+issue.ID, `{"title":"Stale","if_version":1}`)
+	client.expectStatus(http.StatusBadRequest, "PATCH", "/issues/"+issue.ID, `{"title":"Stale","if_version":0}`)
+	if got, _ := store.GetIssue(issue.ID); got.Title != "Versioned" {
+		t.Errorf("stale update should not apply, title is %q", got.Title)
+	}
+}
+
+func TestAPI_CloseReopen_PinnedVersion(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Pinned")
+	store.CreateIssue(issue)
+	ctx := context.Background()
+
+	// Another process writes between the precondition check and the close
+	store.UpdateIssue(&Issue{ID: issue.ID, Title: "Changed", Status: StatusOpen, Priority: 2, Type: IssueTypeTask})
+	if err := store.closeIssue(ctx, issue.ID, ResolutionDone, 1); !errors.Is(err, ErrConflict) {
+		t.Errorf("close at a stale version = %v, want ErrConflict", err)
+	}
+	if err := store.closeIssue(ctx, issue.ID, ResolutionDone, 2); err != nil {
+		t.Fatalf("close at the current version: %v", err)
+	}
+	if err := store.reopenIssue(ctx, issue.ID, 2); !errors.Is(err, ErrConflict) {
+		t.Errorf("reopen at a stale version = %v, want ErrConflict", err)
+	}
+	if got, _ := store.GetIssue(issue.ID); got.Status != StatusClosed {
+		t.Errorf("stale reopen should not apply, status is %s", got.Status)
+	}
+
+	r := httptest.NewRequest("POST", "/issues/"+issue.ID+"/reopen", nil)
+	if err := staleVersion(r, ErrConflict); errorStatus(err) != http.StatusConflict {
+		t.Errorf("lost race without If-Match = %d, want 409", errorStatus(err))
+	}
+	r.Header.Set("If-Match", `"1"`)
+	if err := staleVersion(r, ErrConflict); errorStatus(err) != http.StatusPreconditionFailed {
+		t.Errorf("lost race with If-Match = %d, want 412", errorStatus(err))
+	}
+}
+
+func TestAPI_Update_ConcurrentEditsWithSameETag(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	issue := NewIssue("Contended")
+	store.CreateIssue(issue)
+	_, resp := client.issue(http.StatusOK, "GET", "/issues/"+issue.ID, "")
+	tag := resp.Header.Get("ETag")
+
+	const editors = 8
+	statuses := make(chan int, editors)
+	var wg sync.WaitGroup
+	for i := 0; i < editors; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("PATCH", client.srv.URL+"/issues/"+issue.ID, strings.NewReader(`{"status":"in_progress"}`))
+			req.Header.Set("If-Match", tag)
+			resp, err := client.srv.Client().Do(req)
+			if err != nil {
+				t.Errorf("PATCH: %v", err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusPreconditionFailed] != editors-1 {
+		t.Errorf("exactly one editor should win, got %v", counts)
+	}
+}
+
+func TestAPI_CloseReopen(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	issue := NewIssue("Finish me")
+	store.CreateIssue(issue)
+
+	closed, _ := client.issue(http.StatusOK, "POST", "/issues/"+issue.ID+"/close", `{"resolution":"wontfix"}`)
+	if closed.Status != StatusClosed || closed.Resolution != ResolutionWontfix || closed.ClosedAt == nil {
+		t.Errorf("unexpected closed issue: %+v", closed)
+	}
+
+	data := client.expectStatus(http.StatusConflict, "PATCH", "/issues/"+issue.ID, `{"status":"open"}`)
+	if !strings.Contains(data, "reopen") {
+		t.Errorf("error should point at reopen: %s", data)
+	}
+
+	reopened, _ := client.issue(http.StatusOK, "POST", "/issues/"+issue.ID+"/reopen", "")
+	if reopened.Status != StatusOpen || reopened.ClosedAt != nil || reopened.Resolution != "" {
+		t.Errorf("unexpected reopened issue: %+v", reopened)
+	}
+
+	closed, _ = client.issue(http.StatusOK, "POST", "/issues/"+issue.ID+"/close", "")
+	if closed.Resolution != ResolutionDone {
+		t.Errorf("default resolution should be done, got %q", closed.Resolution)
+	}
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues/"+issue.ID+"/close", `{"resolution":"meh"}`)
+}
+
+func TestAPI_DependenciesAndReady(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	blocker := NewIssue("Blocker")
+	blocked := NewIssue("Blocked")
+	store.CreateIssue(blocker)
+	store.CreateIssue(blocked)
+
+	data := client.expectStatus(http.StatusCreated, "POST", "/issues/"+blocked.ID+"/dependencies",
+		`{"depends_on":"`+blocker.ID+`"}`)
+	if !strings.Contains(data, blocker.ID) {
+		t.Errorf("response should list the new blocker: %s", data)
+	}
+	if ids := client.ids("/ready"); len(ids) != 1 || ids[0] != blocker.ID {
+		t.Errorf("ready = %v, want only %s", ids, blocker.ID)
+	}
+
+	data = client.expectStatus(http.StatusOK, "GET", "/issues/"+blocked.ID+"/dependencies", "")
+	var deps []DependencyExport
+	json.Unmarshal([]byte(data), &deps)
+	if len(deps) != 1 || deps[0].DependsOn != blocker.ID || deps[0].Type != DepBlocks {
+		t.Errorf("dependencies = %s", data)
+	}
+
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues/"+blocked.ID+"/dependencies",
+		`{"depends_on":"`+blocked.ID+`"}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues/"+blocked.ID+"/dependencies",
+		`{"depends_on":"`+blocker.ID+`"}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues/"+blocked.ID+"/dependencies", `{}`)
+	client.expectStatus(http.StatusNotFound, "POST", "/issues/"+blocked.ID+"/dependencies", `{"depends_on":"bl-nope"}`)
+	client.expectStatus(http.StatusNotFound, "POST", "/issues/"+blocked.ID+"/dependencies", `{"depends_on":"gone:bl-nope"}`)
+
+	data = client.expectStatus(http.StatusOK, "DELETE", "/issues/"+blocked.ID+"/dependencies/"+blocker.ID, "")
+	if strings.TrimSpace(data) != "[]" {
+		t.Errorf("blockers after delete = %s", data)
+	}
+	if ids := client.ids("/ready?priority=2"); len(ids) != 2 {
+		t.Errorf("both issues should be ready, got %v", ids)
+	}
+	client.expectStatus(http.StatusNotFound, "DELETE", "/issues/"+blocked.ID+"/dependencies/"+blocker.ID, "")
+	client.expectStatus(http.StatusBadRequest, "GET", "/ready?status=open", "")
+}
+
+func TestAPI_ExportImport(t *testing.T) {
+	store, _, client := newTestAPI(t)
+	a := NewIssue("Alpha")
+	b := NewIssue("Beta")
+	b.Type = IssueTypeBug
+	store.CreateIssue(a)
+	store.CreateIssue(b)
+	store.AddDependency(b.ID, a.ID, DepBlocks)
+
+	resp, exported := client.do("GET", "/export?type=bug&with_blockers", "")
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("export: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if lines := strings.Split(strings.TrimSpace(exported), "\n"); len(lines) != 2 {
+		t.Errorf("export with blockers should have 2 lines, got %d: %s", len(lines), exported)
+	}
+
+	_, _, target := newTestAPI(t)
+	data := target.expectStatus(http.StatusOK, "POST", "/import", exported)
+	var result struct {
+		Created int `json:"created"`
+		Updated int `json:"updated"`
+	}
+	json.Unmarshal([]byte(data), &result)
+	if result.Created != 2 {
+		t.Errorf("import result = %s", data)
+	}
+	if ids := target.ids("/ready"); len(ids) != 1 || ids[0] != a.ID {
+		t.Errorf("imported dependencies should apply, ready = %v", ids)
+	}
+
+	data = target.expectStatus(http.StatusOK, "POST", "/import?continue_on_error=true", exported+"not json\n")
+	if !strings.Contains(data, `"line":3`) {
+		t.Errorf("lenient import should report the bad line: %s", data)
+	}
+	target.expectStatus(http.StatusBadRequest, "POST", "/import", "not json\n")
+	target.expectStatus(http.StatusBadRequest, "POST", "/import", `{"format":"other","format_version":1}`+"\n")
+	target.expectStatus(http.StatusBadRequest, "POST", "/import",
+		`{"id":"bl-dang","title":"Dangling","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-nope","type":"blocks"}]}`+"\n")
+
+	// Failures of the store are not the client's fault
+	broken := newTestStore(t)
+	_, _, brokenClient := newTestAPIFor(t, broken)
+	broken.Close()
+	brokenClient.expectStatus(http.StatusInternalServerError, "POST", "/import", exported)
+}
+
+func TestAPI_Errors(t *testing.T) {
+	_, _, client := newTestAPI(t)
+
+	client.expectStatus(http.StatusNotFound, "GET", "/issues/bl-zzzz", "")
+	client.expectStatus(http.StatusNotFound, "PATCH", "/issues/bl-zzzz", `{"title":"x"}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues", `{"title":""}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues", `{"title":"x","issue_type":"saga"}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues", `{"title":"x","assignee":"me"}`)
+	client.expectStatus(http.StatusBadRequest, "POST", "/issues", `{not json`)
+	client.expectStatus(http.StatusBadRequest, "GET", "/issues?status=blocked", "")
+	client.expectStatus(http.StatusBadRequest, "GET", "/issues?priority=high", "")
+	client.expectStatus(http.StatusMethodNotAllowed, "DELETE", "/issues", "")
+
+	data := client.expectStatus(http.StatusNotFound, "GET", "/issues/bl-zzzz", "")
+	var body map[string]string
+	if err := json.Unmarshal([]byte(data), &body); err != nil || !strings.Contains(body["error"], "not found") {
+		t.Errorf("errors should be JSON with an error message: %s", data)
+	}
+}
+
+func TestAPI_AfterChange(t *testing.T) {
+	_, api, client := newTestAPI(t)
+	changes := 0
+	api.AfterChange = func() error {
+		changes++
+		return nil
+	}
+
+	created, _ := client.issue(http.StatusCreated, "POST", "/issues", `{"title":"Tracked"}`)
+	client.ids("/issues")
+	client.issue(http.StatusOK, "GET", "/issues/"+created.ID, "")
+	client.expectStatus(http.StatusNotFound, "POST", "/issues/bl-zzzz/close", "")
+	if changes != 1 {
+		t.Errorf("only the create should count as a change, got %d", changes)
+	}
+
+	client.issue(http.StatusOK, "POST", "/issues/"+created.ID+"/close", "")
+	if changes != 2 {
+		t.Errorf("close should count as a change, got %d", changes)
+	}
+}
+
+func TestAPI_Events(t *testing.T) {
+	store, dbPath := newFileStore(t)
+	_, _, client := newTestAPIFor(t, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", client.srv.URL+"/events", nil)
+	resp, err := client.srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	type sse struct{ event, data string }
+	stream := make(chan sse)
+	go func() {
+		defer close(stream)
+		scanner := bufio.NewScanner(resp.Body)
+		var current sse
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && current.event != "":
+				stream <- current
+				current = sse{}
+			}
+		}
+	}()
+	next := func() sse {
+		t.Helper()
+		select {
+		case msg, ok := <-stream:
+			if !ok {
+				t.Fatal("event stream ended")
+			}
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event within 5s")
+		}
+		return sse{}
+	}
+
+	// The stream starts only once the watcher has its starting state, so
+	// changes made after the response headers arrive are reported.
+	created, _ := client.issue(http.StatusCreated, "POST", "/issues", `{"title":"Via API"}`)
+	msg := next()
+	var event Event
+	if err := json.Unmarshal([]byte(msg.data), &event); err != nil {
+		t.Fatalf("decode event %q: %v", msg.data, err)
+	}
+	if msg.event != "created" || event.Type != EventCreated || event.IssueID != created.ID || event.Issue.Title != "Via API" {
+		t.Errorf("unexpected event %s: %s", msg.event, msg.data)
+	}
+
+	// Changes by another process show up too
+	other, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer other.Close()
+	other.CloseIssue(created.ID, ResolutionDone)
+	if msg := next(); msg.event != "closed" || !strings.Contains(msg.data, created.ID) {
+		t.Errorf("unexpected event %s: %s", msg.event, msg.data)
+	}
+}
@@ -0,0 +1,568 @@
+package beadslite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config keys stored in the config table.
+const (
+	configDefaultPriority   = "defaults.priority"
+	configDefaultType       = "defaults.type"
+	configDefaultResolution = "defaults.resolution"
+	configIDPrefix          = "id.prefix"
+	configIDLength          = "id.length"
+	configOutputFormat      = "output.format"
+	configAutoSync          = "sync.auto"
+	configActor             = "actor"
+)
+
+// Output formats for output.format.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// syncFileName is the export that sync.auto keeps up to date, inside the
+// workspace directory.
+const syncFileName = "issues.jsonl"
+
+// ConfigKey describes a workspace setting.
+type ConfigKey struct {
+	Name        string
+	Default     string // used when the key is not set; "" for actor means $USER
+	Description string
+	validate    func(value string) error
+}
+
+// ConfigKeys lists every setting bl config accepts, in display order.
+var ConfigKeys = []ConfigKey{
+	{configDefaultPriority, "2", "Priority for new issues (0-4)", validatePriorityValue},
+	{configDefaultType, string(IssueTypeTask), "Type for new issues", validateTypeValue},
+	{configDefaultResolution, string(ResolutionDone), "Resolution when closing issues", validateResolutionValue},
+	{configIDPrefix, defaultIDPrefix, "Prefix for new issue IDs", validateIDPrefix},
+	{configIDLength, strconv.Itoa(defaultIDLength), "Minimum hash characters in new issue IDs", validateIDLengthValue},
+	{configOutputFormat, OutputText, "Default output of list, ready and show (text, json)", validateOutputFormat},
+	{configAutoSync, "false", "Export to " + syncFileName + " after every change", validateBoolValue},
+	{configActor, "", "Name recorded as created_by and updated_by of the issues you change (default $USER)", nil},
+	{configCustomTypes, "", "Extra issue types, comma-separated (e.g. chore,spike)", validateCustomTypes},
+	{configCustomStatuses, "", "Extra statuses as name:category (e.g. review:in_progress)", validateCustomStatuses},
+	{configRequireClosedBlockers, "false", "Refuse to close issues whose blockers are still open", validateBoolValue},
+}
+
+// lookupConfigKey finds a setting by name.
+func lookupConfigKey(name string) (ConfigKey, error) {
+	for _, key := range ConfigKeys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	return ConfigKey{}, fmt.Errorf("unknown config key %q", name)
+}
+
+func validatePriorityValue(value string) error {
+	p, err := strconv.Atoi(value)
+	if err != nil || p < 0 || p > 4 {
+		return fmt.Errorf("invalid priority: %q (valid: 0-4)", value)
+	}
+	return nil
+}
+
+func validateCustomTypes(value string) error {
+	_, err := newVocabulary(value, "")
+	return err
+}
+
+func validateCustomStatuses(value string) error {
+	_, err := newVocabulary("", value)
+	return err
+}
+
+func validateTypeValue(value string) error {
+	if !IssueType(value).Valid() {
+		return fmt.Errorf("invalid type: %q (valid: task, bug, feature, epic)", value)
+	}
+	return nil
+}
+
+func validateResolutionValue(value string) error {
+	if value == "" || !Resolution(value).Valid() {
+		return fmt.Errorf("invalid resolution: %q (valid: done, wontfix, duplicate)", value)
+	}
+	return nil
+}
+
+func validateIDLengthValue(value string) error {
+	length, err := strconv.Atoi(value)
+	if err != nil || length < minIDLength || length > maxIDLength {
+		return fmt.Errorf("id length must be %d-%d, got %q", minIDLength, maxIDLength, value)
+	}
+	return nil
+}
+
+func validateOutputFormat(value string) error {
+	if value != OutputText && value != OutputJSON {
+		return fmt.Errorf("invalid output format: %q (valid: text, json)", value)
+	}
+	return nil
+}
+
+func validateBoolValue(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("invalid boolean: %q (valid: true, false)", value)
+	}
+	return nil
+}
+
+// Settings holds the effective value of every workspace setting.
+type Settings struct {
+	DefaultPriority   int
+	DefaultType       IssueType
+	DefaultResolution Resolution
+	IDPrefix          string
+	IDLength          int
+	OutputFormat      string
+	AutoSync          bool
+	Actor             string
+
+	RequireClosedBlockers bool
+}
+
+// Bounds for the length of the hash part of generated IDs.
+const (
+	minIDLength = 3
+	maxIDLength = 12
+)
+
+// ID allocation gives up after maxIDAttempts collisions, and moves to a
+// longer ID after every attemptsPerLength collisions.
+const (
+	maxIDAttempts     = 100
+	attemptsPerLength = 10
+)
+
+// GetConfig returns the value stored under key, or "" if it is not set.
+func (s *Store) GetConfig(key string) (string, error) {
+	return s.GetConfigContext(context.Background(), key)
+}
+
+// GetConfigContext is GetConfig with a context.
+func (s *Store) GetConfigContext(ctx context.Context, key string) (string, error) {
+	var value string
+	err := s.q.QueryRowContext(ctx, `SELECT value FROM config WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get config %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// SetConfig stores value under key, replacing any previous value.
+func (s *Store) SetConfig(key, value string) error {
+	return s.SetConfigContext(context.Background(), key, value)
+}
+
+// SetConfigContext is SetConfig with a context.
+func (s *Store) SetConfigContext(ctx context.Context, key, value string) error {
+	if _, err := s.exec(ctx, `
+		INSERT INTO config (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value); err != nil {
+		return fmt.Errorf("set config %s: %w", key, err)
+	}
+	return nil
+}
+
+// Setting returns the effective value of a known setting: the stored value,
+// or the key's default if it is not set.
+func (s *Store) Setting(name string) (string, error) {
+	return s.SettingContext(context.Background(), name)
+}
+
+// SettingContext is Setting with a context.
+func (s *Store) SettingContext(ctx context.Context, name string) (string, error) {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := s.GetConfigContext(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		value = key.Default
+	}
+	if name == configActor && value == "" {
+		value = os.Getenv("USER")
+	}
+	return value, nil
+}
+
+// IsSet reports whether a setting has a stored value rather than its default.
+func (s *Store) IsSet(name string) (bool, error) {
+	return s.IsSetContext(context.Background(), name)
+}
+
+// IsSetContext is IsSet with a context.
+func (s *Store) IsSetContext(ctx context.Context, name string) (bool, error) {
+	value, err := s.GetConfigContext(ctx, name)
+	return value != "", err
+}
+
+// SetSetting validates and stores a known setting.
+func (s *Store) SetSetting(name, value string) error {
+	return s.SetSettingContext(context.Background(), name, value)
+}
+
+// SetSettingContext is SetSetting with a context.
+func (s *Store) SetSettingContext(ctx context.Context, name, value string) error {
+	key, err := lookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+	// The default type may be one of the workspace's custom types
+	if name == configDefaultType {
+		vocab, err := s.VocabularyContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !vocab.ValidType(IssueType(value)) {
+			return fmt.Errorf("invalid type: %q (valid: %s)", value, vocab.TypeList())
+		}
+	} else if key.validate != nil {
+		if err := key.validate(value); err != nil {
+			return err
+		}
+	}
+	return s.SetConfigContext(ctx, name, value)
+}
+
+// Settings loads the effective value of every setting.
+func (s *Store) Settings() (*Settings, error) {
+	return s.SettingsContext(context.Background())
+}
+
+// SettingsContext is Settings with a context.
+func (s *Store) SettingsContext(ctx context.Context) (*Settings, error) {
+	values := make(map[string]string, len(ConfigKeys))
+	for _, key := range ConfigKeys {
+		value, err := s.SettingContext(ctx, key.Name)
+		if err != nil {
+			return nil, err
+		}
+		values[key.Name] = value
+	}
+
+	// Stored values were validated when set, but fall back to the defaults
+	// rather than fail if the table was edited by hand.
+	settings := &Settings{
+		DefaultPriority:   2,
+		DefaultType:       IssueType(values[configDefaultType]),
+		DefaultResolution: Resolution(values[configDefaultResolution]),
+		IDPrefix:          values[configIDPrefix],
+		IDLength:          defaultIDLength,
+		OutputFormat:      values[configOutputFormat],
+		Actor:             values[configActor],
+	}
+	if p, err := strconv.Atoi(values[configDefaultPriority]); err == nil {
+		settings.DefaultPriority = p
+	}
+	if length, err := strconv.Atoi(values[configIDLength]); err == nil {
+		settings.IDLength = length
+	}
+	settings.AutoSync, _ = strconv.ParseBool(values[configAutoSync])
+	settings.RequireClosedBlockers, _ = strconv.ParseBool(values[configRequireClosedBlockers])
+	return settings, nil
+}
+
+// IDPrefix returns the prefix used for new issue IDs.
+func (s *Store) IDPrefix() (string, error) {
+	return s.IDPrefixContext(context.Background())
+}
+
+// IDPrefixContext is IDPrefix with a context.
+func (s *Store) IDPrefixContext(ctx context.Context) (string, error) {
+	prefix, err := s.GetConfigContext(ctx, configIDPrefix)
+	if err != nil || prefix == "" {
+		return defaultIDPrefix, err
+	}
+	return prefix, nil
+}
+
+// SetIDPrefix changes the prefix used for new issue IDs. Existing issues keep
+// their IDs; use RenamePrefix to rewrite them as well.
+func (s *Store) SetIDPrefix(prefix string) error {
+	return s.SetIDPrefixContext(context.Background(), prefix)
+}
+
+// SetIDPrefixContext is SetIDPrefix with a context.
+func (s *Store) SetIDPrefixContext(ctx context.Context, prefix string) error {
+	if err := validateIDPrefix(prefix); err != nil {
+		return err
+	}
+	return s.SetConfigContext(ctx, configIDPrefix, prefix)
+}
+
+// IDLength returns the number of hash characters in new issue IDs.
+func (s *Store) IDLength() (int, error) {
+	return s.IDLengthContext(context.Background())
+}
+
+// IDLengthContext is IDLength with a context.
+func (s *Store) IDLengthContext(ctx context.Context) (int, error) {
+	value, err := s.GetConfigContext(ctx, configIDLength)
+	if err != nil || value == "" {
+		return defaultIDLength, err
+	}
+	length, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q in config", configIDLength, value)
+	}
+	return length, nil
+}
+
+// SetIDLength changes the number of hash characters in new issue IDs.
+func (s *Store) SetIDLength(length int) error {
+	return s.SetIDLengthContext(context.Background(), length)
+}
+
+// SetIDLengthContext is SetIDLength with a context.
+func (s *Store) SetIDLengthContext(ctx context.Context, length int) error {
+	return s.SetSettingContext(ctx, configIDLength, strconv.Itoa(length))
+}
+
+// NewIssue creates a new issue like the package-level NewIssue, but with an
+// unused ID that has this store's configured prefix. The configured length is
+// a minimum: it grows as the workspace does to keep collisions unlikely.
+func (s *Store) NewIssue(title string) (*Issue, error) {
+	return s.NewIssueContext(context.Background(), title)
+}
+
+// NewIssueContext is NewIssue with a context.
+func (s *Store) NewIssueContext(ctx context.Context, title string) (*Issue, error) {
+	issue := NewIssue(title)
+	id, err := s.allocateID(ctx, title, issue.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	issue.ID = id
+	return issue, nil
+}
+
+// NewChildIssue creates a new issue whose ID is the next child number under
+// parentID, e.g. "bl-a3f8.3". The parent must exist.
+func (s *Store) NewChildIssue(parentID, title string) (*Issue, error) {
+	return s.NewChildIssueContext(context.Background(), parentID, title)
+}
+
+// NewChildIssueContext is NewChildIssue with a context.
+func (s *Store) NewChildIssueContext(ctx context.Context, parentID, title string) (*Issue, error) {
+	if _, err := s.GetIssueContext(ctx, parentID); err != nil {
+		return nil, fmt.Errorf("parent %s: %w", parentID, err)
+	}
+	id, err := s.allocateChildID(ctx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	issue := NewIssue(title)
+	issue.ID = id
+	return issue, nil
+}
+
+// allocateChildID takes the next number from parentID's counter. The
+// counter is advanced atomically, so concurrent callers get different
+// numbers; numbers already taken by existing issues are skipped.
+func (s *Store) allocateChildID(ctx context.Context, parentID string) (string, error) {
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		var n int
+		if err := retryBusy(ctx, func() error {
+			return s.q.QueryRowContext(ctx, `
+				INSERT INTO child_counters (parent_id, last) VALUES (?, 1)
+				ON CONFLICT(parent_id) DO UPDATE SET last = last + 1
+				RETURNING last`, parentID).Scan(&n)
+		}); err != nil {
+			return "", fmt.Errorf("advance child counter: %w", err)
+		}
+		id := childID(parentID, n)
+		var exists bool
+		if err := s.q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM issues WHERE id = ?)`, id).Scan(&exists); err != nil {
+			return "", fmt.Errorf("check id %s: %w", id, err)
+		}
+		if !exists {
+			return id, nil
+		}
+	}
+	return "", errors.New("no free child ID found")
+}
+
+// childCounter returns the highest child number handed out under parentID.
+func (s *Store) childCounter(ctx context.Context, parentID string) (int, error) {
+	var last int
+	err := s.q.QueryRowContext(ctx, `SELECT last FROM child_counters WHERE parent_id = ?`, parentID).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get child counter: %w", err)
+	}
+	return last, nil
+}
+
+// allocateID picks an ID that is not in use. Each collision is retried with a
+// new nonce, and repeated collisions at one length move on to the next.
+func (s *Store) allocateID(ctx context.Context, title string, now time.Time) (string, error) {
+	prefix, err := s.IDPrefixContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	minLength, err := s.IDLengthContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	var count int
+	if err := s.q.QueryRowContext(ctx, `SELECT COUNT(*) FROM issues`).Scan(&count); err != nil {
+		return "", fmt.Errorf("count issues: %w", err)
+	}
+	length := adaptiveIDLength(count, minLength)
+
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		if attempt > 0 && attempt%attemptsPerLength == 0 && length < maxIDLength {
+			length++
+		}
+		// Offset the timestamp to act as a nonce for each retry
+		id := generateHashID(prefix, title, "", now.Add(time.Duration(attempt)), length)
+		var exists bool
+		if err := s.q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM issues WHERE id = ?)`, id).Scan(&exists); err != nil {
+			return "", fmt.Errorf("check id %s: %w", id, err)
+		}
+		if !exists {
+			return id, nil
+		}
+	}
+	return "", errors.New("no free ID found")
+}
+
+// validateIDPrefix checks that prefix is a lowercase letter followed by
+// lowercase letters or digits, so IDs stay unambiguous to split.
+func validateIDPrefix(prefix string) error {
+	if prefix == "" {
+		return errors.New("id prefix cannot be empty")
+	}
+	for i, c := range prefix {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return fmt.Errorf("invalid id prefix %q: use a lowercase letter followed by lowercase letters or digits", prefix)
+		}
+	}
+	return nil
+}
+
+// renamePrefix returns id with its prefix replaced, or "" if id does not
+// start with oldPrefix.
+func renamePrefix(id, oldPrefix, newPrefix string) string {
+	rest, ok := strings.CutPrefix(id, oldPrefix+"-")
+	if !ok {
+		return ""
+	}
+	return newPrefix + "-" + rest
+}
+
+// RenamePrefix rewrites every issue ID that carries the current prefix, along
+// with all dependency references to those issues, and makes newPrefix the
+// prefix for new issues. Issues imported with other prefixes are left alone.
+// It returns the number of issues renamed. Either everything is renamed or
+// nothing is.
+func (s *Store) RenamePrefix(newPrefix string) (int, error) {
+	return s.RenamePrefixContext(context.Background(), newPrefix)
+}
+
+// RenamePrefixContext is RenamePrefix with a context.
+func (s *Store) RenamePrefixContext(ctx context.Context, newPrefix string) (int, error) {
+	if err := validateIDPrefix(newPrefix); err != nil {
+		return 0, err
+	}
+	oldPrefix, err := s.IDPrefixContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if oldPrefix == newPrefix {
+		return 0, nil
+	}
+
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return 0, err
+	}
+	renamed := 0
+	now := time.Now()
+	err = s.WithTransactionContext(ctx, func(tx *StoreTx) error {
+		// Issues and the dependencies pointing at them are renamed one statement
+		// at a time; check the foreign keys once everything is consistent again.
+		if _, err := tx.exec(ctx, `PRAGMA defer_foreign_keys = ON`); err != nil {
+			return err
+		}
+
+		rows, err := tx.q.QueryContext(ctx, `SELECT id FROM issues`)
+		if err != nil {
+			return fmt.Errorf("list issue ids: %w", err)
+		}
+		existing := make(map[string]bool)
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			existing[id] = true
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			newID := renamePrefix(id, oldPrefix, newPrefix)
+			if newID == "" {
+				continue
+			}
+			if existing[newID] {
+				return fmt.Errorf("cannot rename %s: %s already exists", id, newID)
+			}
+			// A new ID is a change to the issue, for watchers and conditional updates
+			if _, err := tx.exec(ctx, `
+				UPDATE issues SET id = ?, updated_at = ?, updated_by = ?, version = version + 1
+				WHERE id = ?`, newID, now, actor, id); err != nil {
+				return fmt.Errorf("rename %s: %w", id, err)
+			}
+			for _, stmt := range []string{
+				`UPDATE dependencies SET issue_id = ? WHERE issue_id = ?`,
+				`UPDATE dependencies SET depends_on_id = ? WHERE depends_on_id = ?`,
+				`UPDATE external_dependencies SET issue_id = ? WHERE issue_id = ?`,
+				`UPDATE child_counters SET parent_id = ? WHERE parent_id = ?`,
+			} {
+				if _, err := tx.exec(ctx, stmt, newID, id); err != nil {
+					return fmt.Errorf("rename %s: %w", id, err)
+				}
+			}
+			renamed++
+		}
+
+		return tx.SetConfigContext(ctx, configIDPrefix, newPrefix)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return renamed, nil
+}
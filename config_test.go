@@ -0,0 +1,407 @@
+package beadslite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStoreConfig(t *testing.T) {
+	store := newTestStore(t)
+
+	value, err := store.GetConfig("missing")
+	if err != nil || value != "" {
+		t.Fatalf("GetConfig(missing) = %q, %v; want empty", value, err)
+	}
+
+	store.SetConfig("key", "one")
+	store.SetConfig("key", "two")
+	value, _ = store.GetConfig("key")
+	if value != "two" {
+		t.Errorf("expected overwritten value two, got %q", value)
+	}
+}
+
+func TestStoreNewIssue_UsesConfiguredPrefixAndLength(t *testing.T) {
+	store := newTestStore(t)
+
+	issue, err := store.NewIssue("Default")
+	if err != nil {
+		t.Fatalf("NewIssue: %v", err)
+	}
+	if !strings.HasPrefix(issue.ID, "bl-") || len(issue.ID) != len("bl-")+defaultIDLength {
+		t.Errorf("expected default bl-xxxx ID, got %s", issue.ID)
+	}
+
+	if err := store.SetIDPrefix("api"); err != nil {
+		t.Fatalf("SetIDPrefix: %v", err)
+	}
+	if err := store.SetIDLength(6); err != nil {
+		t.Fatalf("SetIDLength: %v", err)
+	}
+	issue, _ = store.NewIssue("Configured")
+	if !strings.HasPrefix(issue.ID, "api-") || len(issue.ID) != len("api-")+6 {
+		t.Errorf("expected api-xxxxxx ID, got %s", issue.ID)
+	}
+}
+
+func TestStoreSetIDPrefix_Invalid(t *testing.T) {
+	store := newTestStore(t)
+
+	for _, prefix := range []string{"", "API", "my-api", "a:b", "1abc", "a.b"} {
+		if err := store.SetIDPrefix(prefix); err == nil {
+			t.Errorf("SetIDPrefix(%q) should fail", prefix)
+		}
+	}
+	for _, length := range []int{0, minIDLength - 1, maxIDLength + 1} {
+		if err := store.SetIDLength(length); err == nil {
+			t.Errorf("SetIDLength(%d) should fail", length)
+		}
+	}
+}
+
+func TestStoreRenamePrefix(t *testing.T) {
+	store := newTestStore(t)
+
+	blocker := NewIssue("Blocker")
+	blocked := NewIssue("Blocked")
+	foreign := newIssue("Imported", "ext", 4)
+	for _, issue := range []*Issue{blocker, blocked, foreign} {
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	store.AddDependency(blocked.ID, blocker.ID, DepBlocks)
+	store.AddDependency(blocked.ID, foreign.ID, DepBlocks)
+
+	renamed, err := store.RenamePrefix("web")
+	if err != nil {
+		t.Fatalf("RenamePrefix: %v", err)
+	}
+	if renamed != 2 {
+		t.Errorf("expected 2 renamed issues, got %d", renamed)
+	}
+
+	newBlocker := "web" + strings.TrimPrefix(blocker.ID, "bl")
+	newBlocked := "web" + strings.TrimPrefix(blocked.ID, "bl")
+	if _, err := store.GetIssue(blocker.ID); err == nil {
+		t.Errorf("old ID %s should be gone", blocker.ID)
+	}
+	if got, err := store.GetIssue(newBlocker); err != nil {
+		t.Errorf("renamed issue %s not found: %v", newBlocker, err)
+	} else if got.Version != 2 || !got.UpdatedAt.After(blocker.UpdatedAt) {
+		t.Errorf("renamed issue = version %d, updated %v; want version 2, updated since %v",
+			got.Version, got.UpdatedAt, blocker.UpdatedAt)
+	}
+	if got, err := store.GetIssue(foreign.ID); err != nil {
+		t.Errorf("issue with another prefix should be untouched: %v", err)
+	} else if got.Version != 1 {
+		t.Errorf("issue with another prefix = version %d, want 1", got.Version)
+	}
+
+	deps, _ := store.GetDependencies(newBlocked)
+	targets := map[string]bool{}
+	for _, dep := range deps {
+		targets[dep.DependsOnID] = true
+	}
+	if len(deps) != 2 || !targets[newBlocker] || !targets[foreign.ID] {
+		t.Errorf("dependencies not rewritten: %+v", deps)
+	}
+
+	prefix, _ := store.IDPrefix()
+	if prefix != "web" {
+		t.Errorf("expected prefix web, got %s", prefix)
+	}
+}
+
+func TestStoreRenamePrefix_CollisionRollsBack(t *testing.T) {
+	store := newTestStore(t)
+
+	first := NewIssue("First")
+	second := NewIssue("Second")
+	clash := *second
+	clash.ID = "web" + strings.TrimPrefix(second.ID, "bl")
+	for _, issue := range []*Issue{first, second, &clash} {
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+
+	if _, err := store.RenamePrefix("web"); err == nil {
+		t.Fatal("expected collision error")
+	}
+
+	// Nothing was renamed, including issues processed before the collision
+	if _, err := store.GetIssue(first.ID); err != nil {
+		t.Errorf("%s should still exist after rollback: %v", first.ID, err)
+	}
+	prefix, _ := store.IDPrefix()
+	if prefix != defaultIDPrefix {
+		t.Errorf("prefix should be unchanged, got %s", prefix)
+	}
+}
+
+func TestStoreAllocateID_RetriesOnCollision(t *testing.T) {
+	store := newTestStore(t)
+	now := time.Now()
+
+	// Occupy the ID the first attempt would produce
+	taken := NewIssue("Same title")
+	taken.ID = generateHashID(defaultIDPrefix, "Same title", "", now, defaultIDLength)
+	if err := store.CreateIssue(taken); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	id, err := store.allocateID(context.Background(), "Same title", now)
+	if err != nil {
+		t.Fatalf("allocateID: %v", err)
+	}
+	if id == taken.ID {
+		t.Fatalf("allocateID returned the taken ID %s", id)
+	}
+	if len(id) != len(taken.ID) {
+		t.Errorf("a single collision should not change the length: %s vs %s", id, taken.ID)
+	}
+}
+
+func TestStoreNewIssue_GrowsIDLength(t *testing.T) {
+	store := newTestStore(t)
+	store.SetIDLength(minIDLength)
+
+	// 36^3 IDs get crowded quickly; the allocator should lengthen them
+	// well before collisions become likely, and never hand out a duplicate.
+	seen := make(map[string]bool)
+	for i := 0; i < 300; i++ {
+		issue, err := store.NewIssue(fmt.Sprintf("Issue %d", i))
+		if err != nil {
+			t.Fatalf("NewIssue: %v", err)
+		}
+		if seen[issue.ID] {
+			t.Fatalf("duplicate ID %s", issue.ID)
+		}
+		seen[issue.ID] = true
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue(%s): %v", issue.ID, err)
+		}
+	}
+
+	issue, _ := store.NewIssue("Latest")
+	if got := len(issue.ID) - len("bl-"); got <= minIDLength {
+		t.Errorf("expected IDs longer than %d chars after 300 issues, got %s", minIDLength, issue.ID)
+	}
+}
+
+func TestStoreNewChildIssue(t *testing.T) {
+	store := newTestStore(t)
+
+	epic := NewIssue("Epic")
+	epic.Type = IssueTypeEpic
+	store.CreateIssue(epic)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		child, err := store.NewChildIssue(epic.ID, fmt.Sprintf("Child %d", i))
+		if err != nil {
+			t.Fatalf("NewChildIssue: %v", err)
+		}
+		if err := store.CreateIssue(child); err != nil {
+			t.Fatalf("CreateIssue(%s): %v", child.ID, err)
+		}
+		ids = append(ids, child.ID)
+	}
+	want := []string{epic.ID + ".1", epic.ID + ".2", epic.ID + ".3"}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("child %d: got %s, want %s", i, ids[i], want[i])
+		}
+	}
+
+	// Numbers are not reused after a delete
+	store.DeleteIssue(ids[2])
+	child, _ := store.NewChildIssue(epic.ID, "After delete")
+	if child.ID != epic.ID+".4" {
+		t.Errorf("expected %s.4 after delete, got %s", epic.ID, child.ID)
+	}
+
+	// Grandchildren nest
+	grandchild, err := store.NewChildIssue(ids[0], "Grandchild")
+	if err != nil {
+		t.Fatalf("NewChildIssue(grandchild): %v", err)
+	}
+	if grandchild.ID != ids[0]+".1" {
+		t.Errorf("expected %s.1, got %s", ids[0], grandchild.ID)
+	}
+
+	if _, err := store.NewChildIssue("bl-none", "Orphan"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("expected ErrIssueNotFound for missing parent, got %v", err)
+	}
+}
+
+func TestStoreNewChildIssue_SkipsExistingIDs(t *testing.T) {
+	store := newTestStore(t)
+
+	epic := NewIssue("Epic")
+	store.CreateIssue(epic)
+
+	// A child created with an explicit ID, e.g. by import, advances the counter
+	imported := NewIssue("Imported child")
+	imported.ID = epic.ID + ".5"
+	store.CreateIssue(imported)
+
+	child, err := store.NewChildIssue(epic.ID, "Next")
+	if err != nil {
+		t.Fatalf("NewChildIssue: %v", err)
+	}
+	if child.ID != epic.ID+".6" {
+		t.Errorf("expected %s.6, got %s", epic.ID, child.ID)
+	}
+}
+
+func TestStoreRenamePrefix_Children(t *testing.T) {
+	store := newTestStore(t)
+
+	epic := NewIssue("Epic")
+	store.CreateIssue(epic)
+	child, _ := store.NewChildIssue(epic.ID, "Child")
+	store.CreateIssue(child)
+
+	if _, err := store.RenamePrefix("web"); err != nil {
+		t.Fatalf("RenamePrefix: %v", err)
+	}
+
+	newEpic := "web" + strings.TrimPrefix(epic.ID, "bl")
+	if _, err := store.GetIssue(newEpic + ".1"); err != nil {
+		t.Errorf("child should be renamed with its parent: %v", err)
+	}
+	next, err := store.NewChildIssue(newEpic, "Second child")
+	if err != nil {
+		t.Fatalf("NewChildIssue: %v", err)
+	}
+	if next.ID != newEpic+".2" {
+		t.Errorf("counter should follow the rename: got %s, want %s.2", next.ID, newEpic)
+	}
+}
+
+func TestStoreRecordsActor(t *testing.T) {
+	store := newTestStore(t)
+	store.SetSetting(configActor, "alice")
+	issue := NewIssue("Tracked")
+	if err := store.CreateIssue(issue); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	store.SetSetting(configActor, "bob")
+	got, _ := store.GetIssue(issue.ID)
+	got.Title = "Retitled"
+	if err := store.UpdateIssue(got); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	if got, _ := store.GetIssue(issue.ID); got.CreatedBy != "alice" || got.UpdatedBy != "bob" {
+		t.Errorf("after update: created_by %q, updated_by %q; want alice, bob", got.CreatedBy, got.UpdatedBy)
+	}
+
+	store.SetSetting(configActor, "carol")
+	store.ClaimIssue(issue.ID)
+	if got, _ := store.GetIssue(issue.ID); got.UpdatedBy != "carol" {
+		t.Errorf("after claim: updated_by %q, want carol", got.UpdatedBy)
+	}
+	store.SetSetting(configActor, "dave")
+	store.CloseIssue(issue.ID, ResolutionDone)
+	if got, _ := store.GetIssue(issue.ID); got.CreatedBy != "alice" || got.UpdatedBy != "dave" {
+		t.Errorf("after close: created_by %q, updated_by %q; want alice, dave", got.CreatedBy, got.UpdatedBy)
+	}
+
+	// Imports keep the actor of the change they bring over
+	got, _ = store.GetIssue(issue.ID)
+	export := toIssueExport(got, nil)
+	export.Description, export.UpdatedBy = "Imported", "erin"
+	line, _ := json.Marshal(export)
+	if _, err := ImportFromJSONL(store, bytes.NewReader(line)); err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+	if got, _ := store.GetIssue(issue.ID); got.Description != "Imported" || got.CreatedBy != "alice" || got.UpdatedBy != "erin" {
+		t.Errorf("after import: %+v; want created_by alice, updated_by erin", got)
+	}
+}
+
+func TestStoreSettings_Defaults(t *testing.T) {
+	store := newTestStore(t)
+	t.Setenv("USER", "alice")
+
+	settings, err := store.Settings()
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	want := Settings{
+		DefaultPriority:   2,
+		DefaultType:       IssueTypeTask,
+		DefaultResolution: ResolutionDone,
+		IDPrefix:          defaultIDPrefix,
+		IDLength:          defaultIDLength,
+		OutputFormat:      OutputText,
+		AutoSync:          false,
+		Actor:             "alice",
+	}
+	if *settings != want {
+		t.Errorf("Settings() = %+v, want %+v", *settings, want)
+	}
+}
+
+func TestStoreSetSetting(t *testing.T) {
+	store := newTestStore(t)
+
+	valid := map[string]string{
+		"defaults.priority":   "0",
+		"defaults.type":       "bug",
+		"defaults.resolution": "wontfix",
+		"id.prefix":           "api",
+		"id.length":           "6",
+		"output.format":       "json",
+		"sync.auto":           "true",
+		"actor":               "ci-bot",
+	}
+	for key, value := range valid {
+		if err := store.SetSetting(key, value); err != nil {
+			t.Errorf("SetSetting(%s, %s): %v", key, value, err)
+		}
+	}
+
+	settings, _ := store.Settings()
+	want := Settings{
+		DefaultPriority:   0,
+		DefaultType:       IssueTypeBug,
+		DefaultResolution: ResolutionWontfix,
+		IDPrefix:          "api",
+		IDLength:          6,
+		OutputFormat:      OutputJSON,
+		AutoSync:          true,
+		Actor:             "ci-bot",
+	}
+	if *settings != want {
+		t.Errorf("Settings() = %+v, want %+v", *settings, want)
+	}
+
+	invalid := map[string]string{
+		"defaults.priority":   "7",
+		"defaults.type":       "story",
+		"defaults.resolution": "",
+		"id.prefix":           "Bad",
+		"id.length":           "2",
+		"output.format":       "yaml",
+		"sync.auto":           "sometimes",
+		"no.such.key":         "x",
+	}
+	for key, value := range invalid {
+		if err := store.SetSetting(key, value); err == nil {
+			t.Errorf("SetSetting(%s, %q) should fail", key, value)
+		}
+	}
+	if _, err := store.Setting("no.such.key"); err == nil {
+		t.Error("Setting(no.such.key) should fail")
+	}
+}
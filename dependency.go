@@ -6,6 +6,13 @@ import (
 	"time"
 )
 
+// ErrSelfDependency is returned when an issue is made to depend on itself.
+var ErrSelfDependency = errors.New("issue cannot depend on itself")
+
+// ErrDependencyExists is returned when adding a dependency that is already
+// there.
+var ErrDependencyExists = errors.New("dependency already exists")
+
 // DepType represents the type of dependency between issues.
 type DepType string
 
@@ -49,7 +56,7 @@ func (d *Dependency) Validate() error {
 		return fmt.Errorf("invalid dependency type: %q", d.Type)
 	}
 	if d.IssueID == d.DependsOnID {
-		return errors.New("issue cannot depend on itself")
+		return ErrSelfDependency
 	}
 	return nil
 }
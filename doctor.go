@@ -0,0 +1,376 @@
+package beadslite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProblemKind names a kind of integrity problem found by CheckIntegrity.
+type ProblemKind string
+
+const (
+	ProblemDanglingDependency  ProblemKind = "dangling_dependency"  // an end of the edge does not exist
+	ProblemDuplicateDependency ProblemKind = "duplicate_dependency" // the same edge is stored more than once
+	ProblemInvalidValue        ProblemKind = "invalid_value"        // a field holds a value the workspace does not allow
+	ProblemClosedFields        ProblemKind = "closed_fields"        // closed_at or resolution disagrees with the status
+	ProblemCycle               ProblemKind = "dependency_cycle"     // issues that block each other
+)
+
+// Problem is one integrity problem in a workspace database. Databases
+// written with foreign keys off, by older versions, or by hand can have
+// them; bl doctor reports them.
+type Problem struct {
+	Kind    ProblemKind `json:"kind"`
+	IssueID string      `json:"issue_id"`
+	Detail  string      `json:"detail"`
+	Fixable bool        `json:"fixable"` // RepairIntegrity fixes it without losing information
+
+	fix func(ctx context.Context, s *Store) error // nil unless Fixable
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("%s %s: %s", p.Kind, p.IssueID, p.Detail)
+}
+
+// newProblem returns a problem, fixable if fix is not nil.
+func newProblem(kind ProblemKind, issueID, detail string, fix func(ctx context.Context, s *Store) error) *Problem {
+	return &Problem{Kind: kind, IssueID: issueID, Detail: detail, Fixable: fix != nil, fix: fix}
+}
+
+// CheckIntegrity looks for dependencies on missing issues, duplicate
+// dependencies, fields with invalid values, closed_at and resolution that
+// disagree with the status, and dependency cycles. It changes nothing.
+func (s *Store) CheckIntegrity() ([]*Problem, error) {
+	return s.CheckIntegrityContext(context.Background())
+}
+
+// CheckIntegrityContext is CheckIntegrity with a context.
+func (s *Store) CheckIntegrityContext(ctx context.Context) ([]*Problem, error) {
+	checks := []func(context.Context) ([]*Problem, error){
+		s.danglingDependencies,
+		s.duplicateDependencies,
+		s.invalidIssues,
+		s.dependencyCycles,
+	}
+	var problems []*Problem
+	for _, check := range checks {
+		found, err := check(ctx)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+// RepairIntegrity fixes the problems CheckIntegrity finds that can be fixed
+// safely, in one transaction, and returns them. Dangling and duplicate
+// dependencies are deleted, and closed_at and resolution are brought in line
+// with the status. Invalid values and cycles need a person to decide, and
+// are left alone.
+func (s *Store) RepairIntegrity() ([]*Problem, error) {
+	return s.RepairIntegrityContext(context.Background())
+}
+
+// RepairIntegrityContext is RepairIntegrity with a context.
+func (s *Store) RepairIntegrityContext(ctx context.Context) ([]*Problem, error) {
+	var fixed []*Problem
+	err := s.WithTransactionContext(ctx, func(tx *StoreTx) error {
+		problems, err := tx.CheckIntegrityContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, problem := range problems {
+			if problem.fix == nil {
+				continue
+			}
+			if err := problem.fix(ctx, tx.Store); err != nil {
+				return fmt.Errorf("fix %s: %w", problem, err)
+			}
+			fixed = append(fixed, problem)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fixed, nil
+}
+
+// danglingDependencies finds dependencies whose issue, or local blocker, does
+// not exist. Blockers in other workspaces are not checked here.
+func (s *Store) danglingDependencies(ctx context.Context) ([]*Problem, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT 'dependencies', rowid, issue_id, depends_on_id,
+			EXISTS(SELECT 1 FROM issues WHERE id = issue_id)
+		FROM dependencies
+		WHERE issue_id NOT IN (SELECT id FROM issues) OR depends_on_id NOT IN (SELECT id FROM issues)
+		UNION ALL
+		SELECT 'external_dependencies', rowid, issue_id, workspace || ':' || depends_on_id, 0
+		FROM external_dependencies
+		WHERE issue_id NOT IN (SELECT id FROM issues)
+		ORDER BY 3, 4`)
+	if err != nil {
+		return nil, fmt.Errorf("check dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []*Problem
+	for rows.Next() {
+		var (
+			table, issueID, dependsOnID string
+			rowid                       int64
+			issueExists                 bool
+		)
+		if err := rows.Scan(&table, &rowid, &issueID, &dependsOnID, &issueExists); err != nil {
+			return nil, fmt.Errorf("check dependencies: %w", err)
+		}
+		detail := "blocked by missing issue " + dependsOnID
+		if !issueExists {
+			detail = "missing issue is blocked by " + dependsOnID
+		}
+		problems = append(problems, newProblem(ProblemDanglingDependency, issueID, detail,
+			func(ctx context.Context, s *Store) error {
+				_, err := s.exec(ctx, `DELETE FROM `+table+` WHERE rowid = ?`, rowid)
+				return err
+			}))
+	}
+	return problems, rows.Err()
+}
+
+// duplicateDependencies finds edges stored more than once. bl's own schema
+// cannot hold them, but a database whose tables were recreated by hand or by
+// another tool without their primary keys can.
+func (s *Store) duplicateDependencies(ctx context.Context) ([]*Problem, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT 'dependencies', issue_id, '', depends_on_id, type, COUNT(*), MIN(rowid)
+		FROM dependencies
+		WHERE issue_id IN (SELECT id FROM issues) AND depends_on_id IN (SELECT id FROM issues)
+		GROUP BY issue_id, depends_on_id, type HAVING COUNT(*) > 1
+		UNION ALL
+		SELECT 'external_dependencies', issue_id, workspace, depends_on_id, type, COUNT(*), MIN(rowid)
+		FROM external_dependencies
+		WHERE issue_id IN (SELECT id FROM issues)
+		GROUP BY issue_id, workspace, depends_on_id, type HAVING COUNT(*) > 1
+		ORDER BY 2, 3, 4`)
+	if err != nil {
+		return nil, fmt.Errorf("check duplicate dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []*Problem
+	for rows.Next() {
+		var (
+			table, issueID, workspace, dependsOnID string
+			depType                                DepType
+			count                                  int
+			keep                                   int64
+		)
+		if err := rows.Scan(&table, &issueID, &workspace, &dependsOnID, &depType, &count, &keep); err != nil {
+			return nil, fmt.Errorf("check duplicate dependencies: %w", err)
+		}
+		ref := dependsOnID
+		match := `issue_id = ? AND depends_on_id = ? AND type = ?`
+		args := []any{issueID, dependsOnID, depType}
+		if table == "external_dependencies" {
+			ref = workspace + ":" + dependsOnID
+			match += ` AND workspace = ?`
+			args = append(args, workspace)
+		}
+		problems = append(problems, newProblem(ProblemDuplicateDependency, issueID,
+			fmt.Sprintf("%s %s stored %d times", depType, ref, count),
+			func(ctx context.Context, s *Store) error {
+				_, err := s.exec(ctx, `DELETE FROM `+table+` WHERE `+match+` AND rowid != ?`, append(args, keep)...)
+				return err
+			}))
+	}
+	return problems, rows.Err()
+}
+
+// invalidIssues checks every issue's fields against the workspace's
+// vocabulary, and its closed_at and resolution against its status.
+func (s *Store) invalidIssues(ctx context.Context) ([]*Problem, error) {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.q.QueryContext(ctx, `SELECT `+issueColumns+` FROM issues ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("check issues: %w", err)
+	}
+	defer rows.Close()
+	issues, err := scanIssues(rows)
+	if err != nil {
+		return nil, fmt.Errorf("check issues: %w", err)
+	}
+
+	var problems []*Problem
+	invalid := func(id, format string, args ...any) {
+		problems = append(problems, newProblem(ProblemInvalidValue, id, fmt.Sprintf(format, args...), nil))
+	}
+	for _, issue := range issues {
+		id := issue.ID
+		if !vocab.ValidStatus(issue.Status) {
+			invalid(id, "status %q (valid: %s)", issue.Status, vocab.StatusList())
+		}
+		if !vocab.ValidType(issue.Type) {
+			invalid(id, "type %q (valid: %s)", issue.Type, vocab.TypeList())
+		}
+		if issue.Priority < 0 || issue.Priority > 4 {
+			invalid(id, "priority %d (valid: 0-4)", issue.Priority)
+		}
+		if !issue.Resolution.Valid() {
+			invalid(id, "resolution %q (valid: done, wontfix, duplicate)", issue.Resolution)
+		}
+		if !vocab.ValidStatus(issue.Status) {
+			continue // no way to tell whether it should be closed
+		}
+
+		switch {
+		case vocab.IsClosed(issue.Status) && issue.ClosedAt == nil:
+			problems = append(problems, newProblem(ProblemClosedFields, id,
+				fmt.Sprintf("%s but has no closed_at", issue.Status),
+				func(ctx context.Context, s *Store) error {
+					// The last update is the best guess at when it was closed
+					_, err := s.exec(ctx, `
+						UPDATE issues SET closed_at = updated_at, version = version + 1
+						WHERE id = ? AND closed_at IS NULL`, id)
+					return err
+				}))
+		case !vocab.IsClosed(issue.Status) && (issue.ClosedAt != nil || issue.Resolution != ""):
+			problems = append(problems, newProblem(ProblemClosedFields, id,
+				fmt.Sprintf("%s but has a closed_at or resolution", issue.Status),
+				func(ctx context.Context, s *Store) error {
+					_, err := s.exec(ctx, `
+						UPDATE issues SET closed_at = NULL, resolution = NULL, version = version + 1
+						WHERE id = ?`, id)
+					return err
+				}))
+		}
+	}
+
+	depRows, err := s.q.QueryContext(ctx, `
+		SELECT issue_id, depends_on_id, type FROM dependencies
+		WHERE type != ?
+		UNION ALL
+		SELECT issue_id, workspace || ':' || depends_on_id, type FROM external_dependencies
+		WHERE type != ?
+		ORDER BY 1, 2`, DepBlocks, DepBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("check dependency types: %w", err)
+	}
+	defer depRows.Close()
+	for depRows.Next() {
+		var issueID, dependsOnID string
+		var depType DepType
+		if err := depRows.Scan(&issueID, &dependsOnID, &depType); err != nil {
+			return nil, fmt.Errorf("check dependency types: %w", err)
+		}
+		invalid(issueID, "dependency on %s has type %q (valid: %s)", dependsOnID, depType, DepBlocks)
+	}
+	return problems, depRows.Err()
+}
+
+// dependencyCycles finds sets of local issues that block each other, which
+// keeps all of them out of ready work for good. Each cycle is reported once,
+// against its smallest issue ID.
+func (s *Store) dependencyCycles(ctx context.Context) ([]*Problem, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT issue_id, depends_on_id FROM dependencies
+		WHERE type = ? AND issue_id IN (SELECT id FROM issues WHERE deleted_at IS NULL)
+		AND depends_on_id IN (SELECT id FROM issues WHERE deleted_at IS NULL)
+		ORDER BY issue_id, depends_on_id`, DepBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("check cycles: %w", err)
+	}
+	defer rows.Close()
+
+	blockers := make(map[string][]string)
+	for rows.Next() {
+		var issueID, dependsOnID string
+		if err := rows.Scan(&issueID, &dependsOnID); err != nil {
+			return nil, fmt.Errorf("check cycles: %w", err)
+		}
+		blockers[issueID] = append(blockers[issueID], dependsOnID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("check cycles: %w", err)
+	}
+
+	var problems []*Problem
+	for _, cycle := range findCycles(blockers) {
+		problems = append(problems, newProblem(ProblemCycle, cycle[0],
+			"blocked by itself through "+strings.Join(append(cycle, cycle[0]), " -> "), nil))
+	}
+	return problems, nil
+}
+
+// findCycles returns the cycles in a graph given as adjacency lists, each
+// rotated to start at its smallest node, in order. A depth-first search
+// reports a cycle whenever it meets a node still on its path; cycles that
+// share nodes may go unreported until the first is broken.
+func findCycles(edges map[string][]string) [][]string {
+	nodes := make([]string, 0, len(edges))
+	for node := range edges {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var (
+		path   []string
+		cycles [][]string
+		seen   = make(map[string]bool)
+		visit  func(node string)
+	)
+	visit = func(node string) {
+		state[node] = onPath
+		path = append(path, node)
+		for _, next := range edges[node] {
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case onPath:
+				start := len(path) - 1
+				for path[start] != next {
+					start--
+				}
+				cycle := rotateToSmallest(append([]string(nil), path[start:]...))
+				if key := strings.Join(cycle, " "); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+	}
+	for _, node := range nodes {
+		if state[node] == unvisited {
+			visit(node)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+	return cycles
+}
+
+// rotateToSmallest rotates a cycle so that it starts at its smallest node.
+func rotateToSmallest(cycle []string) []string {
+	smallest := 0
+	for i, node := range cycle {
+		if node < cycle[smallest] {
+			smallest = i
+		}
+	}
+	return append(cycle[smallest:], cycle[:smallest]...)
+}
@@ -0,0 +1,172 @@
+package beadslite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newKeylessStore returns a store on a database whose dependencies table was
+// created without keys, as a hand-made or foreign database might be, holding
+// rows.
+func newKeylessStore(t *testing.T, rows ...[2]string) *Store {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), dbName)
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE dependencies (
+		issue_id TEXT NOT NULL, depends_on_id TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'blocks', created_at DATETIME NOT NULL)`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	for _, row := range rows {
+		if _, err := db.Exec(`INSERT INTO dependencies (issue_id, depends_on_id, created_at)
+			VALUES (?, ?, '2024-01-01T00:00:00Z')`, row[0], row[1]); err != nil {
+			t.Fatalf("insert dependency: %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// problemStrings describes problems the way bl doctor lists them.
+func problemStrings(problems []*Problem) []string {
+	var out []string
+	for _, problem := range problems {
+		s := problem.String()
+		if problem.Fixable {
+			s += " (fixable)"
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestCheckIntegrity_Clean(t *testing.T) {
+	store := newTestStore(t)
+	a, b := NewIssue("A"), NewIssue("B")
+	store.CreateIssue(a)
+	store.CreateIssue(b)
+	store.AddDependency(a.ID, b.ID, DepBlocks)
+	store.CloseIssue(b.ID, ResolutionDone)
+
+	problems, err := store.CheckIntegrity()
+	if err != nil || len(problems) != 0 {
+		t.Errorf("CheckIntegrity = %v, %v; want no problems", problemStrings(problems), err)
+	}
+}
+
+func TestCheckIntegrity_FindsAndRepairs(t *testing.T) {
+	store := newKeylessStore(t,
+		[2]string{"bl-a", "bl-gone"},
+		[2]string{"bl-gone2", "bl-a"},
+		[2]string{"bl-a", "bl-b"},
+		[2]string{"bl-a", "bl-b"},
+	)
+	for _, id := range []string{"bl-a", "bl-b", "bl-c", "bl-d", "bl-e", "bl-f"} {
+		issue := NewIssue("Issue " + id)
+		issue.ID = id
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	store.AddDependency("bl-b", "bl-c", DepBlocks)
+	store.AddDependency("bl-c", "bl-b", DepBlocks)
+	for _, stmt := range []string{
+		`UPDATE issues SET status = 'closed', closed_at = NULL WHERE id = 'bl-d'`,
+		`UPDATE issues SET resolution = 'done' WHERE id = 'bl-e'`,
+		`UPDATE issues SET status = 'bogus', priority = 9 WHERE id = 'bl-f'`,
+	} {
+		if _, err := store.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	problems, err := store.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity: %v", err)
+	}
+	unfixable := []string{
+		`invalid_value bl-f: status "bogus" (valid: open, in_progress, closed)`,
+		`invalid_value bl-f: priority 9 (valid: 0-4)`,
+		`dependency_cycle bl-b: blocked by itself through bl-b -> bl-c -> bl-b`,
+	}
+	want := append([]string{
+		`dangling_dependency bl-a: blocked by missing issue bl-gone (fixable)`,
+		`dangling_dependency bl-gone2: missing issue is blocked by bl-a (fixable)`,
+		`duplicate_dependency bl-a: blocks bl-b stored 2 times (fixable)`,
+		`closed_fields bl-d: closed but has no closed_at (fixable)`,
+		`closed_fields bl-e: open but has a closed_at or resolution (fixable)`,
+	}, unfixable...)
+	if got := problemStrings(problems); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckIntegrity =\n%q\nwant\n%q", got, want)
+	}
+
+	fixed, err := store.RepairIntegrity()
+	if err != nil {
+		t.Fatalf("RepairIntegrity: %v", err)
+	}
+	if len(fixed) != 5 {
+		t.Errorf("RepairIntegrity fixed %q, want the 5 fixable problems", problemStrings(fixed))
+	}
+	problems, _ = store.CheckIntegrity()
+	if got := problemStrings(problems); !reflect.DeepEqual(got, unfixable) {
+		t.Errorf("after repair =\n%q\nwant\n%q", got, unfixable)
+	}
+
+	deps, _ := store.GetDependencies("bl-a")
+	if len(deps) != 1 || deps[0].DependsOnID != "bl-b" {
+		t.Errorf("bl-a dependencies = %+v, want one on bl-b", deps)
+	}
+	if d, _ := store.GetIssue("bl-d"); d.ClosedAt == nil || !d.ClosedAt.Equal(d.UpdatedAt) {
+		t.Errorf("bl-d closed_at = %v, want its updated_at %v", d.ClosedAt, d.UpdatedAt)
+	}
+	if e, _ := store.GetIssue("bl-e"); e.Resolution != "" || e.ClosedAt != nil {
+		t.Errorf("bl-e = %+v, want no resolution or closed_at", e)
+	}
+}
+
+func TestStoreAddDependency_MissingIssue(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Real")
+	store.CreateIssue(issue)
+
+	err := store.AddDependency(issue.ID, "bl-none", DepBlocks)
+	if err == nil || err.Error() != "issue bl-none: issue not found" {
+		t.Errorf("missing blocker error = %v", err)
+	}
+	if err := store.AddDependency("bl-none", issue.ID, DepBlocks); err == nil {
+		t.Error("expected a dependency of a missing issue to fail")
+	}
+	if err := store.AddDependency("bl-none", "api:bl-a1b2", DepBlocks); err == nil {
+		t.Error("expected a remote dependency of a missing issue to fail")
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges map[string][]string
+		want  [][]string
+	}{
+		{"none", map[string][]string{"a": {"b"}, "b": {"c"}}, nil},
+		{"pair", map[string][]string{"b": {"a"}, "a": {"b"}}, [][]string{{"a", "b"}}},
+		{"rotated", map[string][]string{"a": {"c"}, "c": {"b"}, "b": {"c"}}, [][]string{{"b", "c"}}},
+		{"separate", map[string][]string{"x": {"y"}, "y": {"x"}, "a": {"b"}, "b": {"c"}, "c": {"a"}},
+			[][]string{{"a", "b", "c"}, {"x", "y"}}},
+	}
+	for _, tt := range tests {
+		if got := findCycles(tt.edges); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: findCycles = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
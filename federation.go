@@ -0,0 +1,410 @@
+package beadslite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/driver"
+)
+
+// ErrWorkspaceNotFound is returned when a remote workspace name is not registered.
+var ErrWorkspaceNotFound = errors.New("workspace not found")
+
+// RemoteWorkspace is another workspace registered under a short name, so its
+// issues can be referenced as "<name>:<id>".
+type RemoteWorkspace struct {
+	Name      string
+	Path      string // absolute path to the remote database
+	CreatedAt time.Time
+}
+
+// ParseIssueRef splits a reference of the form "<workspace>:<id>". Plain IDs
+// return an empty workspace.
+func ParseIssueRef(ref string) (workspace, id string) {
+	if ws, id, ok := strings.Cut(ref, ":"); ok {
+		return ws, id
+	}
+	return "", ref
+}
+
+// validateWorkspaceName checks that name can be used in "<name>:<id>" references.
+func validateWorkspaceName(name string) error {
+	if name == "" {
+		return errors.New("workspace name cannot be empty")
+	}
+	if strings.ContainsAny(name, ": \t\n") {
+		return fmt.Errorf("invalid workspace name %q: must not contain ':' or whitespace", name)
+	}
+	return nil
+}
+
+// resolveRemoteDB finds the database for a workspace path, which may name the
+// database file itself, a .beads-lite directory, or a directory containing one.
+func resolveRemoteDB(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return abs, nil
+	}
+	for _, candidate := range []string{filepath.Join(abs, dbName), filepath.Join(abs, beadsDir, dbName)} {
+		if fileExists(candidate) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no beads-lite database found in %s", abs)
+}
+
+// AddRemoteWorkspace registers the workspace at path under name.
+func (s *Store) AddRemoteWorkspace(name, path string) error {
+	return s.AddRemoteWorkspaceContext(context.Background(), name, path)
+}
+
+// AddRemoteWorkspaceContext is AddRemoteWorkspace with a context.
+func (s *Store) AddRemoteWorkspaceContext(ctx context.Context, name, path string) error {
+	if err := validateWorkspaceName(name); err != nil {
+		return err
+	}
+	dbPath, err := resolveRemoteDB(path)
+	if err != nil {
+		return fmt.Errorf("workspace %s: %w", name, err)
+	}
+
+	if _, err := s.exec(ctx, `
+		INSERT INTO remote_workspaces (name, path, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET path = excluded.path`,
+		name, dbPath, time.Now()); err != nil {
+		return fmt.Errorf("add workspace: %w", err)
+	}
+	return nil
+}
+
+// RemoveRemoteWorkspace unregisters a workspace. Dependencies that reference
+// it are kept, but are treated as open blockers until it is registered again.
+func (s *Store) RemoveRemoteWorkspace(name string) error {
+	return s.RemoveRemoteWorkspaceContext(context.Background(), name)
+}
+
+// RemoveRemoteWorkspaceContext is RemoveRemoteWorkspace with a context.
+func (s *Store) RemoveRemoteWorkspaceContext(ctx context.Context, name string) error {
+	result, err := s.exec(ctx, `DELETE FROM remote_workspaces WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("remove workspace: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", name, ErrWorkspaceNotFound)
+	}
+	return nil
+}
+
+// GetRemoteWorkspace returns a registered workspace by name.
+func (s *Store) GetRemoteWorkspace(name string) (*RemoteWorkspace, error) {
+	return s.GetRemoteWorkspaceContext(context.Background(), name)
+}
+
+// GetRemoteWorkspaceContext is GetRemoteWorkspace with a context.
+func (s *Store) GetRemoteWorkspaceContext(ctx context.Context, name string) (*RemoteWorkspace, error) {
+	ws := &RemoteWorkspace{}
+	err := s.q.QueryRowContext(ctx, `
+		SELECT name, path, created_at FROM remote_workspaces WHERE name = ?`, name).Scan(
+		&ws.Name, &ws.Path, &ws.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%s: %w", name, ErrWorkspaceNotFound)
+	}
+	return ws, err
+}
+
+// ListRemoteWorkspaces returns all registered workspaces ordered by name.
+func (s *Store) ListRemoteWorkspaces() ([]*RemoteWorkspace, error) {
+	return s.ListRemoteWorkspacesContext(context.Background())
+}
+
+// ListRemoteWorkspacesContext is ListRemoteWorkspaces with a context.
+func (s *Store) ListRemoteWorkspacesContext(ctx context.Context) ([]*RemoteWorkspace, error) {
+	rows, err := s.q.QueryContext(ctx, `SELECT name, path, created_at FROM remote_workspaces ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*RemoteWorkspace
+	for rows.Next() {
+		ws := &RemoteWorkspace{}
+		if err := rows.Scan(&ws.Name, &ws.Path, &ws.CreatedAt); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, rows.Err()
+}
+
+// OpenReadOnlyStore opens an existing database without creating or migrating
+// it, and with writes refused by SQLite. Used to read other workspaces, which
+// may have been created by an older version of bl.
+func OpenReadOnlyStore(dbPath string) (*Store, error) {
+	if !fileExists(dbPath) {
+		return nil, fmt.Errorf("open database %s: %w", dbPath, os.ErrNotExist)
+	}
+	dsn := (&url.URL{Scheme: "file", Path: filepath.ToSlash(dbPath), RawQuery: "mode=ro"}).String()
+	db, err := driver.Open(dsn, initReadOnlyConn)
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %w", dbPath, err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open database %s: %w", dbPath, err)
+	}
+	return &Store{db: db, q: db}, nil
+}
+
+// initReadOnlyConn configures a connection to a database that cannot be
+// migrated. Whatever an older schema lacks is filled in by temporary objects,
+// which shadow the real ones for this connection only: a view of issues that
+// reads missing columns as their defaults, and an empty config table.
+func initReadOnlyConn(conn *sqlite3.Conn) error {
+	if err := initConn(conn); err != nil {
+		return err
+	}
+	columns, err := connColumns(conn, "issues")
+	if err != nil {
+		return err
+	}
+	var fallbacks []string
+	for _, col := range addedIssueColumns {
+		if len(columns) > 0 && !columns[col.name] {
+			fallbacks = append(fallbacks, col.fallback+" AS "+col.name)
+		}
+	}
+	if len(fallbacks) > 0 {
+		if err := conn.Exec(`CREATE TEMP VIEW issues AS
+			SELECT *, ` + strings.Join(fallbacks, ", ") + ` FROM main.issues`); err != nil {
+			return fmt.Errorf("read old schema: %w", err)
+		}
+	}
+
+	config, err := connColumns(conn, "config")
+	if err != nil {
+		return err
+	}
+	if len(config) == 0 {
+		if err := conn.Exec(`CREATE TEMP TABLE config (key TEXT PRIMARY KEY, value TEXT NOT NULL)`); err != nil {
+			return fmt.Errorf("read old schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// connColumns returns the names of a table's columns, or none if the table
+// does not exist.
+func connColumns(conn *sqlite3.Conn, table string) (map[string]bool, error) {
+	stmt, _, err := conn.Prepare(`SELECT name FROM pragma_table_info(?)`)
+	if err != nil {
+		return nil, fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer stmt.Close()
+	if err := stmt.BindText(1, table); err != nil {
+		return nil, fmt.Errorf("inspect %s: %w", table, err)
+	}
+
+	columns := make(map[string]bool)
+	for stmt.Step() {
+		columns[stmt.ColumnText(0)] = true
+	}
+	if err := stmt.Err(); err != nil {
+		return nil, fmt.Errorf("inspect %s: %w", table, err)
+	}
+	return columns, nil
+}
+
+// remoteStores opens registered workspaces read-only on first use and keeps
+// them open until Close, so a query touching many remote issues opens each
+// database once.
+type remoteStores struct {
+	local  *Store
+	stores map[string]*Store
+}
+
+func newRemoteStores(local *Store) *remoteStores {
+	return &remoteStores{local: local, stores: make(map[string]*Store)}
+}
+
+// get returns the store for a registered workspace.
+func (r *remoteStores) get(ctx context.Context, name string) (*Store, error) {
+	if store, ok := r.stores[name]; ok {
+		return store, nil
+	}
+	ws, err := r.local.GetRemoteWorkspaceContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	store, err := OpenReadOnlyStore(ws.Path)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %w", name, err)
+	}
+	r.stores[name] = store
+	return store, nil
+}
+
+// issue looks up a "<workspace>:<id>" reference.
+func (r *remoteStores) issue(ctx context.Context, ref string) (*Issue, error) {
+	name, id := ParseIssueRef(ref)
+	store, err := r.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetIssueContext(ctx, id)
+}
+
+// closed reports whether a "<workspace>:<id>" reference names a closed-like
+// issue by that workspace's own statuses. Unreadable issues count as open.
+func (r *remoteStores) closed(ctx context.Context, ref string) bool {
+	name, _ := ParseIssueRef(ref)
+	issue, err := r.issue(ctx, ref)
+	if err != nil {
+		return false
+	}
+	store, err := r.get(ctx, name)
+	if err != nil {
+		return false
+	}
+	vocab, err := store.VocabularyContext(ctx)
+	return err == nil && vocab.IsClosed(issue.Status)
+}
+
+func (r *remoteStores) Close() {
+	for _, store := range r.stores {
+		store.Close()
+	}
+}
+
+// ResolveRemoteID expands the ID in a "<workspace>:<partial>" reference as
+// ResolveID does, within the named workspace, and returns the full reference.
+func (s *Store) ResolveRemoteID(ref string) (string, error) {
+	return s.ResolveRemoteIDContext(context.Background(), ref)
+}
+
+// ResolveRemoteIDContext is ResolveRemoteID with a context.
+func (s *Store) ResolveRemoteIDContext(ctx context.Context, ref string) (string, error) {
+	name, partial := ParseIssueRef(ref)
+	if name == "" {
+		return "", fmt.Errorf("%q is not a workspace reference", ref)
+	}
+	remotes := newRemoteStores(s)
+	defer remotes.Close()
+	store, err := remotes.get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	id, err := store.ResolveIDContext(ctx, partial)
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + id, nil
+}
+
+// filterExternallyBlocked drops issues that have an open blocker in another
+// workspace. A blocker whose workspace or issue cannot be read counts as open,
+// so an unreachable workspace never makes work look ready by mistake.
+func (s *Store) filterExternallyBlocked(ctx context.Context, issues []*Issue) ([]*Issue, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT issue_id, workspace || ':' || depends_on_id
+		FROM external_dependencies WHERE type = 'blocks'`)
+	if err != nil {
+		return nil, err
+	}
+	blockers := make(map[string][]string)
+	for rows.Next() {
+		var issueID, ref string
+		if err := rows.Scan(&issueID, &ref); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		blockers[issueID] = append(blockers[issueID], ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(blockers) == 0 {
+		return issues, nil
+	}
+
+	remotes := newRemoteStores(s)
+	defer remotes.Close()
+
+	closed := make(map[string]bool)
+	ready := issues[:0]
+	for _, issue := range issues {
+		blocked := false
+		for _, ref := range blockers[issue.ID] {
+			isClosed, seen := closed[ref]
+			if !seen {
+				isClosed = remotes.closed(ctx, ref)
+				closed[ref] = isClosed
+			}
+			if !isClosed {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			ready = append(ready, issue)
+		}
+	}
+	return ready, nil
+}
+
+// WorkspaceIssues pairs a workspace name with its issues for aggregate views.
+// The local workspace has an empty name.
+type WorkspaceIssues struct {
+	Workspace string
+	Issues    []*Issue
+	Err       error // set if the workspace could not be read
+}
+
+// ListAllWorkspaceIssues returns the local issues followed by those of every
+// registered workspace. Unreadable workspaces are reported in Err rather than
+// failing the whole listing.
+func (s *Store) ListAllWorkspaceIssues() ([]WorkspaceIssues, error) {
+	return s.ListAllWorkspaceIssuesContext(context.Background())
+}
+
+// ListAllWorkspaceIssuesContext is ListAllWorkspaceIssues with a context.
+func (s *Store) ListAllWorkspaceIssuesContext(ctx context.Context) ([]WorkspaceIssues, error) {
+	local, err := s.ListIssuesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := []WorkspaceIssues{{Issues: local}}
+
+	workspaces, err := s.ListRemoteWorkspacesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remotes := newRemoteStores(s)
+	defer remotes.Close()
+
+	for _, ws := range workspaces {
+		entry := WorkspaceIssues{Workspace: ws.Name}
+		if store, err := remotes.get(ctx, ws.Name); err != nil {
+			entry.Err = err
+		} else {
+			entry.Issues, entry.Err = store.ListIssuesContext(ctx)
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
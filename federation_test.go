@@ -0,0 +1,294 @@
+package beadslite
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newFileStore creates a store backed by a database file in a temp directory.
+func newFileStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), dbName)
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, dbPath
+}
+
+func TestParseIssueRef(t *testing.T) {
+	tests := []struct {
+		ref, workspace, id string
+	}{
+		{"bl-a1b2", "", "bl-a1b2"},
+		{"api:bl-a1b2", "api", "bl-a1b2"},
+	}
+	for _, tt := range tests {
+		ws, id := ParseIssueRef(tt.ref)
+		if ws != tt.workspace || id != tt.id {
+			t.Errorf("ParseIssueRef(%q) = (%q, %q), want (%q, %q)", tt.ref, ws, id, tt.workspace, tt.id)
+		}
+	}
+}
+
+func TestStoreRemoteWorkspaces(t *testing.T) {
+	local, _ := newFileStore(t)
+	_, remotePath := newFileStore(t)
+
+	if err := local.AddRemoteWorkspace("api", filepath.Dir(remotePath)); err != nil {
+		t.Fatalf("AddRemoteWorkspace: %v", err)
+	}
+	if err := local.AddRemoteWorkspace("bad:name", remotePath); err == nil {
+		t.Error("names containing ':' should be rejected")
+	}
+	if err := local.AddRemoteWorkspace("missing", filepath.Join(t.TempDir(), "nope")); err == nil {
+		t.Error("nonexistent paths should be rejected")
+	}
+
+	workspaces, err := local.ListRemoteWorkspaces()
+	if err != nil {
+		t.Fatalf("ListRemoteWorkspaces: %v", err)
+	}
+	if len(workspaces) != 1 || workspaces[0].Name != "api" || workspaces[0].Path != remotePath {
+		t.Fatalf("unexpected workspaces: %+v", workspaces)
+	}
+
+	if err := local.RemoveRemoteWorkspace("api"); err != nil {
+		t.Fatalf("RemoveRemoteWorkspace: %v", err)
+	}
+	if err := local.RemoveRemoteWorkspace("api"); !errors.Is(err, ErrWorkspaceNotFound) {
+		t.Errorf("expected ErrWorkspaceNotFound, got %v", err)
+	}
+}
+
+func TestStoreGetReadyWork_CrossWorkspaceBlocker(t *testing.T) {
+	local, _ := newFileStore(t)
+	remote, remotePath := newFileStore(t)
+
+	blocker := NewIssue("Remote API change")
+	if err := remote.CreateIssue(blocker); err != nil {
+		t.Fatalf("CreateIssue(remote): %v", err)
+	}
+	if err := local.AddRemoteWorkspace("api", remotePath); err != nil {
+		t.Fatalf("AddRemoteWorkspace: %v", err)
+	}
+
+	feature := NewIssue("Use new API")
+	local.CreateIssue(feature)
+	ref := "api:" + blocker.ID
+	if err := local.AddDependency(feature.ID, ref, DepBlocks); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+
+	deps, err := local.GetDependencies(feature.ID)
+	if err != nil {
+		t.Fatalf("GetDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].DependsOnID != ref {
+		t.Fatalf("expected dependency on %s, got %+v", ref, deps)
+	}
+
+	ready, _ := local.GetReadyWork()
+	if len(ready) != 0 {
+		t.Fatalf("feature should be blocked by open remote issue, got %d ready", len(ready))
+	}
+
+	if err := remote.CloseIssue(blocker.ID, ResolutionDone); err != nil {
+		t.Fatalf("CloseIssue(remote): %v", err)
+	}
+	ready, _ = local.GetReadyWork()
+	if len(ready) != 1 || ready[0].ID != feature.ID {
+		t.Fatalf("feature should be ready once remote blocker closes, got %+v", ready)
+	}
+
+	// An unregistered workspace is treated as an open blocker
+	local.RemoveRemoteWorkspace("api")
+	ready, _ = local.GetReadyWork()
+	if len(ready) != 0 {
+		t.Errorf("unreachable blocker should keep the issue blocked, got %d ready", len(ready))
+	}
+
+	// Removing the dependency clears it
+	if err := local.RemoveDependency(feature.ID, ref, DepBlocks); err != nil {
+		t.Fatalf("RemoveDependency: %v", err)
+	}
+	ready, _ = local.GetReadyWork()
+	if len(ready) != 1 {
+		t.Errorf("expected issue ready after removing remote blocker, got %d", len(ready))
+	}
+}
+
+func TestOpenReadOnlyStore_RefusesWrites(t *testing.T) {
+	_, dbPath := newFileStore(t)
+
+	store, err := OpenReadOnlyStore(dbPath)
+	if err != nil {
+		t.Fatalf("OpenReadOnlyStore: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.CreateIssue(NewIssue("Should fail")); err == nil {
+		t.Error("read-only store should refuse writes")
+	}
+	if _, err := OpenReadOnlyStore(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Error("opening a missing database should fail")
+	}
+}
+
+func TestOpenReadOnlyStore_OldSchema(t *testing.T) {
+	local, _ := newFileStore(t)
+
+	// A workspace last used by a version of bl from before the columns added
+	// since the first release and the config table
+	remotePath := filepath.Join(t.TempDir(), dbName)
+	db, err := sql.Open("sqlite3", remotePath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE issues (
+		id TEXT PRIMARY KEY, title TEXT NOT NULL, description TEXT,
+		status TEXT NOT NULL DEFAULT 'open', priority INTEGER NOT NULL DEFAULT 2,
+		issue_type TEXT NOT NULL DEFAULT 'task', created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL, closed_at DATETIME, resolution TEXT)`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO issues (id, title, description, status, created_at, updated_at, closed_at) VALUES
+		('bl-old1', 'Old open', '', 'open', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z', NULL),
+		('bl-old2', 'Old closed', '', 'closed', '2024-01-01T00:00:00Z', '2024-01-02T00:00:00Z', '2024-01-02T00:00:00Z')`); err != nil {
+		t.Fatalf("insert old issues: %v", err)
+	}
+	db.Close()
+
+	if err := local.AddRemoteWorkspace("old", remotePath); err != nil {
+		t.Fatalf("AddRemoteWorkspace: %v", err)
+	}
+
+	all, err := local.ListAllWorkspaceIssues()
+	if err != nil {
+		t.Fatalf("ListAllWorkspaceIssues: %v", err)
+	}
+	if len(all) != 2 || all[1].Err != nil || len(all[1].Issues) != 2 {
+		t.Fatalf("unexpected old workspace entry: %+v", all[1])
+	}
+	if got := all[1].Issues[0]; got.Version != 1 || got.DeletedAt != nil || got.CreatedBy != "" {
+		t.Errorf("old issue should read with default columns, got %+v", got)
+	}
+
+	// Closed blockers in the old workspace unblock local work; open ones do not
+	blocked, ready := NewIssue("Blocked"), NewIssue("Ready")
+	local.CreateIssue(blocked)
+	local.CreateIssue(ready)
+	if err := local.AddDependency(blocked.ID, "old:bl-old1", DepBlocks); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if err := local.AddDependency(ready.ID, "old:bl-old2", DepBlocks); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	work, err := local.GetReadyWork()
+	if err != nil {
+		t.Fatalf("GetReadyWork: %v", err)
+	}
+	if len(work) != 1 || work[0].ID != ready.ID {
+		t.Errorf("expected only %s ready, got %+v", ready.ID, work)
+	}
+
+	// The remote database was only read
+	check, err := sql.Open("sqlite3", remotePath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer check.Close()
+	var tables int
+	if err := check.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil || tables != 1 {
+		t.Errorf("old workspace schema changed: %d tables, %v", tables, err)
+	}
+}
+
+func TestStoreListAllWorkspaceIssues(t *testing.T) {
+	local, _ := newFileStore(t)
+	remote, remotePath := newFileStore(t)
+
+	local.CreateIssue(NewIssue("Local"))
+	remote.CreateIssue(NewIssue("Remote"))
+	local.AddRemoteWorkspace("api", remotePath)
+
+	// A registered workspace whose database later disappears
+	gonePath := filepath.Join(t.TempDir(), dbName)
+	gone, _ := NewStore(gonePath)
+	gone.Close()
+	local.AddRemoteWorkspace("gone", gonePath)
+	os.Remove(gonePath)
+
+	all, err := local.ListAllWorkspaceIssues()
+	if err != nil {
+		t.Fatalf("ListAllWorkspaceIssues: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected local + 2 remotes, got %d", len(all))
+	}
+	if all[0].Workspace != "" || len(all[0].Issues) != 1 {
+		t.Errorf("unexpected local entry: %+v", all[0])
+	}
+	if all[1].Workspace != "api" || len(all[1].Issues) != 1 || all[1].Issues[0].Title != "Remote" {
+		t.Errorf("unexpected api entry: %+v", all[1])
+	}
+	if all[2].Workspace != "gone" || all[2].Err == nil {
+		t.Errorf("missing workspace should report an error: %+v", all[2])
+	}
+}
+
+func TestCLI_Workspace_CrossRepoBlocker(t *testing.T) {
+	setupTestDir(t)
+
+	os.MkdirAll("api", 0755)
+	os.MkdirAll("web", 0755)
+	runCLI([]string{"init", "--db", "api/beads.db"})
+	runCLI([]string{"init", "--db", "web/beads.db"})
+
+	apiOut, _ := runCLI([]string{"--db", "api/beads.db", "create", "Add endpoint"})
+	apiID := extractID(apiOut)
+
+	out, err := runCLI([]string{"--db", "web/beads.db", "workspace", "add", "api", "api"})
+	if err != nil {
+		t.Fatalf("workspace add failed: %v", err)
+	}
+	if !strings.Contains(out, "Added workspace api") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	listOut, _ := runCLI([]string{"--db", "web/beads.db", "workspace", "list"})
+	if !strings.Contains(listOut, "api") {
+		t.Errorf("workspace list should show api: %s", listOut)
+	}
+
+	if _, err := runCLI([]string{"--db", "web/beads.db", "create", "Bad blocker", "--blocked-by", "api:bl-zzzz"}); err == nil {
+		t.Error("blocking on a nonexistent remote issue should fail")
+	}
+	if _, err := runCLI([]string{"--db", "web/beads.db", "create", "Call endpoint", "--blocked-by", "api:" + apiID}); err != nil {
+		t.Fatalf("create with remote blocker failed: %v", err)
+	}
+
+	readyOut, _ := runCLI([]string{"--db", "web/beads.db", "ready"})
+	if strings.Contains(readyOut, "Call endpoint") {
+		t.Errorf("web task should be blocked by api task: %s", readyOut)
+	}
+
+	allOut, err := runCLI([]string{"--db", "web/beads.db", "list", "--all-workspaces"})
+	if err != nil {
+		t.Fatalf("list --all-workspaces failed: %v", err)
+	}
+	if !strings.Contains(allOut, "api:"+apiID) || !strings.Contains(allOut, "Call endpoint") {
+		t.Errorf("aggregate list should show both workspaces: %s", allOut)
+	}
+
+	runCLI([]string{"--db", "api/beads.db", "close", apiID})
+	readyOut, _ = runCLI([]string{"--db", "web/beads.db", "ready"})
+	if !strings.Contains(readyOut, "Call endpoint") {
+		t.Errorf("web task should be ready after api task closes: %s", readyOut)
+	}
+}
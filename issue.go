@@ -2,9 +2,12 @@ package beadslite
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -68,6 +71,12 @@ func (r Resolution) Valid() bool {
 	}
 }
 
+// Defaults for generated issue IDs; a workspace can override both in its config.
+const (
+	defaultIDPrefix = "bl"
+	defaultIDLength = 4
+)
+
 // Issue represents a trackable work item with dependencies.
 type Issue struct {
 	ID          string     `json:"id"`
@@ -80,12 +89,34 @@ type Issue struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 	ClosedAt    *time.Time `json:"closed_at,omitempty"`
 	Resolution  Resolution `json:"resolution,omitempty"`
+
+	// CreatedBy and UpdatedBy are the actor setting of whoever created the
+	// issue and whoever changed it last.
+	CreatedBy string `json:"created_by,omitempty"`
+	UpdatedBy string `json:"updated_by,omitempty"`
+
+	// DeletedAt is when the issue was moved to the trash, nil unless it is
+	// in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Version counts the writes to the issue in its database, starting at 1.
+	// UpdateIssue only saves an issue whose Version is the stored one, or 0.
+	Version int64 `json:"version,omitempty"`
+
+	// Extra holds fields from a newer export format that this version does not
+	// understand. They are stored verbatim and written back out on export.
+	Extra map[string]json.RawMessage `json:"-"`
 }
 
 // NewIssue creates a new issue with a hash-based ID and sensible defaults.
 func NewIssue(title string) *Issue {
+	return newIssue(title, defaultIDPrefix, defaultIDLength)
+}
+
+// newIssue creates a new issue whose ID has the given prefix and hash length.
+func newIssue(title, prefix string, length int) *Issue {
 	now := time.Now()
-	id := generateHashID("bl", title, "", now, 4)
+	id := generateHashID(prefix, title, "", now, length)
 
 	return &Issue{
 		ID:        id,
@@ -98,15 +129,41 @@ func NewIssue(title string) *Issue {
 	}
 }
 
-// Validate checks if the issue has valid field values.
+// childID returns the ID of the nth child of parentID, e.g. "bl-a3f8.2".
+func childID(parentID string, n int) string {
+	return fmt.Sprintf("%s.%d", parentID, n)
+}
+
+// ParseChildID splits a child ID like "bl-a3f8.2" into its parent ID and
+// number. ok is false for IDs that are not child IDs.
+func ParseChildID(id string) (parentID string, n int, ok bool) {
+	idx := strings.LastIndex(id, ".")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(id[idx+1:])
+	if err != nil || n < 1 || strconv.Itoa(n) != id[idx+1:] {
+		return "", 0, false
+	}
+	return id[:idx], n, true
+}
+
+// Validate checks if the issue has valid field values, allowing only the
+// built-in types and statuses.
 func (i *Issue) Validate() error {
+	return i.ValidateIn(DefaultVocabulary())
+}
+
+// ValidateIn checks if the issue has valid field values, allowing the types
+// and statuses of the given workspace vocabulary.
+func (i *Issue) ValidateIn(v *Vocabulary) error {
 	if strings.TrimSpace(i.Title) == "" {
 		return errors.New("title cannot be empty")
 	}
-	if !i.Status.Valid() {
+	if !v.ValidStatus(i.Status) {
 		return fmt.Errorf("invalid status: %q", i.Status)
 	}
-	if !i.Type.Valid() {
+	if !v.ValidType(i.Type) {
 		return fmt.Errorf("invalid issue type: %q", i.Type)
 	}
 	if i.Priority < 0 || i.Priority > 4 {
@@ -130,16 +187,39 @@ func generateHashID(prefix, title, description string, timestamp time.Time, leng
 	// Hash the content
 	hash := sha256.Sum256([]byte(content))
 
-	// Use enough bytes for the desired length
-	numBytes := 3 // 3 bytes gives us ~4.6 base36 chars
-	if length > 4 {
-		numBytes = 4
+	// Use enough bytes for the desired length: each base36 char carries
+	// ~5.17 bits, so 3 bytes cover 4 chars, 4 bytes cover 6, and so on.
+	numBytes := (length*517/100)/8 + 1
+	if numBytes > len(hash) {
+		numBytes = len(hash)
 	}
 
 	shortHash := encodeBase36(hash[:numBytes], length)
 	return fmt.Sprintf("%s-%s", prefix, shortHash)
 }
 
+// maxCollisionProbability is the highest chance, over the whole workspace, of
+// two issues having drawn the same ID that adaptiveIDLength will accept.
+const maxCollisionProbability = 0.25
+
+// collisionProbability estimates the chance that at least two of n random IDs
+// with length base36 characters are equal (the birthday bound).
+func collisionProbability(n, length int) float64 {
+	space := math.Pow(36, float64(length))
+	return 1 - math.Exp(-float64(n)*float64(n)/(2*space))
+}
+
+// adaptiveIDLength returns the shortest ID length, no shorter than minLength,
+// that keeps the collision probability acceptable once a workspace holding
+// count issues gains one more.
+func adaptiveIDLength(count, minLength int) int {
+	length := minLength
+	for length < maxIDLength && collisionProbability(count+1, length) > maxCollisionProbability {
+		length++
+	}
+	return length
+}
+
 // encodeBase36 converts a byte slice to a base36 string of specified length.
 func encodeBase36(data []byte, length int) string {
 	// Convert bytes to big integer
@@ -1,6 +1,8 @@
 package beadslite
 
 import (
+	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -308,3 +310,111 @@ func TestIssueValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateHashIDLength(t *testing.T) {
+	now := time.Now()
+	for length := minIDLength; length <= maxIDLength; length++ {
+		id := generateHashID("api", "Title", "", now, length)
+		if !strings.HasPrefix(id, "api-") {
+			t.Errorf("length %d: expected api- prefix, got %s", length, id)
+		}
+		if got := len(id) - len("api-"); got != length {
+			t.Errorf("length %d: got %d hash chars in %s", length, got, id)
+		}
+	}
+}
+
+func TestCollisionProbability(t *testing.T) {
+	tests := []struct {
+		n, length int
+		min, max  float64
+	}{
+		{1, 4, 0, 1e-6},
+		{100, 4, 0.002, 0.004},
+		{1000, 4, 0.2, 0.3},
+		{5000, 4, 0.99, 1},
+		{5000, 5, 0.1, 0.2},
+	}
+	for _, tt := range tests {
+		p := collisionProbability(tt.n, tt.length)
+		if p < tt.min || p > tt.max {
+			t.Errorf("collisionProbability(%d, %d) = %.4f, want %.4f-%.4f", tt.n, tt.length, p, tt.min, tt.max)
+		}
+	}
+}
+
+// TestCollisionProbability_MatchesGeneratedIDs checks the birthday estimate
+// against IDs actually produced by generateHashID. Inputs are fixed, so the
+// result is deterministic.
+func TestCollisionProbability_MatchesGeneratedIDs(t *testing.T) {
+	const (
+		length = 3
+		n      = 216 // expected collision probability ~0.39 in 36^3
+		trials = 300
+	)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	collided := 0
+	for trial := 0; trial < trials; trial++ {
+		seen := make(map[string]bool, n)
+		for i := 0; i < n; i++ {
+			id := generateHashID("bl", fmt.Sprintf("trial %d issue %d", trial, i), "", base, length)
+			if seen[id] {
+				collided++
+				break
+			}
+			seen[id] = true
+		}
+	}
+
+	observed := float64(collided) / trials
+	expected := collisionProbability(n, length)
+	if math.Abs(observed-expected) > 0.1 {
+		t.Errorf("observed collision rate %.3f, expected about %.3f", observed, expected)
+	}
+}
+
+func TestAdaptiveIDLength(t *testing.T) {
+	tests := []struct {
+		count, minLength, want int
+	}{
+		{0, 4, 4},
+		{500, 4, 4},
+		{2000, 4, 5},
+		{10000, 4, 6},
+		{10000, 8, 8},
+		{1 << 40, 4, maxIDLength},
+	}
+	for _, tt := range tests {
+		if got := adaptiveIDLength(tt.count, tt.minLength); got != tt.want {
+			t.Errorf("adaptiveIDLength(%d, %d) = %d, want %d", tt.count, tt.minLength, got, tt.want)
+		}
+	}
+}
+
+func TestParseChildID(t *testing.T) {
+	tests := []struct {
+		id     string
+		parent string
+		n      int
+		ok     bool
+	}{
+		{"bl-a3f8.1", "bl-a3f8", 1, true},
+		{"bl-a3f8.12", "bl-a3f8", 12, true},
+		{"bl-a3f8.1.2", "bl-a3f8.1", 2, true},
+		{"bl-a3f8", "", 0, false},
+		{"bl-a3f8.0", "", 0, false},
+		{"bl-a3f8.01", "", 0, false},
+		{"bl-a3f8.x", "", 0, false},
+		{".1", "", 0, false},
+	}
+	for _, tt := range tests {
+		parent, n, ok := ParseChildID(tt.id)
+		if parent != tt.parent || n != tt.n || ok != tt.ok {
+			t.Errorf("ParseChildID(%q) = (%q, %d, %v), want (%q, %d, %v)", tt.id, parent, n, ok, tt.parent, tt.n, tt.ok)
+		}
+	}
+	if got := childID("bl-a3f8", 3); got != "bl-a3f8.3" {
+		t.Errorf("childID = %q, want bl-a3f8.3", got)
+	}
+}
@@ -2,13 +2,19 @@ package beadslite
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"sort"
+	"strings"
 	"time"
+
+	"github.com/ncruces/go-sqlite3"
 )
 
 // IssueExport represents an issue with embedded dependencies for JSONL export.
@@ -24,7 +30,144 @@ type IssueExport struct {
 	UpdatedAt    time.Time          `json:"updated_at"`
 	ClosedAt     *time.Time         `json:"closed_at,omitempty"`
 	Resolution   Resolution         `json:"resolution,omitempty"`
+	CreatedBy    string             `json:"created_by,omitempty"`
+	UpdatedBy    string             `json:"updated_by,omitempty"`
+	DeletedAt    *time.Time         `json:"deleted_at,omitempty"` // set for issues in the trash
+	Version      int64              `json:"version,omitempty"`    // of the serving database, in API, MCP and CLI output; not in export files
 	Dependencies []DependencyExport `json:"dependencies"`
+
+	// Extra holds fields this version does not know about, so that records
+	// written by a newer exporter survive an import/export round trip.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// exportFields is the set of JSON field names IssueExport understands.
+var exportFields = jsonFieldNames(reflect.TypeOf(IssueExport{}))
+
+// jsonFieldNames returns the JSON names of a struct type's serialized fields.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+// UnmarshalJSON decodes a record, keeping any unknown fields in Extra.
+func (e *IssueExport) UnmarshalJSON(data []byte) error {
+	type plain IssueExport
+	if err := json.Unmarshal(data, (*plain)(e)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name := range fields {
+		if exportFields[name] {
+			delete(fields, name)
+		}
+	}
+	e.Extra = nil
+	if len(fields) > 0 {
+		e.Extra = fields
+	}
+	return nil
+}
+
+// MarshalJSON encodes a record, appending any preserved unknown fields after
+// the known ones in sorted order. Records without extras encode exactly as a
+// plain struct would, keeping exports byte-for-byte stable.
+func (e IssueExport) MarshalJSON() ([]byte, error) {
+	type plain IssueExport
+	data, err := json.Marshal(plain(e))
+	if err != nil || len(e.Extra) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(e.Extra))
+	for name := range e.Extra {
+		if !exportFields[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1]) // drop the closing brace
+	for _, name := range names {
+		key, _ := json.Marshal(name)
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(e.Extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// FormatName identifies a beads-lite export in its header record.
+const FormatName = "beads-lite"
+
+// FormatVersion is the export format version written by this build.
+// Imports accept any version up to and including it.
+const FormatVersion = 1
+
+// ExportHeader is the optional first record of a JSONL export. It lets an
+// importer know which format version (and exporter) produced the file.
+type ExportHeader struct {
+	Format          string    `json:"format"` // always FormatName
+	FormatVersion   int       `json:"format_version"`
+	ExporterVersion string    `json:"exporter_version,omitempty"`
+	IDPrefix        string    `json:"id_prefix,omitempty"`
+	ExportedAt      time.Time `json:"exported_at"`
+}
+
+// newExportHeader describes an export produced by this build from a
+// workspace whose IDs use prefix.
+func newExportHeader(prefix string) ExportHeader {
+	return ExportHeader{
+		Format:          FormatName,
+		FormatVersion:   FormatVersion,
+		ExporterVersion: Version,
+		IDPrefix:        prefix,
+		ExportedAt:      time.Now().UTC(),
+	}
+}
+
+// parseHeader reports whether line is a header record and, if so, decodes it.
+// A header is recognized by its "format" field, which issue records never have.
+func parseHeader(line []byte) (*ExportHeader, bool) {
+	var probe struct {
+		Format *string `json:"format"`
+	}
+	if err := json.Unmarshal(line, &probe); err != nil || probe.Format == nil {
+		return nil, false
+	}
+	var header ExportHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, false
+	}
+	return &header, true
+}
+
+// Validate checks that the header describes a format this build can read.
+func (h *ExportHeader) Validate() error {
+	if h.Format != FormatName {
+		return fmt.Errorf("unknown export format %q", h.Format)
+	}
+	if h.FormatVersion < 1 {
+		return fmt.Errorf("invalid format version %d", h.FormatVersion)
+	}
+	if h.FormatVersion > FormatVersion {
+		return fmt.Errorf("export format version %d is newer than supported version %d (exported by bl %s); upgrade bl to import it",
+			h.FormatVersion, FormatVersion, h.ExporterVersion)
+	}
+	return nil
 }
 
 // DependencyExport represents a dependency relationship for JSONL export.
@@ -35,10 +178,62 @@ type DependencyExport struct {
 
 // ImportStats tracks the results of an import operation.
 type ImportStats struct {
-	Created int
-	Updated int
+	Created    int
+	Updated    int
+	Collisions []IDRemap      // incoming issues whose ID was already taken by a different local issue
+	Rejected   []*ImportError // records skipped by a lenient import, in file order
+	Header     *ExportHeader  // the file's header record, if it had one
+}
+
+// ImportError describes a record that a lenient import rejected.
+type ImportError struct {
+	Line int    // 1-based line number in the input
+	ID   string // issue ID, empty if the line could not be parsed
+	Raw  []byte // the line as read, for writing a rejects file
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// IDRemap records an incoming issue whose ID collided with a different local issue.
+// NewID is empty when the collision was detected but not remapped.
+type IDRemap struct {
+	OldID string
+	NewID string
+	Title string
+}
+
+// ImportOptions controls how ImportFromJSONLWithOptions treats incoming records.
+type ImportOptions struct {
+	// RemapCollisions assigns a fresh ID to incoming issues that collide with a
+	// different local issue, instead of overwriting the local issue.
+	RemapCollisions bool
+
+	// ContinueOnError skips records that fail to parse, validate, or resolve
+	// their dependencies, reporting them in ImportStats.Rejected instead of
+	// aborting the whole import.
+	ContinueOnError bool
+
+	// Progress, if set, is called periodically as records are processed.
+	Progress func(ImportProgress)
 }
 
+// ImportProgress reports how far an import has advanced.
+type ImportProgress struct {
+	Phase string // "scan", "issues", or "dependencies"
+	Done  int
+	Total int // 0 while scanning, until the record count is known
+}
+
+// progressInterval is the number of records processed between progress reports.
+const progressInterval = 1000
+
 // toIssueExport converts an Issue and its dependencies to an IssueExport.
 func toIssueExport(issue *Issue, deps []*Dependency) IssueExport {
 	export := IssueExport{
@@ -52,7 +247,12 @@ func toIssueExport(issue *Issue, deps []*Dependency) IssueExport {
 		UpdatedAt:    issue.UpdatedAt,
 		ClosedAt:     issue.ClosedAt,
 		Resolution:   issue.Resolution,
+		CreatedBy:    issue.CreatedBy,
+		UpdatedBy:    issue.UpdatedBy,
+		DeletedAt:    issue.DeletedAt,
+		Version:      issue.Version,
 		Dependencies: make([]DependencyExport, len(deps)),
+		Extra:        issue.Extra,
 	}
 	for i, dep := range deps {
 		export.Dependencies[i] = DependencyExport{
@@ -79,33 +279,156 @@ func WriteIssuesAsJSONL(issues []*Issue, allDeps map[string][]*Dependency, w io.
 // ExportToJSONL writes all issues to the writer in JSONL format.
 // Issues are sorted by ID for deterministic output (git-friendly).
 func ExportToJSONL(store *Store, w io.Writer) error {
-	issues, err := store.ListIssues()
+	return ExportToJSONLWithOptions(store, w, ExportOptions{Priority: -1})
+}
+
+// ExportOptions selects a subset of issues for ExportToJSONLWithOptions.
+// The zero value (with Priority set to -1) exports everything; each set field
+// narrows the selection further.
+type ExportOptions struct {
+	Status     string
+	Type       string
+	Priority   int // -1 for any priority
+	Resolution string
+	IDs        []string  // export only these issues
+	Since      time.Time // export only issues updated at or after this time
+
+	// WithBlockers adds every issue the selection transitively depends on,
+	// whether or not it matches the filters, so the export can be imported
+	// elsewhere without dangling dependencies.
+	WithBlockers bool
+
+	// IncludeTrash exports the issues in the trash too, with their
+	// deleted_at, and the dependencies on them.
+	IncludeTrash bool
+
+	// Header writes an ExportHeader as the first line. It is off by default
+	// because its timestamp would change every export and add noise to diffs.
+	Header bool
+}
+
+// ExportToJSONLWithOptions writes the issues selected by opts to the writer
+// in JSONL format, sorted by ID.
+func ExportToJSONLWithOptions(store *Store, w io.Writer, opts ExportOptions) error {
+	return ExportToJSONLContext(context.Background(), store, w, opts)
+}
+
+// ExportToJSONLContext is ExportToJSONLWithOptions with a context, which
+// stops the export's queries when it is done.
+func ExportToJSONLContext(ctx context.Context, store *Store, w io.Writer, opts ExportOptions) error {
+	issues, err := store.listIssues(ctx, opts.IncludeTrash)
 	if err != nil {
 		return fmt.Errorf("list issues: %w", err)
 	}
 
 	// Batch-fetch all dependencies to avoid N+1 queries
-	allDeps, err := store.GetAllDependencies()
+	allDeps, err := store.allDependencies(ctx, opts.IncludeTrash)
 	if err != nil {
 		return fmt.Errorf("get all dependencies: %w", err)
 	}
 
+	selected := selectForExport(issues, allDeps, opts)
+
 	// Sort by ID for deterministic output
-	sort.Slice(issues, func(i, j int) bool {
-		return issues[i].ID < issues[j].ID
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].ID < selected[j].ID
 	})
 
-	return WriteIssuesAsJSONL(issues, allDeps, w)
+	// Versions count writes to this database only: in a file they would
+	// change on every write without meaning anything to an importer
+	for _, issue := range selected {
+		issue.Version = 0
+	}
+
+	if opts.Header {
+		prefix, err := store.IDPrefixContext(ctx)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(newExportHeader(prefix)); err != nil {
+			return fmt.Errorf("encode header: %w", err)
+		}
+	}
+	return WriteIssuesAsJSONL(selected, allDeps, w)
+}
+
+// selectForExport applies the export filters and, if requested, expands the
+// selection with the transitive closure of blockers.
+func selectForExport(issues []*Issue, allDeps map[string][]*Dependency, opts ExportOptions) []*Issue {
+	filter := IssueFilter{
+		Status:     Status(opts.Status),
+		Type:       IssueType(opts.Type),
+		Priority:   priorityFlag(opts.Priority),
+		Resolution: Resolution(opts.Resolution),
+	}
+	selected := filter.Apply(issues)
+
+	if len(opts.IDs) > 0 || !opts.Since.IsZero() {
+		wanted := make(map[string]bool, len(opts.IDs))
+		for _, id := range opts.IDs {
+			wanted[id] = true
+		}
+		var narrowed []*Issue
+		for _, issue := range selected {
+			if len(wanted) > 0 && !wanted[issue.ID] {
+				continue
+			}
+			if !opts.Since.IsZero() && issue.UpdatedAt.Before(opts.Since) {
+				continue
+			}
+			narrowed = append(narrowed, issue)
+		}
+		selected = narrowed
+	}
+
+	if !opts.WithBlockers {
+		return selected
+	}
+
+	byID := make(map[string]*Issue, len(issues))
+	for _, issue := range issues {
+		byID[issue.ID] = issue
+	}
+	included := make(map[string]bool, len(selected))
+	queue := make([]string, 0, len(selected))
+	for _, issue := range selected {
+		included[issue.ID] = true
+		queue = append(queue, issue.ID)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, dep := range allDeps[id] {
+			blocker, ok := byID[dep.DependsOnID]
+			if dep.Type != DepBlocks || !ok || included[blocker.ID] {
+				continue
+			}
+			included[blocker.ID] = true
+			selected = append(selected, blocker)
+			queue = append(queue, blocker.ID)
+		}
+	}
+	return selected
 }
 
 // ExportToFile writes all issues to the specified file in JSONL format.
 func ExportToFile(store *Store, path string) error {
+	return ExportToFileWithOptions(store, path, ExportOptions{Priority: -1})
+}
+
+// ExportToFileWithOptions writes the issues selected by opts to the specified file.
+func ExportToFileWithOptions(store *Store, path string, opts ExportOptions) error {
+	return ExportToFileContext(context.Background(), store, path, opts)
+}
+
+// ExportToFileContext is ExportToFileWithOptions with a context.
+func ExportToFileContext(ctx context.Context, store *Store, path string, opts ExportOptions) error {
 	f, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("create file: %w", err)
 	}
 
-	if err := ExportToJSONL(store, f); err != nil {
+	if err := ExportToJSONLContext(ctx, store, f, opts); err != nil {
 		f.Close()
 		return err
 	}
@@ -120,105 +443,632 @@ func ExportToFile(store *Store, path string) error {
 // Two-phase import: issues first, then dependencies. This handles JSONL files
 // where dependencies may reference issues that appear later in the file
 // (e.g., alphabetically sorted exports where bl-g9d5 depends on bl-it9o).
+//
+// Records are streamed rather than buffered: the input is read once to
+// validate it and then re-read for each phase, so memory use is bounded by
+// the longest line plus the set of IDs in the file. Non-seekable readers
+// are first spooled to a temp file.
 func ImportFromJSONL(store *Store, r io.Reader) (*ImportStats, error) {
-	stats := &ImportStats{}
+	return ImportFromJSONLWithOptions(store, r, ImportOptions{})
+}
+
+// ImportFromJSONLWithOptions is ImportFromJSONL with explicit options.
+//
+// Before writing anything, each incoming issue is checked against the local
+// issue with the same ID. Short hash IDs make it possible for two repos to
+// mint the same ID for unrelated issues, so an incoming record whose title or
+// created_at differs from the local issue is treated as a collision rather
+// than an update. Collisions are always reported in ImportStats; with
+// RemapCollisions set, the incoming issue gets a fresh ID and every dependency
+// in the file that referenced the old ID is rewritten to the new one.
+//
+// With ContinueOnError set, a bad record no longer aborts the import. Lines
+// that fail to parse or validate, or whose dependencies point at IDs found
+// neither in the file nor in the database, are skipped and reported in
+// ImportStats.Rejected; everything else is imported in the same transaction.
+// Rejection cascades: a record that depends on a rejected record (and not on
+// an existing local issue of the same ID) is rejected too.
+func ImportFromJSONLWithOptions(store *Store, r io.Reader, opts ImportOptions) (*ImportStats, error) {
+	return ImportFromJSONLContext(context.Background(), store, r, opts)
+}
+
+// ImportFromJSONLContext is ImportFromJSONLWithOptions with a context. If
+// ctx is done before the import commits, the transaction is rolled back and
+// nothing is imported.
+func ImportFromJSONLContext(ctx context.Context, store *Store, r io.Reader, opts ImportOptions) (*ImportStats, error) {
+	src, cleanup, err := rewindable(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	vocab, err := store.VocabularyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	run := &importRun{
+		ctx:      ctx,
+		store:    store,
+		src:      src,
+		opts:     opts,
+		ids:      make(map[string]bool),
+		rejected: make(map[int]*ImportError),
+		renamed:  make(map[string]string),
+		vocab:    vocab,
+		stats:    &ImportStats{},
+	}
+
+	// Validate every line and collect the file's IDs before opening the
+	// transaction, so a malformed file never holds the write lock
+	if err := run.scan(); err != nil {
+		return nil, err
+	}
+
+	// Process within a transaction
+	err = store.WithTransactionContext(ctx, func(tx *StoreTx) error {
+		// Every pass from here on reads and writes through the transaction
+		run.store = tx.Store
+
+		// Phase 0: Detect (and optionally remap) ID collisions
+		if err := run.resolveCollisions(); err != nil {
+			return err
+		}
 
-	// Pre-scan all lines to avoid transaction timeout during I/O
-	var lines [][]byte
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) > 0 {
-			// Make a copy since scanner reuses buffer
-			lineCopy := make([]byte, len(line))
-			copy(lineCopy, line)
-			lines = append(lines, lineCopy)
+		// Lenient imports drop records whose dependencies cannot be satisfied
+		if opts.ContinueOnError {
+			if err := run.checkReferences(); err != nil {
+				return err
+			}
+		}
+
+		// Phase 1: Create/update all issues (without dependencies)
+		if err := run.importIssues(); err != nil {
+			return err
 		}
+
+		// Phase 2: Clear old dependencies and add new ones
+		// Now all issues exist, so FK constraints will be satisfied
+		return run.importDependencies()
+	})
+
+	if err != nil {
+		return nil, err
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("read error: %w", err)
+	run.stats.Rejected = run.rejectedByLine()
+	return run.stats, nil
+}
+
+// importRun holds the state of a single import as it makes repeated passes
+// over the input.
+type importRun struct {
+	ctx      context.Context // stops the import between records
+	store    *Store
+	src      io.ReadSeeker
+	opts     ImportOptions
+	ids      map[string]bool      // IDs of accepted records in the file
+	rejected map[int]*ImportError // rejected records, keyed by line number
+	renamed  map[string]string    // old ID -> new ID for remapped collisions
+	accepted int                  // number of records not (yet) rejected
+	header   int                  // line number of the header record, 0 if none
+	vocab    *Vocabulary          // the workspace's types and statuses
+	stats    *ImportStats
+}
+
+// reject handles a per-record failure. Strict imports return it as a fatal
+// *ImportError; lenient imports record it against the line and carry on.
+// Failures of the database, other than a record breaking one of its
+// constraints, or of ctx are not the record's fault, and fail the import
+// either way.
+func (run *importRun) reject(lineNum int, raw []byte, id string, what string, err error) error {
+	var dbErr *sqlite3.Error
+	if (errors.As(err, &dbErr) && !errors.Is(err, sqlite3.CONSTRAINT)) || run.ctx.Err() != nil {
+		return fmt.Errorf("line %d: %s: %w", lineNum, what, err)
 	}
+	rejected := &ImportError{Line: lineNum, ID: id, Raw: raw, Err: fmt.Errorf("%s: %w", what, err)}
+	if !run.opts.ContinueOnError {
+		return rejected
+	}
+	run.rejected[lineNum] = rejected
+	run.accepted--
+	return nil
+}
 
-	// Parse all exports upfront
-	exports := make([]IssueExport, 0, len(lines))
-	for lineNum, line := range lines {
+// records streams every record that has not been rejected, with remapped
+// IDs already applied.
+func (run *importRun) records(fn func(lineNum int, raw []byte, export *IssueExport) error) error {
+	return forEachLine(run.src, func(lineNum int, raw []byte) error {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		if run.rejected[lineNum] != nil || lineNum == run.header {
+			return nil
+		}
 		var export IssueExport
-		if err := json.Unmarshal(line, &export); err != nil {
-			return nil, fmt.Errorf("line %d: parse error: %w", lineNum+1, err)
+		if err := json.Unmarshal(raw, &export); err != nil {
+			return fmt.Errorf("line %d: parse error: %w", lineNum, err)
 		}
-		exports = append(exports, export)
+		remapExport(&export, run.renamed)
+		return fn(lineNum, raw, &export)
+	})
+}
+
+// scan parses and validates every line, collecting the IDs the file defines.
+func (run *importRun) scan() error {
+	scanned := 0
+	err := forEachLine(run.src, func(lineNum int, raw []byte) error {
+		if err := run.ctx.Err(); err != nil {
+			return err
+		}
+		// Only the first record may be a header
+		if scanned == 0 && run.header == 0 {
+			if header, ok := parseHeader(raw); ok {
+				if err := header.Validate(); err != nil {
+					return &ImportError{Line: lineNum, Raw: raw, Err: err}
+				}
+				run.header = lineNum
+				run.stats.Header = header
+				return nil
+			}
+		}
+
+		scanned++
+		run.accepted++
+		run.opts.report("scan", scanned, 0)
+
+		var export IssueExport
+		if err := json.Unmarshal(raw, &export); err != nil {
+			return run.reject(lineNum, raw, "", "parse error", err)
+		}
+		if err := export.validate(run.vocab); err != nil {
+			return run.reject(lineNum, raw, export.ID, "invalid record", err)
+		}
+		run.ids[export.ID] = true
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	run.opts.finish("scan", scanned, scanned)
+	return nil
+}
 
-	// Process within a transaction
-	err := store.WithTransaction(func() error {
-		// Phase 1: Create/update all issues (without dependencies)
-		for i, export := range exports {
-			lineNum := i + 1
+// resolveCollisions finds incoming issues whose ID is already used by a different
+// local issue. When RemapCollisions is set, each colliding issue is assigned a
+// fresh ID that is unused both locally and in the file, and later passes see the
+// record (and every dependency on it) under the new ID.
+func (run *importRun) resolveCollisions() error {
+	err := run.records(func(lineNum int, raw []byte, export *IssueExport) error {
+		existing, err := run.store.getIssue(run.ctx, export.ID, true)
+		if errors.Is(err, ErrIssueNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: check existing: %w", lineNum, err)
+		}
+		if !isCollision(existing, export) {
+			return nil
+		}
 
-			existing, err := store.GetIssue(export.ID)
-			if err != nil && !errors.Is(err, ErrIssueNotFound) {
-				return fmt.Errorf("line %d: check existing: %w", lineNum, err)
+		collision := IDRemap{OldID: export.ID, Title: export.Title}
+		if run.opts.RemapCollisions {
+			newID, err := freshID(run.ctx, run.store, export, run.ids)
+			if err != nil {
+				return fmt.Errorf("line %d: remap %s: %w", lineNum, export.ID, err)
 			}
+			run.ids[newID] = true
+			run.renamed[export.ID] = newID
+			collision.NewID = newID
+		}
+		run.stats.Collisions = append(run.stats.Collisions, collision)
+		return nil
+	})
+	if err != nil || !run.opts.RemapCollisions {
+		return err
+	}
+	return run.followRenamedParents()
+}
 
-			issue := &Issue{
-				ID:          export.ID,
-				Title:       export.Title,
-				Description: export.Description,
-				Status:      export.Status,
-				Priority:    export.Priority,
-				Type:        export.Type,
-				CreatedAt:   export.CreatedAt,
-				UpdatedAt:   export.UpdatedAt,
-				ClosedAt:    export.ClosedAt,
-				Resolution:  export.Resolution,
+// followRenamedParents gives the children of every remapped issue IDs under
+// its new ID, so "bl-a3f8.1" moves along when "bl-a3f8" becomes "bl-k2m9".
+// It repeats until no more records are renamed, which carries grandchildren
+// along whatever order the file lists them in.
+func (run *importRun) followRenamedParents() error {
+	for changed := len(run.renamed) > 0; changed; {
+		changed = false
+		err := run.records(func(lineNum int, raw []byte, export *IssueExport) error {
+			parentID, n, ok := ParseChildID(export.ID)
+			if !ok {
+				return nil
 			}
+			newParentID, renamed := run.renamed[parentID]
+			if !renamed {
+				return nil
+			}
+			newID := childID(newParentID, n)
+			if run.ids[newID] {
+				var err error
+				if newID, err = freshChildID(run.ctx, run.store, newParentID, run.ids); err != nil {
+					return fmt.Errorf("line %d: remap %s: %w", lineNum, export.ID, err)
+				}
+			}
+			run.ids[newID] = true
+			run.renamed[export.ID] = newID
+			changed = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			if existing != nil {
-				if err := store.UpdateIssue(issue); err != nil {
-					return fmt.Errorf("line %d: update issue: %w", lineNum, err)
+// checkReferences rejects records with local dependencies on IDs that exist
+// neither in the file nor in the database, repeating until no further records are
+// rejected so that dependents of rejected records are caught as well.
+func (run *importRun) checkReferences() error {
+	known := make(map[string]bool) // dependency targets already found in the database
+	for changed := true; changed; {
+		changed = false
+		err := run.records(func(lineNum int, raw []byte, export *IssueExport) error {
+			for _, dep := range export.Dependencies {
+				if run.ids[dep.DependsOn] || known[dep.DependsOn] {
+					continue
+				}
+				// Cross-workspace references are resolved when read, not on import
+				if workspace, _ := ParseIssueRef(dep.DependsOn); workspace != "" {
+					continue
 				}
-				stats.Updated++
-			} else {
-				if err := store.CreateIssue(issue); err != nil {
-					return fmt.Errorf("line %d: create issue: %w", lineNum, err)
+				_, err := run.store.getIssue(run.ctx, dep.DependsOn, true)
+				if err == nil {
+					known[dep.DependsOn] = true
+					continue
 				}
-				stats.Created++
+				if !errors.Is(err, ErrIssueNotFound) {
+					return fmt.Errorf("line %d: check dependency: %w", lineNum, err)
+				}
+
+				delete(run.ids, export.ID)
+				changed = true
+				return run.reject(lineNum, raw, export.ID, "dependency "+dep.DependsOn, ErrIssueNotFound)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importIssues creates or updates every accepted issue. A lenient import
+// that rejects a record here must also reject the records depending on it,
+// some of which may already be written, so it undoes the pass, drops them
+// with checkReferences and writes the remaining records again.
+func (run *importRun) importIssues() error {
+	for {
+		if _, err := run.store.exec(run.ctx, `SAVEPOINT import_issues`); err != nil {
+			return fmt.Errorf("import issues: %w", err)
+		}
+		rejected, created, updated := len(run.rejected), run.stats.Created, run.stats.Updated
+		if err := run.writeIssues(); err != nil {
+			return err
+		}
+		if len(run.rejected) == rejected {
+			if _, err := run.store.exec(run.ctx, `RELEASE import_issues`); err != nil {
+				return fmt.Errorf("import issues: %w", err)
 			}
+			return nil
 		}
 
-		// Phase 2: Clear old dependencies and add new ones
-		// Now all issues exist, so FK constraints will be satisfied
-		for i, export := range exports {
-			lineNum := i + 1
+		if _, err := run.store.exec(run.ctx, `ROLLBACK TO import_issues`); err != nil {
+			return fmt.Errorf("import issues: %w", err)
+		}
+		if _, err := run.store.exec(run.ctx, `RELEASE import_issues`); err != nil {
+			return fmt.Errorf("import issues: %w", err)
+		}
+		run.stats.Created, run.stats.Updated = created, updated
+		if err := run.checkReferences(); err != nil {
+			return err
+		}
+	}
+}
 
-			// Clear existing dependencies before re-adding
-			if err := store.RemoveAllDependencies(export.ID); err != nil {
-				return fmt.Errorf("line %d: remove deps: %w", lineNum, err)
+// writeIssues makes one pass of importIssues.
+func (run *importRun) writeIssues() error {
+	done, total := 0, run.accepted
+	err := run.records(func(lineNum int, raw []byte, export *IssueExport) error {
+		existing, err := run.store.getIssue(run.ctx, export.ID, true)
+		if err != nil && !errors.Is(err, ErrIssueNotFound) {
+			return fmt.Errorf("line %d: check existing: %w", lineNum, err)
+		}
+
+		issue := export.toIssue()
+		if existing != nil {
+			if err := run.store.replaceIssue(run.ctx, issue); err != nil {
+				delete(run.ids, export.ID)
+				return run.reject(lineNum, raw, export.ID, "update issue", err)
+			}
+			run.stats.Updated++
+		} else {
+			if err := run.store.CreateIssueContext(run.ctx, issue); err != nil {
+				delete(run.ids, export.ID)
+				return run.reject(lineNum, raw, export.ID, "create issue", err)
 			}
+			run.stats.Created++
+		}
 
-			for _, dep := range export.Dependencies {
-				if err := store.AddDependency(export.ID, dep.DependsOn, dep.Type); err != nil {
-					return fmt.Errorf("line %d: add dependency: %w", lineNum, err)
+		done++
+		run.opts.report("issues", done, total)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	run.opts.finish("issues", done, total)
+	return nil
+}
+
+// importDependencies replaces the dependencies of every accepted issue with
+// those listed in its record. By now each record has been written, and its
+// dependencies checked by scan and checkReferences, so a failure here is not
+// the record's alone to reject: it fails the import, rolling back everything.
+func (run *importRun) importDependencies() error {
+	done, total := 0, run.accepted
+	err := run.records(func(lineNum int, raw []byte, export *IssueExport) error {
+		// Clear existing dependencies before re-adding
+		if err := run.store.RemoveAllDependenciesContext(run.ctx, export.ID); err != nil {
+			return fmt.Errorf("line %d: remove deps: %w", lineNum, err)
+		}
+
+		for _, dep := range export.Dependencies {
+			if err := run.store.addDependency(run.ctx, export.ID, dep.DependsOn, dep.Type, true); err != nil {
+				// A strict import does not check references up front
+				if errors.Is(err, ErrIssueNotFound) {
+					return &ImportError{Line: lineNum, ID: export.ID, Raw: raw, Err: fmt.Errorf("add dependency: %w", err)}
 				}
+				return fmt.Errorf("line %d: add dependency: %w", lineNum, err)
 			}
 		}
 
+		done++
+		run.opts.report("dependencies", done, total)
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	run.opts.finish("dependencies", done, total)
+	return nil
+}
 
+// rejectedByLine returns the rejected records in file order.
+func (run *importRun) rejectedByLine() []*ImportError {
+	if len(run.rejected) == 0 {
+		return nil
+	}
+	rejected := make([]*ImportError, 0, len(run.rejected))
+	for _, e := range run.rejected {
+		rejected = append(rejected, e)
+	}
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].Line < rejected[j].Line
+	})
+	return rejected
+}
+
+// toIssue converts an imported record to an Issue, dropping its dependencies.
+func (e *IssueExport) toIssue() *Issue {
+	return &Issue{
+		ID:          e.ID,
+		Title:       e.Title,
+		Description: e.Description,
+		Status:      e.Status,
+		Priority:    e.Priority,
+		Type:        e.Type,
+		CreatedAt:   e.CreatedAt,
+		UpdatedAt:   e.UpdatedAt,
+		ClosedAt:    e.ClosedAt,
+		Resolution:  e.Resolution,
+		CreatedBy:   e.CreatedBy,
+		UpdatedBy:   e.UpdatedBy,
+		DeletedAt:   e.DeletedAt,
+		Extra:       e.Extra,
+	}
+}
+
+// validate checks an imported record's issue fields, against the importing
+// workspace's types and statuses, and its dependencies.
+func (e *IssueExport) validate(vocab *Vocabulary) error {
+	if e.ID == "" {
+		return errors.New("id cannot be empty")
+	}
+	if err := e.toIssue().ValidateIn(vocab); err != nil {
+		return err
+	}
+	seen := make(map[DependencyExport]bool, len(e.Dependencies))
+	for _, dep := range e.Dependencies {
+		if err := NewDependency(e.ID, dep.DependsOn, dep.Type).Validate(); err != nil {
+			return err
+		}
+		if seen[dep] {
+			return fmt.Errorf("duplicate dependency: %s %s", dep.Type, dep.DependsOn)
+		}
+		seen[dep] = true
+	}
+	return nil
+}
+
+// rewindable returns a seekable view of r so the import can make several passes
+// over it. Readers that are already seekable and positioned at the start are used
+// directly; anything else (such as a pipe on stdin) is spooled to a temp file.
+// The returned cleanup function must always be called.
+func rewindable(r io.Reader) (io.ReadSeeker, func(), error) {
+	if rs, ok := r.(io.ReadSeeker); ok {
+		if pos, err := rs.Seek(0, io.SeekCurrent); err == nil && pos == 0 {
+			return rs, func() {}, nil
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "bl-import-*.jsonl")
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("create spool file: %w", err)
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("read error: %w", err)
+	}
+	return tmp, cleanup, nil
+}
+
+// forEachLine rewinds src and streams it one line at a time, calling fn with
+// each non-blank line (whitespace trimmed) and its 1-based line number.
+// Lines may be arbitrarily long; only one line is held in memory at a time.
+func forEachLine(src io.ReadSeeker, fn func(lineNum int, line []byte) error) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind input: %w", err)
+	}
+
+	reader := bufio.NewReader(src)
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("read error: %w", readErr)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if err := fn(lineNum, line); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
 	}
-	return stats, nil
+}
+
+// report forwards progress to the Progress callback, if any, every
+// progressInterval records.
+func (o ImportOptions) report(phase string, done, total int) {
+	if o.Progress != nil && done > 0 && done%progressInterval == 0 {
+		o.Progress(ImportProgress{Phase: phase, Done: done, Total: total})
+	}
+}
+
+// finish reports the final count for a completed phase.
+func (o ImportOptions) finish(phase string, done, total int) {
+	if o.Progress != nil {
+		o.Progress(ImportProgress{Phase: phase, Done: done, Total: total})
+	}
+}
+
+// remapExport rewrites an incoming record's ID and dependency targets
+// according to renamed (old ID -> new ID).
+func remapExport(export *IssueExport, renamed map[string]string) {
+	if len(renamed) == 0 {
+		return
+	}
+	if newID, ok := renamed[export.ID]; ok {
+		export.ID = newID
+	}
+	for i, dep := range export.Dependencies {
+		if newID, ok := renamed[dep.DependsOn]; ok {
+			export.Dependencies[i].DependsOn = newID
+		}
+	}
+}
+
+// isCollision reports whether an incoming record describes a different issue
+// than the local one sharing its ID: a different created_at or title means
+// the ID was reused, not that the issue was edited.
+func isCollision(existing *Issue, incoming *IssueExport) bool {
+	return existing.Title != incoming.Title || !existing.CreatedAt.Equal(incoming.CreatedAt)
+}
+
+// freshID generates a new ID with the same prefix and hash length as the
+// incoming issue's ID that is unused both locally and in the import file.
+// A child ID gets the next free number under the same parent instead.
+func freshID(ctx context.Context, store *Store, export *IssueExport, taken map[string]bool) (string, error) {
+	if parentID, _, ok := ParseChildID(export.ID); ok {
+		return freshChildID(ctx, store, parentID, taken)
+	}
+
+	prefix, hash := "bl", export.ID
+	if idx := strings.LastIndex(export.ID, "-"); idx >= 0 {
+		prefix, hash = export.ID[:idx], export.ID[idx+1:]
+	}
+	length := max(len(hash), 4)
+
+	now := time.Now()
+	for attempt := 0; attempt < 100; attempt++ {
+		// Offset the timestamp to act as a nonce for each retry
+		id := generateHashID(prefix, export.Title, export.Description, now.Add(time.Duration(attempt)), length)
+		if taken[id] {
+			continue
+		}
+		if _, err := store.getIssue(ctx, id, true); errors.Is(err, ErrIssueNotFound) {
+			return id, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no free ID found")
+}
+
+// freshChildID returns the first child ID under parentID that is past the
+// local counter and unused both locally and in the import file.
+func freshChildID(ctx context.Context, store *Store, parentID string, taken map[string]bool) (string, error) {
+	last, err := store.childCounter(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+	for n := last + 1; n <= last+maxIDAttempts; n++ {
+		id := childID(parentID, n)
+		if taken[id] {
+			continue
+		}
+		if _, err := store.getIssue(ctx, id, true); errors.Is(err, ErrIssueNotFound) {
+			return id, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.New("no free child ID found")
+}
+
+// WriteRejectedLines writes the original text of each rejected record, one per
+// line, so the file can be corrected and imported again.
+func WriteRejectedLines(rejected []*ImportError, w io.Writer) error {
+	for _, e := range rejected {
+		if _, err := fmt.Fprintf(w, "%s\n", e.Raw); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // ImportFromFile reads issues from the specified file in JSONL format.
 func ImportFromFile(store *Store, path string) (*ImportStats, error) {
+	return ImportFromFileWithOptions(store, path, ImportOptions{})
+}
+
+// ImportFromFileWithOptions is ImportFromFile with explicit options.
+func ImportFromFileWithOptions(store *Store, path string, opts ImportOptions) (*ImportStats, error) {
+	return ImportFromFileContext(context.Background(), store, path, opts)
+}
+
+// ImportFromFileContext is ImportFromFileWithOptions with a context.
+func ImportFromFileContext(ctx context.Context, store *Store, path string, opts ImportOptions) (*ImportStats, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("open file: %w", err)
 	}
 	defer f.Close()
 
-	return ImportFromJSONL(store, f)
+	return ImportFromJSONLContext(ctx, store, f, opts)
 }
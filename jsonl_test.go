@@ -2,9 +2,15 @@ package beadslite
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -78,6 +84,74 @@ func TestExportToJSONL(t *testing.T) {
 	if !strings.Contains(lines[1], `"type":"blocks"`) {
 		t.Errorf("second line should have blocks dependency type: %s", lines[1])
 	}
+	if strings.Contains(output, `"version"`) {
+		t.Errorf("export files should leave out the database's versions: %s", output)
+	}
+}
+
+func TestExportToJSONLWithOptions(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	issues := []*Issue{
+		{ID: "bl-base", Title: "Base", Status: StatusClosed, Priority: 2, Type: IssueTypeTask, CreatedAt: old, UpdatedAt: old},
+		{ID: "bl-midl", Title: "Middle", Status: StatusOpen, Priority: 2, Type: IssueTypeTask, CreatedAt: old, UpdatedAt: old},
+		{ID: "bl-bug1", Title: "Bug", Status: StatusOpen, Priority: 1, Type: IssueTypeBug, CreatedAt: old, UpdatedAt: recent},
+		{ID: "bl-othr", Title: "Other", Status: StatusOpen, Priority: 3, Type: IssueTypeTask, CreatedAt: old, UpdatedAt: recent},
+	}
+	for _, issue := range issues {
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	// bug1 -> midl -> base
+	store.AddDependency("bl-bug1", "bl-midl", DepBlocks)
+	store.AddDependency("bl-midl", "bl-base", DepBlocks)
+
+	// UpdateIssue stamps updated_at, so reset the fixtures directly
+	for _, issue := range issues {
+		store.db.Exec(`UPDATE issues SET updated_at = ? WHERE id = ?`, issue.UpdatedAt, issue.ID)
+	}
+
+	tests := []struct {
+		name string
+		opts ExportOptions
+		want []string
+	}{
+		{"all", ExportOptions{Priority: -1}, []string{"bl-base", "bl-bug1", "bl-midl", "bl-othr"}},
+		{"type", ExportOptions{Priority: -1, Type: "bug"}, []string{"bl-bug1"}},
+		{"status", ExportOptions{Priority: -1, Status: "closed"}, []string{"bl-base"}},
+		{"priority", ExportOptions{Priority: 3}, []string{"bl-othr"}},
+		{"ids", ExportOptions{Priority: -1, IDs: []string{"bl-midl", "bl-othr"}}, []string{"bl-midl", "bl-othr"}},
+		{"since", ExportOptions{Priority: -1, Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"bl-bug1", "bl-othr"}},
+		{"with blockers", ExportOptions{Priority: -1, Type: "bug", WithBlockers: true}, []string{"bl-base", "bl-bug1", "bl-midl"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := ExportToJSONLWithOptions(store, &buf, tt.opts); err != nil {
+				t.Fatalf("ExportToJSONLWithOptions: %v", err)
+			}
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if line == "" {
+					continue
+				}
+				var export IssueExport
+				if err := json.Unmarshal([]byte(line), &export); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				got = append(got, export.ID)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("exported %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestImportFromJSONL(t *testing.T) {
@@ -182,6 +256,631 @@ func TestImportFromJSONL_Upsert(t *testing.T) {
 	}
 }
 
+func TestImportFromJSONL_CollisionDetected(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	local := &Issue{
+		ID:        "bl-c0l1",
+		Title:     "Local Task",
+		Status:    StatusOpen,
+		Priority:  2,
+		Type:      IssueTypeTask,
+		CreatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := store.CreateIssue(local); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	// Same ID, different title and created_at: an unrelated issue from another repo
+	input := `{"id":"bl-c0l1","title":"Foreign Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	stats, err := ImportFromJSONL(store, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+
+	if len(stats.Collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %d", len(stats.Collisions))
+	}
+	if stats.Collisions[0].OldID != "bl-c0l1" || stats.Collisions[0].NewID != "" {
+		t.Errorf("unexpected collision record: %+v", stats.Collisions[0])
+	}
+	// Without remapping, upsert semantics are preserved
+	if stats.Updated != 1 {
+		t.Errorf("expected 1 updated, got %d", stats.Updated)
+	}
+}
+
+func TestImportFromJSONL_NoCollisionOnEdit(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	local := &Issue{
+		ID:        "bl-ed1t",
+		Title:     "Old Title",
+		Status:    StatusOpen,
+		Priority:  2,
+		Type:      IssueTypeTask,
+		CreatedAt: created,
+		UpdatedAt: created,
+	}
+	if err := store.CreateIssue(local); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	// Same title and created_at with a new status is an ordinary edit
+	input := `{"id":"bl-ed1t","title":"Old Title","status":"in_progress","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-02T00:00:00Z","dependencies":[]}`
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{RemapCollisions: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+	if len(stats.Collisions) != 0 {
+		t.Errorf("expected no collisions, got %+v", stats.Collisions)
+	}
+
+	got, err := store.GetIssue("bl-ed1t")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if got.Status != StatusInProgress {
+		t.Errorf("expected 'in_progress', got %q", got.Status)
+	}
+}
+
+func TestImportFromJSONL_CollisionOnCreatedAtOnly(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	local := &Issue{
+		ID:        "bl-c0l2",
+		Title:     "Shared Title",
+		Status:    StatusOpen,
+		Priority:  2,
+		Type:      IssueTypeTask,
+		CreatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := store.CreateIssue(local); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	// Same ID and title, different created_at: still a different issue
+	input := `{"id":"bl-c0l2","title":"Shared Title","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{RemapCollisions: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+	if len(stats.Collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %+v", stats.Collisions)
+	}
+	if stats.Collisions[0].NewID == "" || stats.Collisions[0].NewID == "bl-c0l2" {
+		t.Errorf("expected the incoming issue to be remapped, got %+v", stats.Collisions[0])
+	}
+
+	got, err := store.GetIssue("bl-c0l2")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if !got.CreatedAt.Equal(local.CreatedAt) {
+		t.Errorf("local issue was overwritten: created_at %v", got.CreatedAt)
+	}
+}
+
+func TestImportFromJSONL_RemapCollisions(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	local := &Issue{
+		ID:        "bl-c0l1",
+		Title:     "Local Task",
+		Status:    StatusOpen,
+		Priority:  2,
+		Type:      IssueTypeTask,
+		CreatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := store.CreateIssue(local); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	// bl-c0l1 collides; bl-d3p2 depends on it and must follow the remap
+	input := `{"id":"bl-c0l1","title":"Foreign Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+{"id":"bl-d3p2","title":"Foreign Dependent","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-c0l1","type":"blocks"}]}`
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{RemapCollisions: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+
+	if stats.Created != 2 {
+		t.Errorf("expected 2 created, got %d", stats.Created)
+	}
+	if len(stats.Collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %d", len(stats.Collisions))
+	}
+	newID := stats.Collisions[0].NewID
+	if newID == "" || newID == "bl-c0l1" {
+		t.Fatalf("expected a fresh ID, got %q", newID)
+	}
+	if !strings.HasPrefix(newID, "bl-") || len(newID) != len("bl-c0l1") {
+		t.Errorf("fresh ID %q should keep prefix and length", newID)
+	}
+
+	// Local issue is untouched
+	got, err := store.GetIssue("bl-c0l1")
+	if err != nil {
+		t.Fatalf("GetIssue(local): %v", err)
+	}
+	if got.Title != "Local Task" {
+		t.Errorf("local issue was overwritten: %q", got.Title)
+	}
+
+	// Incoming issue lives under the new ID
+	remapped, err := store.GetIssue(newID)
+	if err != nil {
+		t.Fatalf("GetIssue(remapped): %v", err)
+	}
+	if remapped.Title != "Foreign Task" {
+		t.Errorf("expected 'Foreign Task', got %q", remapped.Title)
+	}
+
+	// Dependency was rewritten to the new ID
+	deps, err := store.GetDependencies("bl-d3p2")
+	if err != nil {
+		t.Fatalf("GetDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].DependsOnID != newID {
+		t.Errorf("expected dependency on %s, got %+v", newID, deps)
+	}
+}
+
+func TestImportFromJSONL_LongLine(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Well past bufio.Scanner's 64KB default token limit
+	description := strings.Repeat("x", 256*1024)
+	input := `{"id":"bl-l0ng","title":"Long","description":"` + description + `","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	if _, err := ImportFromJSONL(store, strings.NewReader(input)); err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+
+	got, err := store.GetIssue("bl-l0ng")
+	if err != nil {
+		t.Fatalf("GetIssue: %v", err)
+	}
+	if len(got.Description) != len(description) {
+		t.Errorf("description length = %d, want %d", len(got.Description), len(description))
+	}
+}
+
+func TestImportFromJSONL_NonSeekableReader(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// Dependency on an issue that appears later in the file still needs two passes
+	input := `{"id":"bl-aaaa","title":"Dependent","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-zzzz","type":"blocks"}]}
+
+{"id":"bl-zzzz","title":"Blocker","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+`
+	// io.MultiReader hides the Seek method, like a pipe on stdin
+	stats, err := ImportFromJSONL(store, io.MultiReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+	if stats.Created != 2 {
+		t.Errorf("expected 2 created, got %d", stats.Created)
+	}
+
+	deps, err := store.GetDependencies("bl-aaaa")
+	if err != nil {
+		t.Fatalf("GetDependencies: %v", err)
+	}
+	if len(deps) != 1 || deps[0].DependsOnID != "bl-zzzz" {
+		t.Errorf("expected dependency on bl-zzzz, got %+v", deps)
+	}
+}
+
+func TestImportFromJSONL_Progress(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	var input strings.Builder
+	const count = 2500
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&input, `{"id":"bl-p%04d","title":"Task %d","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`+"\n", i, i)
+	}
+
+	var reports []ImportProgress
+	opts := ImportOptions{Progress: func(p ImportProgress) { reports = append(reports, p) }}
+	if _, err := ImportFromJSONLWithOptions(store, strings.NewReader(input.String()), opts); err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+
+	// 1000, 2000, 2500 for each of scan, issues, dependencies
+	if len(reports) != 9 {
+		t.Fatalf("expected 9 progress reports, got %d: %+v", len(reports), reports)
+	}
+	last := reports[len(reports)-1]
+	if last.Phase != "dependencies" || last.Done != count || last.Total != count {
+		t.Errorf("unexpected final report: %+v", last)
+	}
+}
+
+func TestImportFromJSONLContext_Canceled(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	var input strings.Builder
+	for i := 0; i < 1500; i++ {
+		fmt.Fprintf(&input, `{"id":"bl-c%04d","title":"Task %d","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`+"\n", i, i)
+	}
+
+	// Cancel partway through writing the issues
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := ImportOptions{Progress: func(p ImportProgress) {
+		if p.Phase == "issues" {
+			cancel()
+		}
+	}}
+	if _, err := ImportFromJSONLContext(ctx, store, strings.NewReader(input.String()), opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ImportFromJSONLContext() error = %v, want context.Canceled", err)
+	}
+
+	issues, err := store.ListIssues()
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("canceled import left %d issues, want 0", len(issues))
+	}
+}
+
+func TestExportToJSONLContext_Canceled(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+	store.CreateIssue(NewIssue("Not exported"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := ExportToJSONLContext(ctx, store, &buf, ExportOptions{Priority: -1}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportToJSONLContext() error = %v, want context.Canceled", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("canceled export wrote %q", buf.String())
+	}
+}
+
+func TestImportFromJSONL_FailedImportRollsBackUnderConcurrency(t *testing.T) {
+	store, _ := newFileStore(t)
+
+	// The last record repeats a dependency, which fails only once every
+	// issue has been written inside the transaction
+	var input strings.Builder
+	const count = 1200
+	for i := 0; i < count; i++ {
+		deps := "[]"
+		if i == count-1 {
+			deps = `[{"depends_on":"bl-r0000","type":"blocks"},{"depends_on":"bl-r0000","type":"blocks"}]`
+		}
+		fmt.Fprintf(&input, `{"id":"bl-r%04d","title":"Task %d","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":%s}`+"\n", i, i, deps)
+	}
+
+	// Other writers start while the import is halfway through its issues
+	started := make(chan struct{})
+	var once sync.Once
+	opts := ImportOptions{Progress: func(p ImportProgress) {
+		if p.Phase == "issues" {
+			once.Do(func() { close(started) })
+		}
+	}}
+
+	const writers, perWriter = 4, 10
+	var wg sync.WaitGroup
+	created := make(chan string, writers*perWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			<-started
+			for i := 0; i < perWriter; i++ {
+				issue := NewIssue(fmt.Sprintf("Concurrent %d-%d", w, i))
+				if err := store.CreateIssue(issue); err != nil {
+					t.Errorf("concurrent CreateIssue: %v", err)
+					continue
+				}
+				created <- issue.ID
+			}
+		}(w)
+	}
+
+	_, err := ImportFromJSONLWithOptions(store, strings.NewReader(input.String()), opts)
+	if once.Do(func() { close(started) }); err == nil {
+		t.Fatal("import with a duplicate dependency should fail")
+	}
+	wg.Wait()
+	close(created)
+
+	issues, err := store.ListIssues()
+	if err != nil {
+		t.Fatalf("ListIssues: %v", err)
+	}
+	for _, issue := range issues {
+		if strings.HasPrefix(issue.Title, "Task ") {
+			t.Fatalf("failed import left %s behind", issue.ID)
+		}
+	}
+	if len(issues) != len(created) {
+		t.Errorf("got %d issues, want the %d created concurrently", len(issues), len(created))
+	}
+	for id := range created {
+		if _, err := store.GetIssue(id); err != nil {
+			t.Errorf("concurrently created %s: %v", id, err)
+		}
+	}
+}
+
+func TestImportFromJSONL_ParseErrorLineNumber(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"id":"bl-ok01","title":"OK","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+
+{not json}`
+
+	_, err := ImportFromJSONL(store, strings.NewReader(input))
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("error should name line 3, got: %v", err)
+	}
+
+	// Nothing was written
+	issues, _ := store.ListIssues()
+	if len(issues) != 0 {
+		t.Errorf("expected no issues after failed import, got %d", len(issues))
+	}
+}
+
+func TestImportFromJSONL_ContinueOnError(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	existing := &Issue{ID: "bl-locl", Title: "Local", Status: StatusOpen, Priority: 2, Type: IssueTypeTask, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if err := store.CreateIssue(existing); err != nil {
+		t.Fatalf("CreateIssue: %v", err)
+	}
+
+	input := strings.Join([]string{
+		// 1: valid, depends on an issue already in the database
+		`{"id":"bl-ok01","title":"OK","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-locl","type":"blocks"}]}`,
+		// 2: malformed JSON
+		`{"id":`,
+		// 3: invalid priority
+		`{"id":"bl-bad1","title":"Bad Priority","status":"open","priority":9,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`,
+		// 4: depends on an ID that exists nowhere
+		`{"id":"bl-dang","title":"Dangling","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-none","type":"blocks"}]}`,
+		// 5: depends on line 4, which is rejected, so this cascades
+		`{"id":"bl-casc","title":"Cascade","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-dang","type":"blocks"}]}`,
+		// 6: depends on a later line in the file
+		`{"id":"bl-fwd1","title":"Forward","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-ok02","type":"blocks"}]}`,
+		// 7: valid
+		`{"id":"bl-ok02","title":"OK Two","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`,
+	}, "\n")
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+
+	if stats.Created != 3 {
+		t.Errorf("expected 3 created, got %d", stats.Created)
+	}
+
+	var lines []int
+	for _, e := range stats.Rejected {
+		lines = append(lines, e.Line)
+	}
+	if fmt.Sprint(lines) != "[2 3 4 5]" {
+		t.Fatalf("expected lines [2 3 4 5] rejected, got %v (%v)", lines, stats.Rejected)
+	}
+	if !errors.Is(stats.Rejected[2], ErrIssueNotFound) {
+		t.Errorf("dangling dependency should wrap ErrIssueNotFound: %v", stats.Rejected[2])
+	}
+
+	for _, id := range []string{"bl-dang", "bl-casc", "bl-bad1"} {
+		if _, err := store.GetIssue(id); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("%s should not be imported", id)
+		}
+	}
+	deps, _ := store.GetDependencies("bl-fwd1")
+	if len(deps) != 1 {
+		t.Errorf("forward dependency should be imported, got %v", deps)
+	}
+
+	// The rejects file reproduces the original lines
+	var buf bytes.Buffer
+	if err := WriteRejectedLines(stats.Rejected, &buf); err != nil {
+		t.Fatalf("WriteRejectedLines: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"id":`+"\n") || strings.Count(buf.String(), "\n") != 4 {
+		t.Errorf("unexpected rejects output: %s", buf.String())
+	}
+}
+
+func TestImportFromJSONL_ContinueOnErrorLeavesRejectedUntouched(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	for _, id := range []string{"bl-a", "bl-c"} {
+		issue := NewIssue("Issue " + id)
+		issue.ID = id
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	store.AddDependency("bl-a", "bl-c", DepBlocks)
+	a, _ := store.GetIssue("bl-a")
+
+	input := strings.Join([]string{
+		// An update of bl-a whose dependencies cannot all be added
+		`{"id":"bl-a","title":"Changed","status":"open","priority":2,"issue_type":"task","created_at":"` + a.CreatedAt.Format(time.RFC3339Nano) + `","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-b","type":"blocks"},{"depends_on":"bl-b","type":"blocks"}]}`,
+		`{"id":"bl-b","title":"B","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`,
+	}, "\n")
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+	if stats.Created != 1 || stats.Updated != 0 || len(stats.Rejected) != 1 || stats.Rejected[0].Line != 1 {
+		t.Errorf("stats = created %d, updated %d, rejected %v; want 1, 0, line 1",
+			stats.Created, stats.Updated, stats.Rejected)
+	}
+	if got, _ := store.GetIssue("bl-a"); got.Title != a.Title {
+		t.Errorf("rejected record changed the title to %q", got.Title)
+	}
+	deps, _ := store.GetDependencies("bl-a")
+	if len(deps) != 1 || deps[0].DependsOnID != "bl-c" {
+		t.Errorf("rejected record changed the dependencies: %+v", deps)
+	}
+}
+
+func TestImportFromJSONL_ContinueOnErrorCascadesWriteFailures(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	// A record that passes validation but that the database refuses to store
+	if _, err := store.db.Exec(`CREATE TRIGGER refuse BEFORE INSERT ON issues WHEN NEW.id = 'bl-fail'
+		BEGIN SELECT RAISE(ABORT, 'refused'); END`); err != nil {
+		t.Fatalf("create trigger: %v", err)
+	}
+
+	input := strings.Join([]string{
+		// 1: depends on line 3, and is written before it fails
+		`{"id":"bl-dep1","title":"Dependent","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-fail","type":"blocks"}]}`,
+		// 2: unrelated
+		`{"id":"bl-ok01","title":"OK","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`,
+		// 3: refused by the database
+		`{"id":"bl-fail","title":"Refused","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`,
+	}, "\n")
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+
+	var lines []int
+	for _, e := range stats.Rejected {
+		lines = append(lines, e.Line)
+	}
+	if fmt.Sprint(lines) != "[1 3]" {
+		t.Fatalf("expected lines [1 3] rejected, got %v (%v)", lines, stats.Rejected)
+	}
+	if stats.Created != 1 {
+		t.Errorf("expected 1 created, got %d", stats.Created)
+	}
+	if _, err := store.GetIssue("bl-dep1"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("dependent of a rejected record should not be imported: %v", err)
+	}
+	if _, err := store.GetIssue("bl-ok01"); err != nil {
+		t.Errorf("unrelated record should be imported: %v", err)
+	}
+}
+
+func TestImportFromJSONL_StrictRejectsInvalidRecord(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"id":"bl-ok01","title":"OK","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+{"id":"bl-bad1","title":"","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	_, err := ImportFromJSONL(store, strings.NewReader(input))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected line 2 error, got %v", err)
+	}
+
+	issues, _ := store.ListIssues()
+	if len(issues) != 0 {
+		t.Errorf("strict import should write nothing, got %d issues", len(issues))
+	}
+}
+
+func TestImportFromJSONL_Header(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"format":"beads-lite","format_version":1,"exporter_version":"v1.2.3","id_prefix":"bl","exported_at":"2026-01-01T00:00:00Z"}
+{"id":"bl-hdr1","title":"After Header","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	stats, err := ImportFromJSONL(store, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+	if stats.Created != 1 {
+		t.Errorf("expected 1 created, got %d", stats.Created)
+	}
+	if stats.Header == nil || stats.Header.ExporterVersion != "v1.2.3" {
+		t.Errorf("expected parsed header, got %+v", stats.Header)
+	}
+}
+
+func TestImportFromJSONL_HeaderTooNew(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"format":"beads-lite","format_version":99,"exporter_version":"v9.0.0","exported_at":"2026-01-01T00:00:00Z"}
+{"id":"bl-new1","title":"Future","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	// Even lenient imports refuse a format they cannot read
+	_, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{ContinueOnError: true})
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestImportFromJSONL_PreservesUnknownFields(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"id":"bl-unk1","title":"Future Fields","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[],"assignee":"alice","labels":["x","y"]}`
+
+	if _, err := ImportFromJSONL(store, strings.NewReader(input)); err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ExportToJSONL(store, &buf); err != nil {
+		t.Fatalf("ExportToJSONL: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `"dependencies":[],"assignee":"alice","labels":["x","y"]}`) {
+		t.Errorf("unknown fields should survive the round trip, got: %s", out)
+	}
+}
+
+func TestIssueExport_MarshalWithoutExtra(t *testing.T) {
+	export := IssueExport{ID: "bl-a1b2", Title: "Plain", Status: StatusOpen, Type: IssueTypeTask, Dependencies: []DependencyExport{}}
+
+	type plain IssueExport
+	want, _ := json.Marshal(plain(export))
+	got, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("records without extras should encode unchanged:\n got %s\nwant %s", got, want)
+	}
+}
+
 func TestRoundTrip(t *testing.T) {
 	// Create first store with issues
 	store1, cleanup1 := setupTestStore(t)
@@ -441,3 +1140,70 @@ func setupTestStore(t *testing.T) (*Store, func()) {
 	}
 	return store, func() { store.Close() }
 }
+
+func TestImportFromJSONL_ChildIDsAdvanceCounter(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	input := `{"id":"bl-e1c0","title":"Epic","status":"open","priority":2,"issue_type":"epic","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+{"id":"bl-e1c0.3","title":"Third","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	if _, err := ImportFromJSONL(store, strings.NewReader(input)); err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+
+	child, err := store.NewChildIssue("bl-e1c0", "Local child")
+	if err != nil {
+		t.Fatalf("NewChildIssue: %v", err)
+	}
+	if child.ID != "bl-e1c0.4" {
+		t.Errorf("expected bl-e1c0.4 after importing bl-e1c0.3, got %s", child.ID)
+	}
+}
+
+func TestImportFromJSONL_RemapCarriesChildren(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	local := &Issue{
+		ID:        "bl-e1c0",
+		Title:     "Local Epic",
+		Status:    StatusOpen,
+		Priority:  2,
+		Type:      IssueTypeEpic,
+		CreatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	store.CreateIssue(local)
+
+	// Children are listed before their parent, and the grandchild before
+	// the child, to check that the order of the file does not matter.
+	input := `{"id":"bl-e1c0.1.1","title":"Foreign Grandchild","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-e1c0.1","type":"blocks"}]}
+{"id":"bl-e1c0.1","title":"Foreign Child","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+{"id":"bl-e1c0","title":"Foreign Epic","status":"open","priority":2,"issue_type":"epic","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+
+	stats, err := ImportFromJSONLWithOptions(store, strings.NewReader(input), ImportOptions{RemapCollisions: true})
+	if err != nil {
+		t.Fatalf("ImportFromJSONLWithOptions: %v", err)
+	}
+	if len(stats.Collisions) != 1 {
+		t.Fatalf("expected 1 collision, got %d", len(stats.Collisions))
+	}
+	newEpic := stats.Collisions[0].NewID
+
+	child, err := store.GetIssue(newEpic + ".1")
+	if err != nil || child.Title != "Foreign Child" {
+		t.Fatalf("child should move under %s: %v", newEpic, err)
+	}
+	grandchild, err := store.GetIssue(newEpic + ".1.1")
+	if err != nil || grandchild.Title != "Foreign Grandchild" {
+		t.Fatalf("grandchild should move under %s.1: %v", newEpic, err)
+	}
+	deps, _ := store.GetDependencies(grandchild.ID)
+	if len(deps) != 1 || deps[0].DependsOnID != newEpic+".1" {
+		t.Errorf("grandchild dependency should follow the rename: %+v", deps)
+	}
+	if _, err := store.GetIssue("bl-e1c0.1"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("nothing should be imported under the local epic, got %v", err)
+	}
+}
@@ -1,17 +1,22 @@
 package beadslite
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -24,11 +29,31 @@ const (
 // Version is set at build time via ldflags
 var Version = "dev"
 
+// stdin is read by "bl import -". It is a variable so tests can substitute it.
+var stdin io.Reader = os.Stdin
+
+// interruptContext returns the context that long-running commands stop on.
+// It is a variable so tests can stop them.
+var interruptContext = func() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
+// dbOverride is the --db value for the command being run.
+var dbOverride string
+
 // Run executes the CLI with the given arguments and writes output to w.
 // This is the main entry point for the CLI, separated from main() for testing.
 func Run(args []string, w io.Writer) error {
+	args, dbPath, err := extractGlobalFlags(args)
+	if err != nil {
+		return err
+	}
+	dbOverride = dbPath
+	defer func() { dbOverride = "" }()
+
 	if len(args) == 0 {
 		printHelp(w)
+		printWorkspaceVocabulary(w)
 		return nil
 	}
 
@@ -37,7 +62,7 @@ func Run(args []string, w io.Writer) error {
 
 	switch cmd {
 	case "init":
-		return cmdInit(w)
+		return cmdInit(cmdArgs, w)
 	case "create":
 		return cmdCreate(cmdArgs, w)
 	case "list":
@@ -48,14 +73,36 @@ func Run(args []string, w io.Writer) error {
 		return cmdUpdate(cmdArgs, w)
 	case "delete":
 		return cmdDelete(cmdArgs, w)
+	case "restore":
+		return cmdRestore(cmdArgs, w)
+	case "trash":
+		return cmdTrash(cmdArgs, w)
 	case "close":
 		return cmdClose(cmdArgs, w)
+	case "reopen":
+		return cmdReopen(cmdArgs, w)
 	case "ready":
 		return cmdReady(cmdArgs, w)
+	case "config":
+		return cmdConfig(cmdArgs, w)
+	case "rename-prefix":
+		return cmdRenamePrefix(cmdArgs, w)
+	case "info":
+		return cmdInfo(w)
+	case "doctor":
+		return cmdDoctor(cmdArgs, w)
+	case "workspace":
+		return cmdWorkspace(cmdArgs, w)
 	case "export":
 		return cmdExport(cmdArgs, w)
 	case "import":
 		return cmdImport(cmdArgs, w)
+	case "mcp":
+		return cmdMCP(cmdArgs, w)
+	case "serve":
+		return cmdServe(cmdArgs, w)
+	case "watch":
+		return cmdWatch(cmdArgs, w)
 	case "onboard":
 		return cmdOnboard(w)
 	case "version", "-v", "--version":
@@ -64,6 +111,7 @@ func Run(args []string, w io.Writer) error {
 		return cmdUpgrade(w)
 	case "help", "-h", "--help":
 		printHelp(w)
+		printWorkspaceVocabulary(w)
 		return nil
 	default:
 		return fmt.Errorf("unknown command: %s", cmd)
@@ -79,17 +127,28 @@ Commands:
   list                  List all issues
   show <id>             Show issue details
   update <id>           Update an issue (including blockers)
-  delete <id>           Delete an issue permanently (requires --confirm)
+  delete <id>           Move an issue to the trash (requires --confirm)
+  restore <id>          Take an issue out of the trash, with its dependencies
+  trash <cmd>           Manage deleted issues (list, purge --older-than <age>, purge --all)
   close <id>            Close an issue
+  reopen <id>           Reopen a closed issue, clearing its resolution
   ready                 List unblocked work
-  export [file]         Export all issues to JSONL (stdout or file)
-  import <file>         Import issues from JSONL file
+  config <cmd>          Workspace settings (get <key>, set <key> <value>, list)
+  rename-prefix <new>   Change the ID prefix, rewriting existing IDs
+  info                  Show which workspace and database are in use
+  doctor                Check the database for broken dependencies and invalid fields
+  workspace <cmd>       Manage remote workspaces (add <name> <path>, list, remove <name>)
+  export [file]         Export issues to JSONL (stdout or file)
+  import <file>         Import issues from JSONL file ("-" for stdin)
+  mcp                   Serve the tracker to agents as MCP tools over stdin/stdout
+  serve                 Serve a JSON HTTP API for editors and dashboards
+  watch                 Print changes to issues as they happen, from any process
   onboard               Print Claude Code integration instructions
   version               Show version
   upgrade               Upgrade to latest release
 
 List/Ready Flags:
-  --json                Output as JSONL (one JSON object per line)
+  --json                Output as JSONL (one JSON object per line), default if output.format is json
   --tree                Show dependency tree
   --priority <int>      Filter by priority (0-4)
   --type <string>       Filter by type (task, bug, feature, epic)
@@ -97,15 +156,24 @@ List/Ready Flags:
 List-Only Flags:
   --status <string>     Filter by status (open, in_progress, closed)
   --resolution <string> Filter by resolution (done, wontfix, duplicate)
+  --all-workspaces      Include issues from registered remote workspaces
+
+Global Flags:
+  --db <path>           Use this database file (or set BL_DIR to a .beads-lite dir)
+
+Init Flags:
+  --prefix <string>     Prefix for issue IDs, default bl
+  --id-length <int>     Minimum hash characters in issue IDs (3-12), default 4
 
 Show Flags:
   --json                Output as JSON
 
 Create Flags:
   --description <text>  Issue description
-  --priority <int>      Priority (0-4), default 2
-  --type <string>       Type (task, bug, feature, epic), default task
-  --blocked-by <id>     Issue ID that blocks this (repeatable)
+  --priority <int>      Priority (0-4), default 2 or defaults.priority
+  --type <string>       Type (task, bug, feature, epic), default task or defaults.type
+  --blocked-by <id>     Issue ID that blocks this (repeatable, <workspace>:<id> for remote)
+  --parent <id>         Create as a child of this issue, with ID <parent>.N
 
 Update Flags:
   --title <string>      New title
@@ -115,39 +183,190 @@ Update Flags:
   --description <text>  New description
   --blocked-by <id>     Add blocker (repeatable)
   --unblock <id>        Remove blocker (repeatable)
+  --if-version <int>    Fail if the issue is no longer at this version (see bl show)
 
 Close Flags:
-  --resolution <string> Resolution (done, wontfix, duplicate), default done
+  --resolution <string> Resolution (done, wontfix, duplicate), default done or defaults.resolution
 
 Delete Flags:
-  --confirm             Required to confirm permanent deletion`)
+  --confirm             Required to confirm deletion
+
+Trash Purge Flags:
+  --older-than <age>    Permanently remove issues deleted longer ago than this (e.g. 30d)
+  --all                 Permanently remove every issue in the trash
+
+Export Flags:
+  --status, --type, --priority, --resolution  Filter as for list
+  --id <id>             Export only this issue (repeatable)
+  --since <time>        Only issues updated since RFC3339 time, YYYY-MM-DD, or age (7d)
+  --with-blockers       Include all transitive blockers of exported issues
+  --include-trash       Include deleted issues, with their deleted_at
+  --header              Write a format header (version, exporter, time) first
+  --schema              Print the JSON Schema for export records
+
+Doctor Flags:
+  --fix                 Repair the problems that can be fixed safely
+
+Serve Flags:
+  --addr <host:port>    Address to listen on, default 127.0.0.1:7474
+
+Watch Flags:
+  --json                Output events as JSONL, default if output.format is json
+
+Import Flags:
+  --remap               Give incoming issues that collide with local IDs fresh IDs
+  --progress            Report progress while importing
+  --continue-on-error   Import valid records, skip and report bad ones
+  --rejects <file>      Where to write rejected lines (default <file>.rejects.jsonl)`)
+}
+
+// printWorkspaceVocabulary adds the current workspace's custom types and
+// statuses to the help text. It prints nothing outside a workspace or when
+// the workspace uses only the built-in ones. The database is only read, so
+// asking for help never creates or migrates it.
+func printWorkspaceVocabulary(w io.Writer) {
+	ws, err := currentWorkspace()
+	if err != nil || !ws.Exists() {
+		return
+	}
+	store, err := OpenReadOnlyStore(ws.DBPath)
+	if err != nil {
+		return
+	}
+	defer store.Close()
+
+	vocab, err := store.Vocabulary()
+	if err != nil || !vocab.Custom() {
+		return
+	}
+
+	statuses := make([]string, len(vocab.Statuses))
+	for i, status := range vocab.Statuses {
+		statuses[i] = string(status)
+		if category := vocab.Category(status); category != status {
+			statuses[i] += " (" + string(category) + ")"
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "This Workspace:")
+	fmt.Fprintf(w, "  types                 %s\n", vocab.TypeList())
+	fmt.Fprintf(w, "  statuses              %s\n", strings.Join(statuses, ", "))
 }
 
-func getDBPath() string {
-	return filepath.Join(beadsDir, dbName)
+// currentWorkspace resolves the workspace for the running command from the
+// working directory, BL_DIR, and --db.
+func currentWorkspace() (*Workspace, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return ResolveWorkspace(cwd, dbOverride)
 }
 
 func openStore() (*Store, error) {
-	dbPath := getDBPath()
-	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
-		return nil, errors.New("not initialized: run 'bl init' first")
+	ws, err := currentWorkspace()
+	if err != nil {
+		return nil, err
 	}
-	return NewStore(dbPath)
+	if !ws.Exists() {
+		return nil, ErrNotInitialized
+	}
+	return NewStore(ws.DBPath)
 }
 
-// cmdInit creates the .beads-lite directory and initializes the database
-func cmdInit(w io.Writer) error {
-	if err := os.MkdirAll(beadsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create %s: %w", beadsDir, err)
+// wantJSON reports whether a command should print JSON: as --json says if
+// it was given, and otherwise as the workspace's output.format setting says.
+func wantJSON(fs *flag.FlagSet, t Tracker) (bool, error) {
+	if fs.Changed("json") {
+		return fs.GetBool("json")
+	}
+	settings, err := t.Settings()
+	if err != nil {
+		return false, err
 	}
+	return settings.OutputFormat == OutputJSON, nil
+}
 
-	store, err := NewStore(getDBPath())
+// autoSync rewrites the workspace's issues.jsonl after a change when the
+// sync.auto setting is on, so a committed export never falls behind.
+func autoSync(store *Store) error {
+	settings, err := store.Settings()
+	if err != nil {
+		return err
+	}
+	if !settings.AutoSync {
+		return nil
+	}
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+	if err := ExportToFile(store, filepath.Join(ws.Dir, syncFileName)); err != nil {
+		return fmt.Errorf("auto-sync: %w", err)
+	}
+	return nil
+}
+
+// cmdInit creates the .beads-lite directory and initializes the database.
+// It initializes an explicitly configured location (--db, BL_DIR, or a shared
+// beads-lite.dir git config) if there is one, and otherwise the current
+// directory, even inside another workspace.
+func cmdInit(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	fs.SetOutput(w)
+	prefix := fs.String("prefix", defaultIDPrefix, "Prefix for issue IDs")
+	idLength := fs.Int("id-length", defaultIDLength, "Minimum number of hash characters in issue IDs")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.Changed("prefix") {
+		if err := validateIDPrefix(*prefix); err != nil {
+			return err
+		}
+	}
+	if *idLength < minIDLength || *idLength > maxIDLength {
+		return fmt.Errorf("--id-length must be %d-%d", minIDLength, maxIDLength)
+	}
+
+	// Reinitialize a workspace chosen by an override or shared from the main
+	// worktree; otherwise create one here, or in the main worktree if this
+	// is a linked one
+	dir, dbPath := beadsDir, filepath.Join(beadsDir, dbName)
+	ws, err := currentWorkspace()
+	switch {
+	case err == nil && ws.Source != "discovered":
+		dir, dbPath = ws.Dir, ws.DBPath
+	case errors.Is(err, ErrNotInitialized):
+		if dir, err = initDir("."); err != nil {
+			return err
+		}
+		dbPath = filepath.Join(dir, dbName)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	store, err := NewStore(dbPath)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	defer store.Close()
 
-	fmt.Fprintln(w, "Initialized beads-lite in", beadsDir)
+	if fs.Changed("prefix") {
+		if err := store.SetIDPrefix(*prefix); err != nil {
+			return err
+		}
+	}
+	if fs.Changed("id-length") {
+		if err := store.SetIDLength(*idLength); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, "Initialized beads-lite in", dir)
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, "Tip: Run 'bl onboard > .claude/CLAUDE.md' to set up Claude Code integration")
 	return nil
@@ -161,6 +380,7 @@ func cmdCreate(args []string, w io.Writer) error {
 	priority := fs.Int("priority", 2, "Priority (0-4)")
 	issueType := fs.String("type", "task", "Type (task, bug, feature, epic)")
 	blockedBy := fs.StringSlice("blocked-by", nil, "Issue ID that blocks this (repeatable)")
+	parent := fs.String("parent", "", "Create as a child of this issue")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -168,7 +388,7 @@ func cmdCreate(args []string, w io.Writer) error {
 
 	remaining := fs.Args()
 	if len(remaining) == 0 {
-		return errors.New("usage: bl create <title> [--description <text>] [--priority <0-4>] [--type <task|bug|feature|epic>] [--blocked-by <id>]")
+		return errors.New("usage: bl create <title> [--description <text>] [--priority <0-4>] [--type <task|bug|feature|epic>] [--blocked-by <id>] [--parent <id>]")
 	}
 
 	title := strings.Join(remaining, " ")
@@ -179,42 +399,45 @@ func cmdCreate(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	issue := NewIssue(title)
-	issue.Description = *description
-	issue.Priority = *priority
-	issue.Type = IssueType(*issueType)
-
-	if err := store.CreateIssue(issue); err != nil {
-		return fmt.Errorf("failed to create issue: %w", err)
+	req := CreateRequest{
+		Title:       title,
+		Description: *description,
+		Parent:      *parent,
+		BlockedBy:   *blockedBy,
 	}
-
-	// Add dependencies if specified
-	if err := addBlockers(store, issue.ID, *blockedBy); err != nil {
+	if fs.Changed("priority") {
+		req.Priority = priority
+	}
+	if fs.Changed("type") {
+		req.Type = IssueType(*issueType)
+	}
+	issue, err := Create(store, req)
+	if err != nil {
 		return err
 	}
 
 	fmt.Fprintf(w, "Created %s: %s\n", issue.ID, issue.Title)
-	return nil
+	return autoSync(store)
 }
 
 // cmdList lists all issues
 func cmdList(args []string, w io.Writer) error {
 	fs := flag.NewFlagSet("list", flag.ContinueOnError)
 	fs.SetOutput(w)
-	jsonFlag := fs.Bool("json", false, "Output as JSONL")
+	fs.Bool("json", false, "Output as JSONL")
 	treeFlag := fs.Bool("tree", false, "Show dependency tree")
 	statusFilter := fs.String("status", "", "Filter by status (open, in_progress, closed)")
 	priorityFilter := fs.Int("priority", -1, "Filter by priority (0-4)")
 	typeFilter := fs.String("type", "", "Filter by type (task, bug, feature, epic)")
 	resolutionFilter := fs.String("resolution", "", "Filter by resolution (done, wontfix, duplicate)")
+	allWorkspaces := fs.Bool("all-workspaces", false, "Include issues from registered remote workspaces")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
-	// Validate filter values before opening store
-	if err := validateFilters(*statusFilter, *priorityFilter, *typeFilter, *resolutionFilter); err != nil {
-		return err
+	if *allWorkspaces && *treeFlag {
+		return errors.New("--tree cannot be combined with --all-workspaces")
 	}
 
 	store, err := openStore()
@@ -223,15 +446,45 @@ func cmdList(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	issues, err := store.ListIssues()
+	// Filter values are checked against the workspace's types and statuses
+	filter := IssueFilter{
+		Status:     Status(*statusFilter),
+		Type:       IssueType(*typeFilter),
+		Priority:   priorityFlag(*priorityFilter),
+		Resolution: Resolution(*resolutionFilter),
+	}
+	vocab, err := store.Vocabulary()
+	if err != nil {
+		return err
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return err
+	}
+
+	jsonOut, err := wantJSON(fs, store)
+	if err != nil {
+		return err
+	}
+
+	if *allWorkspaces {
+		return listAllWorkspaces(store, w, jsonOut, filter)
+	}
+
+	issues, err := store.FindIssues(filter)
 	if err != nil {
 		return fmt.Errorf("failed to list issues: %w", err)
 	}
 
-	// Apply filters
-	issues = filterIssues(issues, *statusFilter, *priorityFilter, *typeFilter, *resolutionFilter)
+	return outputIssues(store, issues, w, jsonOut, *treeFlag)
+}
 
-	return outputIssues(store, issues, w, *jsonFlag, *treeFlag)
+// priorityFlag converts a --priority value, where -1 means any, to a filter
+// priority.
+func priorityFlag(priority int) *int {
+	if priority < 0 {
+		return nil
+	}
+	return &priority
 }
 
 // formatIssueLine returns a formatted string for displaying an issue in list/ready output.
@@ -241,7 +494,7 @@ func formatIssueLine(issue *Issue) string {
 }
 
 // outputIssues handles the common output logic for list and ready commands.
-func outputIssues(store *Store, issues []*Issue, w io.Writer, jsonOut, treeOut bool) error {
+func outputIssues(t Tracker, issues []*Issue, w io.Writer, jsonOut, treeOut bool) error {
 	if len(issues) == 0 {
 		if jsonOut {
 			return nil
@@ -251,11 +504,11 @@ func outputIssues(store *Store, issues []*Issue, w io.Writer, jsonOut, treeOut b
 	}
 
 	if jsonOut {
-		return outputIssuesJSON(store, issues, w)
+		return outputIssuesJSON(t, issues, w)
 	}
 
 	if treeOut {
-		return outputIssuesTree(store, issues, w)
+		return outputIssuesTree(t, issues, w)
 	}
 
 	for _, issue := range issues {
@@ -264,70 +517,62 @@ func outputIssues(store *Store, issues []*Issue, w io.Writer, jsonOut, treeOut b
 	return nil
 }
 
-// addBlockers adds blocker dependencies for an issue, validating that each blocker exists
-// and preventing self-references.
-func addBlockers(store *Store, issueID string, blockerIDs []string) error {
-	for _, blockerID := range blockerIDs {
-		if blockerID == issueID {
-			return errors.New("issue cannot block itself")
-		}
-		if _, err := store.GetIssue(blockerID); err != nil {
-			return fmt.Errorf("blocker issue %s: %w", blockerID, err)
-		}
-		if err := store.AddDependency(issueID, blockerID, DepBlocks); err != nil {
-			return fmt.Errorf("blocker issue %s: %w", blockerID, err)
-		}
-	}
-	return nil
-}
-
-// filterIssues applies status, priority, type, and resolution filters to a slice of issues.
-func filterIssues(issues []*Issue, status string, priority int, issueType string, resolution string) []*Issue {
-	if status == "" && priority < 0 && issueType == "" && resolution == "" {
-		return issues // no filtering needed
+// listAllWorkspaces prints local issues followed by those of every registered
+// workspace, with remote IDs qualified as <workspace>:<id>.
+func listAllWorkspaces(store *Store, w io.Writer, jsonOut bool, filter IssueFilter) error {
+	all, err := store.ListAllWorkspaceIssues()
+	if err != nil {
+		return fmt.Errorf("failed to list issues: %w", err)
 	}
 
-	var filtered []*Issue
-	for _, issue := range issues {
-		if status != "" && string(issue.Status) != status {
-			continue
-		}
-		if priority >= 0 && issue.Priority != priority {
+	var issues []*Issue
+	for _, ws := range all {
+		if ws.Err != nil {
+			fmt.Fprintf(w, "Warning: workspace %s: %v\n", ws.Workspace, ws.Err)
 			continue
 		}
-		if issueType != "" && string(issue.Type) != issueType {
-			continue
-		}
-		if resolution != "" && string(issue.Resolution) != resolution {
-			continue
+		for _, issue := range filter.Apply(ws.Issues) {
+			if ws.Workspace != "" {
+				qualified := *issue
+				qualified.ID = ws.Workspace + ":" + issue.ID
+				issue = &qualified
+			}
+			issues = append(issues, issue)
 		}
-		filtered = append(filtered, issue)
 	}
-	return filtered
-}
 
-// validateFilters checks that filter values are valid before applying them.
-func validateFilters(status string, priority int, issueType string, resolution string) error {
-	if status != "" && !Status(status).Valid() {
-		return fmt.Errorf("invalid status: %q (valid: open, in_progress, closed)", status)
-	}
-	if priority >= 0 && priority > 4 {
-		return fmt.Errorf("invalid priority: %d (valid: 0-4)", priority)
+	if jsonOut {
+		// Only local dependencies are included; remote ones live in their own stores
+		allDeps, err := store.GetAllDependencies()
+		if err != nil {
+			return fmt.Errorf("get all dependencies: %w", err)
+		}
+		return WriteIssuesAsJSONL(issues, allDeps, w)
 	}
-	if issueType != "" && !IssueType(issueType).Valid() {
-		return fmt.Errorf("invalid type: %q (valid: task, bug, feature, epic)", issueType)
+
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No issues found")
+		return nil
 	}
-	if resolution != "" && !Resolution(resolution).Valid() {
-		return fmt.Errorf("invalid resolution: %q (valid: done, wontfix, duplicate)", resolution)
+	for _, issue := range issues {
+		fmt.Fprintln(w, formatIssueLine(issue))
 	}
 	return nil
 }
 
+// byActor formats who made a change for bl show, or "" if nobody was recorded.
+func byActor(actor string) string {
+	if actor == "" {
+		return ""
+	}
+	return " by " + actor
+}
+
 // cmdShow displays details for a single issue
 func cmdShow(args []string, w io.Writer) error {
 	fs := flag.NewFlagSet("show", flag.ContinueOnError)
 	fs.SetOutput(w)
-	jsonOutput := fs.Bool("json", false, "Output as JSON")
+	fs.Bool("json", false, "Output as JSON")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -337,20 +582,26 @@ func cmdShow(args []string, w io.Writer) error {
 	if len(remaining) == 0 {
 		return errors.New("usage: bl show <id> [--json]")
 	}
-	id := remaining[0]
-
 	store, err := openStore()
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
+	id, err := store.ResolveID(remaining[0])
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", remaining[0], err)
+	}
 	issue, err := store.GetIssue(id)
 	if err != nil {
 		return fmt.Errorf("issue %s: %w", id, err)
 	}
 
-	if *jsonOutput {
+	jsonOut, err := wantJSON(fs, store)
+	if err != nil {
+		return err
+	}
+	if jsonOut {
 		deps, err := store.GetDependencies(id)
 		if err != nil {
 			return fmt.Errorf("get dependencies: %w", err)
@@ -363,17 +614,21 @@ func cmdShow(args []string, w io.Writer) error {
 	fmt.Fprintf(w, "Status:   %s\n", issue.Status)
 	fmt.Fprintf(w, "Priority: P%d\n", issue.Priority)
 	fmt.Fprintf(w, "Type:     %s\n", issue.Type)
+	if parentID, _, ok := ParseChildID(issue.ID); ok {
+		fmt.Fprintf(w, "Parent:   %s\n", parentID)
+	}
 	if issue.Description != "" {
 		fmt.Fprintf(w, "Description: %s\n", issue.Description)
 	}
-	fmt.Fprintf(w, "Created:  %s\n", issue.CreatedAt.Format("2006-01-02 15:04:05"))
-	fmt.Fprintf(w, "Updated:  %s\n", issue.UpdatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Created:  %s%s\n", issue.CreatedAt.Format("2006-01-02 15:04:05"), byActor(issue.CreatedBy))
+	fmt.Fprintf(w, "Updated:  %s%s\n", issue.UpdatedAt.Format("2006-01-02 15:04:05"), byActor(issue.UpdatedBy))
 	if issue.ClosedAt != nil {
 		fmt.Fprintf(w, "Closed:   %s\n", issue.ClosedAt.Format("2006-01-02 15:04:05"))
 	}
 	if issue.Resolution != "" {
 		fmt.Fprintf(w, "Resolution: %s\n", issue.Resolution)
 	}
+	fmt.Fprintf(w, "Version:  %d\n", issue.Version)
 
 	// Show dependencies
 	deps, err := store.GetDependencies(id)
@@ -390,10 +645,10 @@ func cmdShow(args []string, w io.Writer) error {
 // cmdUpdate modifies an existing issue
 func cmdUpdate(args []string, w io.Writer) error {
 	if len(args) == 0 {
-		return errors.New("usage: bl update <id> [--title <text>] [--status <open|in_progress|closed>] [--priority <0-4>] [--type <task|bug|feature|epic>] [--description <text>] [--blocked-by <id>] [--unblock <id>]")
+		return errors.New("usage: bl update <id> [--title <text>] [--status <open|in_progress|closed>] [--priority <0-4>] [--type <task|bug|feature|epic>] [--description <text>] [--blocked-by <id>] [--unblock <id>] [--if-version <n>]")
 	}
 
-	id := args[0]
+	ref := args[0]
 	flagArgs := args[1:]
 
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
@@ -405,6 +660,7 @@ func cmdUpdate(args []string, w io.Writer) error {
 	description := fs.String("description", "", "New description")
 	addBlockersFlag := fs.StringSlice("blocked-by", nil, "Add blocker (repeatable)")
 	rmBlockers := fs.StringSlice("unblock", nil, "Remove blocker (repeatable)")
+	ifVersion := fs.Int64("if-version", 0, "Fail if the issue is no longer at this version")
 
 	if err := fs.Parse(flagArgs); err != nil {
 		return err
@@ -416,59 +672,40 @@ func cmdUpdate(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	issue, err := store.GetIssue(id)
+	id, err := store.ResolveID(ref)
 	if err != nil {
-		return fmt.Errorf("issue %s: %w", id, err)
+		return fmt.Errorf("issue %s: %w", ref, err)
 	}
 
-	// Validate inputs before applying changes
-	if *status != "" && !Status(*status).Valid() {
-		return fmt.Errorf("invalid status: %q (valid: open, in_progress, closed)", *status)
+	req := UpdateRequest{
+		Status:    Status(*status),
+		Type:      IssueType(*issueType),
+		Priority:  priorityFlag(*priority),
+		BlockedBy: *addBlockersFlag,
+		Unblock:   *rmBlockers,
 	}
-	if *priority >= 0 && *priority > 4 {
-		return fmt.Errorf("invalid priority: %d (valid: 0-4)", *priority)
-	}
-	if *issueType != "" && !IssueType(*issueType).Valid() {
-		return fmt.Errorf("invalid type: %q (valid: task, bug, feature, epic)", *issueType)
-	}
-
 	if *title != "" {
-		issue.Title = *title
-	}
-	if *status != "" {
-		issue.Status = Status(*status)
-	}
-	if *priority >= 0 {
-		issue.Priority = *priority
-	}
-	if *issueType != "" {
-		issue.Type = IssueType(*issueType)
+		req.Title = title
 	}
 	if fs.Changed("description") {
-		issue.Description = *description
+		req.Description = description
 	}
-
-	if err := store.UpdateIssue(issue); err != nil {
-		return fmt.Errorf("failed to update: %w", err)
+	if fs.Changed("if-version") {
+		if *ifVersion < 1 {
+			return fmt.Errorf("invalid --if-version %d: versions start at 1", *ifVersion)
+		}
+		req.IfVersion = ifVersion
 	}
-
-	// Handle blocker additions
-	if err := addBlockers(store, id, *addBlockersFlag); err != nil {
+	issue, err := Update(store, id, req)
+	if err != nil {
 		return err
 	}
 
-	// Handle blocker removals
-	for _, blockerID := range *rmBlockers {
-		if err := store.RemoveDependency(id, blockerID, DepBlocks); err != nil {
-			return fmt.Errorf("blocker issue %s: %w", blockerID, err)
-		}
-	}
-
 	fmt.Fprintf(w, "Updated %s: %s\n", id, issue.Title)
-	return nil
+	return autoSync(store)
 }
 
-// cmdDelete permanently removes an issue
+// cmdDelete moves an issue to the trash
 func cmdDelete(args []string, w io.Writer) error {
 	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
 	fs.SetOutput(w)
@@ -482,8 +719,6 @@ func cmdDelete(args []string, w io.Writer) error {
 	if len(remaining) == 0 {
 		return errors.New("usage: bl delete <id> --confirm")
 	}
-	id := remaining[0]
-
 	if !*confirm {
 		return errors.New("delete requires --confirm flag")
 	}
@@ -494,6 +729,11 @@ func cmdDelete(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
+	id, err := store.ResolveID(remaining[0])
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", remaining[0], err)
+	}
+
 	// Get issue first to show what was deleted
 	issue, err := store.GetIssue(id)
 	if err != nil {
@@ -505,6 +745,126 @@ func cmdDelete(args []string, w io.Writer) error {
 	}
 
 	fmt.Fprintf(w, "Deleted %s: %s\n", id, issue.Title)
+	fmt.Fprintf(w, "Moved to the trash; bl restore %s brings it back\n", id)
+	return autoSync(store)
+}
+
+// cmdRestore takes an issue out of the trash
+func cmdRestore(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: bl restore <id>")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Trashed issues are invisible to ResolveID, so resolve among the trash
+	trash, err := store.ListTrash()
+	if err != nil {
+		return err
+	}
+	ids := make([]string, len(trash))
+	for i, issue := range trash {
+		ids[i] = issue.ID
+	}
+	id, err := resolveIDAmong(args[0], ids)
+	if err != nil {
+		return fmt.Errorf("trashed issue %s: %w", args[0], err)
+	}
+
+	if err := store.RestoreIssue(id); err != nil {
+		return fmt.Errorf("failed to restore: %w", err)
+	}
+	issue, err := store.GetIssue(id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Restored %s: %s\n", id, issue.Title)
+	return autoSync(store)
+}
+
+// cmdTrash lists and purges deleted issues
+func cmdTrash(args []string, w io.Writer) error {
+	const usage = "usage: bl trash <list | purge --older-than <age> | purge --all>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) != 1 {
+			return errors.New("usage: bl trash list")
+		}
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		trash, err := store.ListTrash()
+		if err != nil {
+			return err
+		}
+		if len(trash) == 0 {
+			fmt.Fprintln(w, "Trash is empty")
+			return nil
+		}
+		for _, issue := range trash {
+			fmt.Fprintf(w, "%s  %s  (deleted %s)\n", issue.ID, issue.Title, issue.DeletedAt.Local().Format("2006-01-02 15:04"))
+		}
+		return nil
+	case "purge":
+		return cmdTrashPurge(args[1:], w)
+	default:
+		return errors.New(usage)
+	}
+}
+
+// cmdTrashPurge permanently removes issues from the trash
+func cmdTrashPurge(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("trash purge", flag.ContinueOnError)
+	fs.SetOutput(w)
+	olderThan := fs.String("older-than", "", "Purge issues deleted longer ago than this age (e.g. 30d)")
+	all := fs.Bool("all", false, "Purge every issue in the trash")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 || (*olderThan == "") == !*all {
+		return errors.New("usage: bl trash purge <--older-than <age> | --all>")
+	}
+
+	var before time.Time // zero purges everything
+	if *olderThan != "" {
+		age, err := parseAge(*olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		before = time.Now().Add(-age)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	purged, err := store.PurgeTrash(before)
+	if err != nil {
+		return fmt.Errorf("failed to purge: %w", err)
+	}
+	switch purged {
+	case 0:
+		fmt.Fprintln(w, "Nothing to purge")
+	case 1:
+		fmt.Fprintln(w, "Purged 1 issue")
+	default:
+		fmt.Fprintf(w, "Purged %d issues\n", purged)
+	}
 	return nil
 }
 
@@ -522,7 +882,6 @@ func cmdClose(args []string, w io.Writer) error {
 		return errors.New("usage: bl close <id> [--resolution <done|wontfix|duplicate>]")
 	}
 
-	id := fs.Arg(0)
 	resolution := Resolution(*resolutionFlag)
 
 	if !resolution.Valid() {
@@ -535,7 +894,18 @@ func cmdClose(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	// Verify issue exists first
+	if !fs.Changed("resolution") {
+		settings, err := store.Settings()
+		if err != nil {
+			return err
+		}
+		resolution = settings.DefaultResolution
+	}
+
+	id, err := store.ResolveID(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", fs.Arg(0), err)
+	}
 	issue, err := store.GetIssue(id)
 	if err != nil {
 		return fmt.Errorf("issue %s: %w", id, err)
@@ -546,14 +916,43 @@ func cmdClose(args []string, w io.Writer) error {
 	}
 
 	fmt.Fprintf(w, "Closed %s: %s\n", id, issue.Title)
-	return nil
+	return autoSync(store)
+}
+
+// cmdReopen moves a closed issue back to open
+func cmdReopen(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: bl reopen <id>")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	id, err := store.ResolveID(args[0])
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", args[0], err)
+	}
+	issue, err := store.GetIssue(id)
+	if err != nil {
+		return fmt.Errorf("issue %s: %w", id, err)
+	}
+
+	if err := store.ReopenIssue(id); err != nil {
+		return fmt.Errorf("failed to reopen: %w", err)
+	}
+
+	fmt.Fprintf(w, "Reopened %s: %s\n", id, issue.Title)
+	return autoSync(store)
 }
 
 // cmdReady lists issues that are ready to work on (not blocked)
 func cmdReady(args []string, w io.Writer) error {
 	fs := flag.NewFlagSet("ready", flag.ContinueOnError)
 	fs.SetOutput(w)
-	jsonFlag := fs.Bool("json", false, "Output as JSONL")
+	fs.Bool("json", false, "Output as JSONL")
 	treeFlag := fs.Bool("tree", false, "Show dependency tree")
 	priorityFilter := fs.Int("priority", -1, "Filter by priority (0-4)")
 	typeFilter := fs.String("type", "", "Filter by type (task, bug, feature, epic)")
@@ -562,8 +961,70 @@ func cmdReady(args []string, w io.Writer) error {
 		return err
 	}
 
-	// Validate filter values before opening store (no status/resolution for ready)
-	if err := validateFilters("", *priorityFilter, *typeFilter, ""); err != nil {
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Validate filter values (no status/resolution for ready)
+	filter := IssueFilter{Type: IssueType(*typeFilter), Priority: priorityFlag(*priorityFilter)}
+	vocab, err := store.Vocabulary()
+	if err != nil {
+		return err
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return err
+	}
+
+	jsonOut, err := wantJSON(fs, store)
+	if err != nil {
+		return err
+	}
+
+	issues, err := store.ReadyIssues(filter)
+	if err != nil {
+		return fmt.Errorf("failed to get ready work: %w", err)
+	}
+
+	return outputIssues(store, issues, w, jsonOut, *treeFlag)
+}
+
+// cmdRenamePrefix changes the workspace's ID prefix and rewrites every issue
+// that used the old one
+func cmdRenamePrefix(args []string, w io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: bl rename-prefix <new-prefix>")
+	}
+	newPrefix := args[0]
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	oldPrefix, err := store.IDPrefix()
+	if err != nil {
+		return err
+	}
+	renamed, err := store.RenamePrefix(newPrefix)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Renamed %d issues: %s-* -> %s-*\n", renamed, oldPrefix, newPrefix)
+	return autoSync(store)
+}
+
+// cmdDoctor reports integrity problems in the database, repairing what it
+// safely can with --fix. It fails if any problems are left.
+func cmdDoctor(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fix := fs.Bool("fix", false, "Repair the problems that can be fixed safely")
+
+	if err := fs.Parse(args); err != nil {
 		return err
 	}
 
@@ -573,29 +1034,261 @@ func cmdReady(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	issues, err := store.GetReadyWork()
+	if *fix {
+		fixed, err := store.RepairIntegrity()
+		if err != nil {
+			return err
+		}
+		for _, problem := range fixed {
+			fmt.Fprintf(w, "Fixed %s\n", problem)
+		}
+		if len(fixed) > 0 {
+			if err := autoSync(store); err != nil {
+				return err
+			}
+		}
+	}
+
+	problems, err := store.CheckIntegrity()
 	if err != nil {
-		return fmt.Errorf("failed to get ready work: %w", err)
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Fprintln(w, "No problems found")
+		return nil
+	}
+	fixable := 0
+	for _, problem := range problems {
+		if problem.Fixable {
+			fixable++
+			fmt.Fprintf(w, "%s (fixable)\n", problem)
+		} else {
+			fmt.Fprintln(w, problem)
+		}
+	}
+	found := fmt.Sprintf("%d problems found", len(problems))
+	if len(problems) == 1 {
+		found = "1 problem found"
+	}
+	if fixable > 0 {
+		return fmt.Errorf("%s, %d fixable with bl doctor --fix", found, fixable)
+	}
+	return errors.New(found)
+}
+
+// cmdInfo reports which workspace a command run from here would use
+func cmdInfo(w io.Writer) error {
+	ws, err := currentWorkspace()
+	if err != nil {
+		return err
+	}
+	if !ws.Exists() {
+		return ErrNotInitialized
+	}
+
+	store, err := OpenReadOnlyStore(ws.DBPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	issues, err := store.ListIssues()
+	if err != nil {
+		return fmt.Errorf("failed to list issues: %w", err)
+	}
+
+	fmt.Fprintf(w, "Workspace: %s\n", ws.Dir)
+	fmt.Fprintf(w, "Database:  %s\n", ws.DBPath)
+	fmt.Fprintf(w, "Source:    %s\n", ws.Source)
+	fmt.Fprintf(w, "Issues:    %d\n", len(issues))
+
+	actor, err := store.Setting(configActor)
+	if err != nil {
+		return err
+	}
+	if actor != "" {
+		fmt.Fprintf(w, "Actor:     %s\n", actor)
+	}
+	return nil
+}
+
+// cmdConfig reads and changes workspace settings
+func cmdConfig(args []string, w io.Writer) error {
+	const usage = "usage: bl config <get <key> | set <key> <value> | list>"
+	if len(args) == 0 {
+		return errors.New(usage)
 	}
 
-	// Apply filters (no status/resolution filter - ready work is already filtered to open/in_progress)
-	issues = filterIssues(issues, "", *priorityFilter, *typeFilter, "")
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
 
-	return outputIssues(store, issues, w, *jsonFlag, *treeFlag)
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			return errors.New("usage: bl config get <key>")
+		}
+		value, err := store.Setting(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, value)
+		return nil
+	case "set":
+		if len(args) != 3 {
+			return errors.New("usage: bl config set <key> <value>")
+		}
+		if err := store.SetSetting(args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Set %s = %s\n", args[1], args[2])
+		return nil
+	case "list":
+		for _, key := range ConfigKeys {
+			value, err := store.Setting(key.Name)
+			if err != nil {
+				return err
+			}
+			set, err := store.IsSet(key.Name)
+			if err != nil {
+				return err
+			}
+			line := fmt.Sprintf("%-20s %s", key.Name, value)
+			if !set {
+				line += "  (default)"
+			}
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	default:
+		return errors.New(usage)
+	}
 }
 
-// cmdExport exports all issues to JSONL format
+// cmdWorkspace manages named remote workspaces
+func cmdWorkspace(args []string, w io.Writer) error {
+	const usage = "usage: bl workspace <add <name> <path> | list | remove <name>>"
+	if len(args) == 0 {
+		return errors.New(usage)
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch args[0] {
+	case "add":
+		if len(args) != 3 {
+			return errors.New("usage: bl workspace add <name> <path>")
+		}
+		if err := store.AddRemoteWorkspace(args[1], args[2]); err != nil {
+			return err
+		}
+		ws, err := store.GetRemoteWorkspace(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Added workspace %s: %s\n", ws.Name, ws.Path)
+		return nil
+	case "list":
+		workspaces, err := store.ListRemoteWorkspaces()
+		if err != nil {
+			return err
+		}
+		if len(workspaces) == 0 {
+			fmt.Fprintln(w, "No workspaces registered")
+			return nil
+		}
+		for _, ws := range workspaces {
+			fmt.Fprintf(w, "%s  %s\n", ws.Name, ws.Path)
+		}
+		return nil
+	case "remove":
+		if len(args) != 2 {
+			return errors.New("usage: bl workspace remove <name>")
+		}
+		if err := store.RemoveRemoteWorkspace(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Removed workspace %s\n", args[1])
+		return nil
+	default:
+		return errors.New(usage)
+	}
+}
+
+// cmdExport exports issues to JSONL format, optionally filtered
 func cmdExport(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(w)
+	statusFilter := fs.String("status", "", "Filter by status (open, in_progress, closed)")
+	priorityFilter := fs.Int("priority", -1, "Filter by priority (0-4)")
+	typeFilter := fs.String("type", "", "Filter by type (task, bug, feature, epic)")
+	resolutionFilter := fs.String("resolution", "", "Filter by resolution (done, wontfix, duplicate)")
+	ids := fs.StringSlice("id", nil, "Export only this issue (repeatable)")
+	since := fs.String("since", "", "Export only issues updated since a time (RFC3339, YYYY-MM-DD, or age like 7d)")
+	withBlockers := fs.Bool("with-blockers", false, "Include all transitive blockers of exported issues")
+	includeTrash := fs.Bool("include-trash", false, "Include deleted issues")
+	header := fs.Bool("header", false, "Write a format header as the first line")
+	schema := fs.Bool("schema", false, "Print the JSON Schema for export records and exit")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *schema {
+		return WriteExportSchema(w)
+	}
+
+	opts := ExportOptions{
+		Status:       *statusFilter,
+		Type:         *typeFilter,
+		Priority:     *priorityFilter,
+		Resolution:   *resolutionFilter,
+		IDs:          *ids,
+		WithBlockers: *withBlockers,
+		IncludeTrash: *includeTrash,
+		Header:       *header,
+	}
+	if *since != "" {
+		t, err := parseTimeOrAge(*since, time.Now())
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		opts.Since = t
+	}
+
 	store, err := openStore()
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
+	vocab, err := store.Vocabulary()
+	if err != nil {
+		return err
+	}
+	filter := IssueFilter{
+		Status:     Status(*statusFilter),
+		Type:       IssueType(*typeFilter),
+		Priority:   priorityFlag(*priorityFilter),
+		Resolution: Resolution(*resolutionFilter),
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
 	// If file argument provided, write to file
-	if len(args) > 0 {
-		filePath := args[0]
-		if err := ExportToFile(store, filePath); err != nil {
+	if fs.NArg() > 0 {
+		filePath := fs.Arg(0)
+		if err := ExportToFileContext(ctx, store, filePath, opts); err != nil {
 			return fmt.Errorf("export failed: %w", err)
 		}
 		fmt.Fprintf(w, "Exported to %s\n", filePath)
@@ -603,16 +1296,59 @@ func cmdExport(args []string, w io.Writer) error {
 	}
 
 	// Otherwise write to stdout
-	return ExportToJSONL(store, w)
+	return ExportToJSONLContext(ctx, store, w, opts)
+}
+
+// parseTimeOrAge parses an absolute time (RFC3339 or YYYY-MM-DD, local time)
+// or an age relative to now such as "36h" or "7d".
+func parseTimeOrAge(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time (RFC3339, YYYY-MM-DD) or age (e.g. 24h, 7d)", s)
+	}
+	return now.Add(-age), nil
+}
+
+// parseAge parses a Go duration, additionally accepting a whole number of days ("30d").
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age: %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %q", s)
+	}
+	return d, nil
 }
 
 // cmdImport imports issues from a JSONL file
 func cmdImport(args []string, w io.Writer) error {
-	if len(args) == 0 {
-		return errors.New("usage: bl import <file>")
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(w)
+	remap := fs.Bool("remap", false, "Give colliding incoming issues fresh IDs")
+	progress := fs.Bool("progress", false, "Report progress while importing")
+	continueOnError := fs.Bool("continue-on-error", false, "Skip bad records instead of aborting")
+	rejectsPath := fs.String("rejects", "", "File for rejected lines (default <file>.rejects.jsonl)")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New("usage: bl import <file|-> [--remap] [--progress] [--continue-on-error] [--rejects <file>]")
 	}
 
-	filePath := args[0]
+	filePath := fs.Arg(0)
 
 	store, err := openStore()
 	if err != nil {
@@ -620,19 +1356,96 @@ func cmdImport(args []string, w io.Writer) error {
 	}
 	defer store.Close()
 
-	stats, err := ImportFromFile(store, filePath)
+	opts := ImportOptions{RemapCollisions: *remap, ContinueOnError: *continueOnError}
+	if *progress {
+		opts.Progress = func(p ImportProgress) {
+			if p.Phase == "scan" {
+				fmt.Fprintf(w, "Scanned %d records\n", p.Done)
+				return
+			}
+			fmt.Fprintf(w, "Importing %s: %d/%d\n", p.Phase, p.Done, p.Total)
+		}
+	}
+
+	// An interrupted import is rolled back rather than left half done
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var stats *ImportStats
+	if filePath == "-" {
+		stats, err = ImportFromJSONLContext(ctx, store, stdin, opts)
+	} else {
+		stats, err = ImportFromFileContext(ctx, store, filePath, opts)
+	}
 	if err != nil {
 		return fmt.Errorf("import failed: %w", err)
 	}
 
 	fmt.Fprintf(w, "Imported: %d created, %d updated\n", stats.Created, stats.Updated)
-	return nil
+	printCollisions(w, stats.Collisions, *remap)
+
+	if len(stats.Rejected) > 0 {
+		path := *rejectsPath
+		if path == "" {
+			path = rejectsFileFor(filePath)
+		}
+		if err := writeRejectsFile(path, stats.Rejected); err != nil {
+			return fmt.Errorf("write rejects: %w", err)
+		}
+		fmt.Fprintf(w, "\nRejected %d line(s), written to %s:\n", len(stats.Rejected), path)
+		for _, e := range stats.Rejected {
+			fmt.Fprintf(w, "  %s\n", e)
+		}
+	}
+	return autoSync(store)
+}
+
+// rejectsFileFor derives the default rejected-lines path for an import source.
+func rejectsFileFor(filePath string) string {
+	if filePath == "-" {
+		return "stdin.rejects.jsonl"
+	}
+	return strings.TrimSuffix(filePath, ".jsonl") + ".rejects.jsonl"
+}
+
+// writeRejectsFile writes rejected import lines to path for fixing and re-import.
+func writeRejectsFile(path string, rejected []*ImportError) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteRejectedLines(rejected, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printCollisions reports ID collisions found during import as a remap table,
+// or as a warning listing the overwritten issues when remapping was not requested.
+func printCollisions(w io.Writer, collisions []IDRemap, remapped bool) {
+	if len(collisions) == 0 {
+		return
+	}
+
+	if !remapped {
+		fmt.Fprintf(w, "\nWarning: %d ID collision(s) overwrote different local issues (use --remap to keep both):\n", len(collisions))
+		for _, c := range collisions {
+			fmt.Fprintf(w, "  %s  %s\n", c.OldID, c.Title)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "\nRemapped %d colliding ID(s):\n", len(collisions))
+	for _, c := range collisions {
+		fmt.Fprintf(w, "  %s -> %s  %s\n", c.OldID, c.NewID, c.Title)
+	}
 }
 
 // outputIssuesJSON outputs issues as JSONL (one JSON object per line)
-func outputIssuesJSON(store *Store, issues []*Issue, w io.Writer) error {
+func outputIssuesJSON(t Tracker, issues []*Issue, w io.Writer) error {
 	// Batch-fetch all dependencies to avoid N+1 queries
-	allDeps, err := store.GetAllDependencies()
+	allDeps, err := t.GetAllDependencies()
 	if err != nil {
 		return fmt.Errorf("get all dependencies: %w", err)
 	}
@@ -658,11 +1471,15 @@ func sortByPriorityThenID(issues []*Issue) {
 }
 
 // outputIssuesTree renders issues as a dependency tree
-func outputIssuesTree(store *Store, issues []*Issue, w io.Writer) error {
-	allDeps, err := store.GetAllDependencies()
+func outputIssuesTree(t Tracker, issues []*Issue, w io.Writer) error {
+	allDeps, err := t.GetAllDependencies()
 	if err != nil {
 		return fmt.Errorf("failed to get dependencies: %w", err)
 	}
+	vocab, err := t.Vocabulary()
+	if err != nil {
+		return err
+	}
 
 	// Build tree structure: roots are issues not blocked by any open issue
 	// Children are issues that ARE blocked by open issues
@@ -689,7 +1506,7 @@ func outputIssuesTree(store *Store, issues []*Issue, w io.Writer) error {
 				continue
 			}
 			// Only count as child if parent is open (not closed)
-			if parent.Status != StatusClosed {
+			if !vocab.IsClosed(parent.Status) {
 				children[d.DependsOnID] = append(children[d.DependsOnID], child)
 				isChild[d.IssueID] = true
 			}
@@ -737,6 +1554,125 @@ func printTree(w io.Writer, children map[string][]*Issue, parentID string, prefi
 	}
 }
 
+// cmdMCP runs a Model Context Protocol server on stdin and stdout until
+// stdin closes
+func cmdMCP(args []string, w io.Writer) error {
+	if len(args) != 0 {
+		return errors.New("usage: bl mcp")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	server := NewMCPServer(store)
+	server.AfterChange = func() error { return autoSync(store) }
+	return server.Serve(stdin, w)
+}
+
+// cmdServe runs the HTTP API until the process is stopped
+func cmdServe(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(w)
+	addr := fs.String("addr", "127.0.0.1:7474", "Address to listen on")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: bl serve [--addr <host:port>]")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	api := NewAPIServer(store)
+	api.AfterChange = func() error { return autoSync(store) }
+
+	fmt.Fprintf(w, "Serving API on http://%s\n", listener.Addr())
+	if host, _, _ := net.SplitHostPort(*addr); !isLoopback(host) {
+		fmt.Fprintln(w, "Warning: the API has no authentication; anyone who can reach this address can change issues")
+	}
+	server := &http.Server{Handler: api, ReadHeaderTimeout: 10 * time.Second}
+	return server.Serve(listener)
+}
+
+// isLoopback reports whether host only accepts local connections.
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// cmdWatch prints changes to the workspace's issues until interrupted
+func cmdWatch(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	fs.SetOutput(w)
+	fs.Bool("json", false, "Output events as JSONL")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: bl watch [--json]")
+	}
+
+	store, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	jsonOut, err := wantJSON(fs, store)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	encoder := json.NewEncoder(w)
+	return store.Watch(ctx, func() {
+		if !jsonOut {
+			fmt.Fprintln(w, "Watching for changes (Ctrl-C to stop)")
+		}
+	}, func(event Event) error {
+		if jsonOut {
+			return encoder.Encode(event)
+		}
+		fmt.Fprintln(w, formatEvent(event))
+		return nil
+	})
+}
+
+// formatEvent returns a one-line description of an event for bl watch.
+func formatEvent(event Event) string {
+	prefix := fmt.Sprintf("%s  %-18s  %s", event.Time.Format("15:04:05"), event.Type, event.IssueID)
+	switch {
+	case event.Type == EventDependencyAdded:
+		return prefix + "  blocked by " + event.DependsOn
+	case event.Type == EventDependencyRemoved:
+		return prefix + "  no longer blocked by " + event.DependsOn
+	case event.Issue != nil:
+		return prefix + "  " + event.Issue.Title
+	default:
+		return prefix
+	}
+}
+
 // cmdOnboard prints Claude Code integration instructions
 func cmdOnboard(w io.Writer) error {
 	const instructions = `# beads-lite
@@ -1,9 +1,15 @@
 package beadslite
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -203,6 +209,124 @@ func TestCLI_Close(t *testing.T) {
 	}
 }
 
+func TestCLI_Reopen(t *testing.T) {
+	setupTestDir(t)
+
+	runCLI([]string{"init"})
+	createOut, _ := runCLI([]string{"create", "Task"})
+	id := extractID(createOut)
+
+	if _, err := runCLI([]string{"reopen", id}); err == nil {
+		t.Error("reopening an open issue should fail")
+	}
+
+	runCLI([]string{"close", id, "--resolution", "wontfix"})
+
+	if _, err := runCLI([]string{"update", id, "--status", "open"}); err == nil {
+		t.Error("update should not move a closed issue back to open")
+	}
+
+	out, err := runCLI([]string{"reopen", id})
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	if !strings.Contains(out, "Reopened "+id) {
+		t.Errorf("unexpected reopen output: %s", out)
+	}
+
+	showOut, _ := runCLI([]string{"show", id})
+	if !strings.Contains(showOut, "open") || strings.Contains(showOut, "wontfix") {
+		t.Errorf("expected open issue without resolution, got: %s", showOut)
+	}
+}
+
+func TestCLI_MCP(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	runCLI([]string{"config", "set", "sync.auto", "true"})
+
+	requests := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"create","arguments":{"title":"From agent"}}}`,
+	}, "\n")
+	oldStdin := stdin
+	stdin = strings.NewReader(requests)
+	t.Cleanup(func() { stdin = oldStdin })
+
+	out, err := runCLI([]string{"mcp"})
+	if err != nil {
+		t.Fatalf("mcp failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %s", len(lines), out)
+	}
+	if !strings.Contains(lines[1], `From agent`) || strings.Contains(lines[1], `"isError"`) {
+		t.Errorf("unexpected create response: %s", lines[1])
+	}
+
+	listOut, _ := runCLI([]string{"list"})
+	if !strings.Contains(listOut, "From agent") {
+		t.Errorf("issue created over MCP should be listed: %s", listOut)
+	}
+	synced, err := os.ReadFile(filepath.Join(".beads-lite", "issues.jsonl"))
+	if err != nil || !strings.Contains(string(synced), "From agent") {
+		t.Errorf("MCP changes should trigger sync.auto: %v %s", err, synced)
+	}
+}
+
+func TestCLI_Serve_InvalidArgs(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	if _, err := runCLI([]string{"serve", "extra"}); err == nil {
+		t.Error("serve with an argument should fail")
+	}
+	if _, err := runCLI([]string{"serve", "--addr", "not an address"}); err == nil {
+		t.Error("serve with a bad --addr should fail")
+	}
+}
+
+func TestCLI_Watch(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	oldInterrupt := interruptContext
+	interruptContext = func() (context.Context, context.CancelFunc) { return ctx, cancel }
+	t.Cleanup(func() { interruptContext = oldInterrupt })
+
+	out, in := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- Run([]string{"watch"}, in)
+		in.Close()
+	}()
+	lines := bufio.NewScanner(out)
+
+	if !lines.Scan() || !strings.Contains(lines.Text(), "Watching") {
+		t.Fatalf("expected a watching banner, got %q", lines.Text())
+	}
+
+	// A separate bl invocation, like another terminal or agent
+	createOut, _ := runCLI([]string{"create", "Watched task"})
+	id := extractID(createOut)
+
+	if !lines.Scan() {
+		t.Fatal("watch ended without printing the change")
+	}
+	if line := lines.Text(); !strings.Contains(line, "created") || !strings.Contains(line, id) || !strings.Contains(line, "Watched task") {
+		t.Errorf("unexpected watch output: %q", line)
+	}
+
+	cancel()
+	go io.Copy(io.Discard, out)
+	if err := <-done; err != nil {
+		t.Errorf("watch should exit cleanly when interrupted: %v", err)
+	}
+}
+
 func TestCLI_Ready(t *testing.T) {
 	setupTestDir(t)
 
@@ -281,6 +405,126 @@ func TestCLI_Update_Unblock(t *testing.T) {
 	}
 }
 
+func TestCLI_Update_IfVersion(t *testing.T) {
+	setupTestDir(t)
+
+	runCLI([]string{"init"})
+	out, _ := runCLI([]string{"create", "Versioned"})
+	id := extractID(out)
+
+	show, _ := runCLI([]string{"show", id})
+	if !strings.Contains(show, "Version:  1") {
+		t.Errorf("show should print the version: %s", show)
+	}
+	if _, err := runCLI([]string{"update", id, "--priority", "0", "--if-version", "1"}); err != nil {
+		t.Fatalf("update --if-version 1 failed: %v", err)
+	}
+
+	// Someone else's update moved the issue to version 2
+	_, err := runCLI([]string{"update", id, "--title", "Stale", "--if-version", "1"})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("stale update error = %v, want ErrConflict", err)
+	}
+	if _, err := runCLI([]string{"update", id, "--title", "Stale", "--if-version", "0"}); err == nil {
+		t.Error("--if-version 0 should be rejected, not disable the check")
+	}
+	show, _ = runCLI([]string{"show", id})
+	if !strings.Contains(show, "Versioned") || !strings.Contains(show, "Priority: P0") || !strings.Contains(show, "Version:  2") {
+		t.Errorf("stale update should not apply: %s", show)
+	}
+}
+
+func TestCLI_Trash(t *testing.T) {
+	setupTestDir(t)
+
+	runCLI([]string{"init"})
+	out, _ := runCLI([]string{"create", "Blocker"})
+	blocker := extractID(out)
+	out, _ = runCLI([]string{"create", "Blocked", "--blocked-by", blocker})
+	blocked := extractID(out)
+
+	out, err := runCLI([]string{"delete", blocker, "--confirm"})
+	if err != nil || !strings.Contains(out, "bl restore "+blocker) {
+		t.Fatalf("delete = %q, %v", out, err)
+	}
+	if out, _ := runCLI([]string{"ready"}); !strings.Contains(out, blocked) || strings.Contains(out, blocker) {
+		t.Errorf("ready after delete should list only the blocked issue: %s", out)
+	}
+	if _, err := runCLI([]string{"show", blocker}); err == nil {
+		t.Error("expected show of a trashed issue to fail")
+	}
+	if out, _ := runCLI([]string{"export"}); strings.Contains(out, blocker) {
+		t.Errorf("export should leave out the trash: %s", out)
+	}
+	if out, _ := runCLI([]string{"export", "--include-trash"}); !strings.Contains(out, `"deleted_at"`) {
+		t.Errorf("export --include-trash should include the trashed issue: %s", out)
+	}
+
+	out, err = runCLI([]string{"trash", "list"})
+	if err != nil || !strings.Contains(out, blocker+"  Blocker  (deleted ") {
+		t.Errorf("trash list = %q, %v", out, err)
+	}
+
+	// Restore accepts a partial ID, resolved among the trash
+	out, err = runCLI([]string{"restore", strings.TrimPrefix(blocker, "bl-")})
+	if err != nil || !strings.Contains(out, "Restored "+blocker+": Blocker") {
+		t.Fatalf("restore = %q, %v", out, err)
+	}
+	if out, _ := runCLI([]string{"ready"}); strings.Contains(out, blocked) {
+		t.Errorf("restored blocker should block again: %s", out)
+	}
+	if _, err := runCLI([]string{"restore", blocker}); err == nil {
+		t.Error("expected restoring an issue that is not in the trash to fail")
+	}
+
+	runCLI([]string{"delete", blocker, "--confirm"})
+	if out, _ := runCLI([]string{"trash", "purge", "--older-than", "30d"}); !strings.Contains(out, "Nothing to purge") {
+		t.Errorf("purge of recent deletions = %q", out)
+	}
+	if _, err := runCLI([]string{"trash", "purge"}); err == nil {
+		t.Error("expected purge without --older-than or --all to fail")
+	}
+	if out, _ := runCLI([]string{"trash", "purge", "--all"}); !strings.Contains(out, "Purged 1 issue") {
+		t.Errorf("purge --all = %q", out)
+	}
+	if out, _ := runCLI([]string{"trash", "list"}); !strings.Contains(out, "Trash is empty") {
+		t.Errorf("trash after purge = %q", out)
+	}
+}
+
+func TestCLI_Doctor(t *testing.T) {
+	setupTestDir(t)
+
+	runCLI([]string{"init"})
+	out, _ := runCLI([]string{"create", "Broken"})
+	id := extractID(out)
+
+	out, err := runCLI([]string{"doctor"})
+	if err != nil || !strings.Contains(out, "No problems found") {
+		t.Fatalf("doctor on a clean workspace = %q, %v", out, err)
+	}
+
+	store, _ := openStore()
+	store.db.Exec(`UPDATE issues SET status = 'closed', closed_at = NULL WHERE id = ?`, id)
+	store.Close()
+
+	out, err = runCLI([]string{"doctor"})
+	if err == nil || err.Error() != "1 problem found, 1 fixable with bl doctor --fix" {
+		t.Errorf("doctor error = %v", err)
+	}
+	if !strings.Contains(out, "closed_fields "+id+": closed but has no closed_at (fixable)") {
+		t.Errorf("doctor should list the problem: %s", out)
+	}
+
+	out, err = runCLI([]string{"doctor", "--fix"})
+	if err != nil {
+		t.Fatalf("doctor --fix failed: %v", err)
+	}
+	if !strings.Contains(out, "Fixed closed_fields "+id) || !strings.Contains(out, "No problems found") {
+		t.Errorf("doctor --fix output: %s", out)
+	}
+}
+
 func TestCLI_Update_BlockedBy_NotFound(t *testing.T) {
 	setupTestDir(t)
 
@@ -376,6 +620,84 @@ func TestCLI_NoInit(t *testing.T) {
 	}
 }
 
+func TestCLI_Workspace_FromSubdirectory(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	runCLI([]string{"create", "Root Task"})
+
+	os.MkdirAll(filepath.Join("src", "auth"), 0755)
+	os.Chdir(filepath.Join("src", "auth"))
+
+	out, err := runCLI([]string{"ready"})
+	if err != nil {
+		t.Fatalf("ready from subdirectory failed: %v", err)
+	}
+	if !strings.Contains(out, "Root Task") {
+		t.Errorf("expected workspace issues, got: %s", out)
+	}
+
+	info, err := runCLI([]string{"info"})
+	if err != nil {
+		t.Fatalf("info failed: %v", err)
+	}
+	if !strings.Contains(info, "Source:    discovered") || !strings.Contains(info, "Issues:    1") {
+		t.Errorf("unexpected info output: %s", info)
+	}
+}
+
+func TestCLI_Workspace_DBFlag(t *testing.T) {
+	setupTestDir(t)
+
+	if _, err := runCLI([]string{"init", "--db", "elsewhere/tasks.db"}); err != nil {
+		t.Fatalf("init --db failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("elsewhere", "tasks.db")); err != nil {
+		t.Fatalf("database not created at --db path: %v", err)
+	}
+	if _, err := os.Stat(".beads-lite"); !os.IsNotExist(err) {
+		t.Error("init --db should not create .beads-lite")
+	}
+
+	runCLI([]string{"--db", "elsewhere/tasks.db", "create", "Override Task"})
+	out, _ := runCLI([]string{"list", "--db", "elsewhere/tasks.db"})
+	if !strings.Contains(out, "Override Task") {
+		t.Errorf("expected issue in --db workspace, got: %s", out)
+	}
+
+	info, _ := runCLI([]string{"info", "--db", "elsewhere/tasks.db"})
+	if !strings.Contains(info, "Source:    --db") {
+		t.Errorf("info should report --db, got: %s", info)
+	}
+
+	// --db after -- or as a flag's value is just text
+	runCLI([]string{"--db", "elsewhere/tasks.db", "create", "--description", "--db", "--", "--db", "flag"})
+	out, _ = runCLI([]string{"list", "--db", "elsewhere/tasks.db"})
+	if !strings.Contains(out, "--db flag") {
+		t.Errorf("expected an issue titled '--db flag', got: %s", out)
+	}
+
+	// Without the flag there is no workspace here
+	if _, err := runCLI([]string{"list"}); err == nil {
+		t.Error("list without --db should fail")
+	}
+}
+
+func TestCLI_Workspace_EnvDir(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv("BL_DIR", "shared-beads")
+
+	runCLI([]string{"init"})
+	runCLI([]string{"create", "Env Task"})
+
+	if _, err := os.Stat(filepath.Join("shared-beads", "beads.db")); err != nil {
+		t.Fatalf("database not created in BL_DIR: %v", err)
+	}
+	info, _ := runCLI([]string{"info"})
+	if !strings.Contains(info, "Source:    BL_DIR") {
+		t.Errorf("info should report BL_DIR, got: %s", info)
+	}
+}
+
 func TestCLI_Export_Stdout(t *testing.T) {
 	setupTestDir(t)
 
@@ -421,6 +743,87 @@ func TestCLI_Export_File(t *testing.T) {
 	}
 }
 
+func TestCLI_Export_Filtered(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	outA, _ := runCLI([]string{"create", "Blocker Task"})
+	outB, _ := runCLI([]string{"create", "Blocked Bug", "--type", "bug", "--blocked-by", extractID(outA)})
+	runCLI([]string{"create", "Unrelated Task"})
+	idB := extractID(outB)
+
+	out, err := runCLI([]string{"export", "--type", "bug"})
+	if err != nil {
+		t.Fatalf("export --type failed: %v", err)
+	}
+	if !strings.Contains(out, "Blocked Bug") || strings.Contains(out, "Blocker Task") {
+		t.Errorf("expected only the bug, got: %s", out)
+	}
+
+	out, err = runCLI([]string{"export", "--id", idB, "--with-blockers"})
+	if err != nil {
+		t.Fatalf("export --with-blockers failed: %v", err)
+	}
+	if !strings.Contains(out, "Blocked Bug") || !strings.Contains(out, "Blocker Task") || strings.Contains(out, "Unrelated Task") {
+		t.Errorf("expected bug and its blocker, got: %s", out)
+	}
+
+	out, _ = runCLI([]string{"export", "--since", "1h"})
+	if strings.Count(out, "\n") != 3 {
+		t.Errorf("all issues were updated within the hour, got: %s", out)
+	}
+
+	out, _ = runCLI([]string{"export", "--since", "2999-01-01"})
+	if out != "" {
+		t.Errorf("no issues should be updated after 2999, got: %s", out)
+	}
+}
+
+func TestCLI_Export_HeaderRoundTrip(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	runCLI([]string{"create", "Headed Task"})
+
+	if _, err := runCLI([]string{"export", "--header", "backup.jsonl"}); err != nil {
+		t.Fatalf("export --header failed: %v", err)
+	}
+	data, _ := os.ReadFile("backup.jsonl")
+	firstLine, _, _ := strings.Cut(string(data), "\n")
+	if !strings.Contains(firstLine, `"format":"beads-lite"`) || !strings.Contains(firstLine, `"format_version":1`) {
+		t.Fatalf("expected header line, got: %s", firstLine)
+	}
+
+	out, err := runCLI([]string{"import", "backup.jsonl"})
+	if err != nil {
+		t.Fatalf("import with header failed: %v", err)
+	}
+	if !strings.Contains(out, "0 created, 1 updated") {
+		t.Errorf("header should not count as an issue, got: %s", out)
+	}
+}
+
+func TestCLI_Export_Schema(t *testing.T) {
+	out, err := runCLI([]string{"export", "--schema"})
+	if err != nil {
+		t.Fatalf("export --schema failed: %v", err)
+	}
+	if !strings.Contains(out, `"$schema"`) || !strings.Contains(out, `"issue_type"`) {
+		t.Errorf("expected JSON Schema, got: %s", out)
+	}
+}
+
+func TestCLI_Export_InvalidFilters(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	if _, err := runCLI([]string{"export", "--status", "bogus"}); err == nil {
+		t.Error("export with invalid status should fail")
+	}
+	if _, err := runCLI([]string{"export", "--since", "yesterday"}); err == nil {
+		t.Error("export with invalid --since should fail")
+	}
+}
+
 func TestCLI_Export_InvalidPath(t *testing.T) {
 	setupTestDir(t)
 	runCLI([]string{"init"})
@@ -484,6 +887,143 @@ func TestCLI_Import_FileNotFound(t *testing.T) {
 	}
 }
 
+func TestCLI_Import_Remap(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	local := `{"id":"bl-c0l1","title":"Local Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-02-01T00:00:00Z","updated_at":"2026-02-01T00:00:00Z","dependencies":[]}`
+	os.WriteFile("local.jsonl", []byte(local), 0644)
+	runCLI([]string{"import", "local.jsonl"})
+
+	foreign := `{"id":"bl-c0l1","title":"Foreign Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+	os.WriteFile("foreign.jsonl", []byte(foreign), 0644)
+
+	out, err := runCLI([]string{"import", "foreign.jsonl", "--remap"})
+	if err != nil {
+		t.Fatalf("import --remap failed: %v", err)
+	}
+	if !strings.Contains(out, "Remapped 1") || !strings.Contains(out, "bl-c0l1 -> bl-") {
+		t.Errorf("expected remap table, got: %s", out)
+	}
+
+	listOut, _ := runCLI([]string{"list"})
+	if !strings.Contains(listOut, "Local Task") || !strings.Contains(listOut, "Foreign Task") {
+		t.Errorf("both issues should exist after remap: %s", listOut)
+	}
+}
+
+func TestCLI_Import_Stdin(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	content := `{"id":"bl-std1","title":"From Stdin","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+	oldStdin := stdin
+	stdin = strings.NewReader(content)
+	t.Cleanup(func() { stdin = oldStdin })
+
+	out, err := runCLI([]string{"import", "-"})
+	if err != nil {
+		t.Fatalf("import - failed: %v", err)
+	}
+	if !strings.Contains(out, "1 created") {
+		t.Errorf("expected '1 created' in output, got: %s", out)
+	}
+
+	showOut, _ := runCLI([]string{"show", "bl-std1"})
+	if !strings.Contains(showOut, "From Stdin") {
+		t.Errorf("imported issue should exist: %s", showOut)
+	}
+}
+
+func TestCLI_Import_Progress(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	content := `{"id":"bl-prg1","title":"Progress Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+	os.WriteFile("import.jsonl", []byte(content), 0644)
+
+	out, err := runCLI([]string{"import", "import.jsonl", "--progress"})
+	if err != nil {
+		t.Fatalf("import --progress failed: %v", err)
+	}
+	if !strings.Contains(out, "Importing issues: 1/1") || !strings.Contains(out, "Importing dependencies: 1/1") {
+		t.Errorf("expected progress lines, got: %s", out)
+	}
+}
+
+func TestCLI_Import_ContinueOnError(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	content := `{"id":"bl-good","title":"Good Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}
+{not valid json at all
+{"id":"bl-orph","title":"Orphan Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[{"depends_on":"bl-gone","type":"blocks"}]}`
+	os.WriteFile("mixed.jsonl", []byte(content), 0644)
+
+	out, err := runCLI([]string{"import", "mixed.jsonl", "--continue-on-error"})
+	if err != nil {
+		t.Fatalf("import --continue-on-error failed: %v", err)
+	}
+	if !strings.Contains(out, "1 created") {
+		t.Errorf("expected '1 created', got: %s", out)
+	}
+	if !strings.Contains(out, "Rejected 2 line(s), written to mixed.rejects.jsonl") {
+		t.Errorf("expected rejects summary, got: %s", out)
+	}
+	if !strings.Contains(out, "line 2: parse error") || !strings.Contains(out, "line 3: dependency bl-gone") {
+		t.Errorf("expected per-line errors, got: %s", out)
+	}
+
+	rejects, err := os.ReadFile("mixed.rejects.jsonl")
+	if err != nil {
+		t.Fatalf("read rejects file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(rejects)), "\n")
+	if len(lines) != 2 || lines[0] != "{not valid json at all" || !strings.Contains(lines[1], "bl-orph") {
+		t.Errorf("rejects file should hold the original lines, got: %s", rejects)
+	}
+
+	listOut, _ := runCLI([]string{"list"})
+	if !strings.Contains(listOut, "Good Task") || strings.Contains(listOut, "Orphan Task") {
+		t.Errorf("only the valid record should be imported: %s", listOut)
+	}
+}
+
+func TestCLI_Import_RejectsFlag(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	os.WriteFile("bad.jsonl", []byte("{oops\n"), 0644)
+
+	_, err := runCLI([]string{"import", "bad.jsonl", "--continue-on-error", "--rejects", "fixme.jsonl"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if _, err := os.Stat("fixme.jsonl"); err != nil {
+		t.Errorf("expected rejects at fixme.jsonl: %v", err)
+	}
+}
+
+func TestCLI_Import_CollisionWarning(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	local := `{"id":"bl-c0l1","title":"Local Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-02-01T00:00:00Z","updated_at":"2026-02-01T00:00:00Z","dependencies":[]}`
+	os.WriteFile("local.jsonl", []byte(local), 0644)
+	runCLI([]string{"import", "local.jsonl"})
+
+	foreign := `{"id":"bl-c0l1","title":"Foreign Task","status":"open","priority":2,"issue_type":"task","created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","dependencies":[]}`
+	os.WriteFile("foreign.jsonl", []byte(foreign), 0644)
+
+	out, err := runCLI([]string{"import", "foreign.jsonl"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if !strings.Contains(out, "Warning") || !strings.Contains(out, "--remap") {
+		t.Errorf("expected collision warning, got: %s", out)
+	}
+}
+
 // TestCLI_RoundTrip_Full is the acceptance test from the Phase 3 spec
 func TestCLI_RoundTrip_Full(t *testing.T) {
 	setupTestDir(t)
@@ -1928,6 +2468,7 @@ func TestCLI_HelpMatchesSubcommandFlags(t *testing.T) {
 			"--priority",
 			"--type",
 			"--resolution",
+			"--all-workspaces",
 		},
 		"ready": {
 			"--json",
@@ -1943,6 +2484,7 @@ func TestCLI_HelpMatchesSubcommandFlags(t *testing.T) {
 			"--priority",
 			"--type",
 			"--blocked-by",
+			"--parent",
 		},
 		"update": {
 			"--title",
@@ -1952,6 +2494,7 @@ func TestCLI_HelpMatchesSubcommandFlags(t *testing.T) {
 			"--description",
 			"--blocked-by",
 			"--unblock",
+			"--if-version",
 		},
 		"close": {
 			"--resolution",
@@ -1959,6 +2502,41 @@ func TestCLI_HelpMatchesSubcommandFlags(t *testing.T) {
 		"delete": {
 			"--confirm",
 		},
+		"export": {
+			"--status",
+			"--type",
+			"--priority",
+			"--resolution",
+			"--id",
+			"--since",
+			"--with-blockers",
+			"--include-trash",
+			"--header",
+			"--schema",
+		},
+		"trash purge": {
+			"--older-than",
+			"--all",
+		},
+		"serve": {
+			"--addr",
+		},
+		"watch": {
+			"--json",
+		},
+		"doctor": {
+			"--fix",
+		},
+		"init": {
+			"--prefix",
+			"--id-length",
+		},
+		"import": {
+			"--remap",
+			"--progress",
+			"--continue-on-error",
+			"--rejects",
+		},
 	}
 
 	// Check that each flag appears in the help text
@@ -1991,10 +2569,21 @@ func TestCLI_HelpDocumentsAllCommands(t *testing.T) {
 		"show",
 		"update",
 		"delete",
+		"restore",
+		"trash",
 		"close",
+		"reopen",
 		"ready",
+		"config",
+		"rename-prefix",
+		"info",
+		"doctor",
+		"workspace",
 		"export",
 		"import",
+		"mcp",
+		"serve",
+		"watch",
 		"onboard",
 		"version",
 		"upgrade",
@@ -2012,3 +2601,408 @@ func TestCLI_HelpDocumentsAllCommands(t *testing.T) {
 		t.Errorf("main help is missing documentation for these commands: %v", missing)
 	}
 }
+
+func TestCLI_Init_Prefix(t *testing.T) {
+	setupTestDir(t)
+
+	if _, err := runCLI([]string{"init", "--prefix", "api", "--id-length", "6"}); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	out, err := runCLI([]string{"create", "Prefixed task"})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	id := strings.TrimSuffix(strings.Fields(out)[1], ":")
+	if !strings.HasPrefix(id, "api-") || len(id) != len("api-")+6 {
+		t.Errorf("expected api-xxxxxx ID, got: %s", out)
+	}
+
+	if _, err := runCLI([]string{"init", "--prefix", "Bad-Prefix"}); err == nil {
+		t.Error("invalid prefix should be rejected")
+	}
+	if _, err := runCLI([]string{"init", "--id-length", "40"}); err == nil {
+		t.Error("out-of-range id length should be rejected")
+	}
+}
+
+func TestCLI_RenamePrefix(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	out, _ := runCLI([]string{"create", "Blocker"})
+	blockerID := extractID(out)
+	runCLI([]string{"create", "Blocked", "--blocked-by", blockerID})
+
+	out, err := runCLI([]string{"rename-prefix", "web"})
+	if err != nil {
+		t.Fatalf("rename-prefix failed: %v", err)
+	}
+	if !strings.Contains(out, "Renamed 2 issues: bl-* -> web-*") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	newBlockerID := "web" + strings.TrimPrefix(blockerID, "bl")
+	listOut, _ := runCLI([]string{"list"})
+	if strings.Contains(listOut, "bl-") {
+		t.Errorf("old IDs should be gone: %s", listOut)
+	}
+	readyOut, _ := runCLI([]string{"ready"})
+	if strings.Contains(readyOut, "Blocked") {
+		t.Errorf("dependency should survive the rename: %s", readyOut)
+	}
+
+	runCLI([]string{"close", newBlockerID})
+	readyOut, _ = runCLI([]string{"ready"})
+	if !strings.Contains(readyOut, "Blocked") {
+		t.Errorf("closing the renamed blocker should unblock: %s", readyOut)
+	}
+
+	out, _ = runCLI([]string{"create", "After rename"})
+	if !strings.Contains(out, "Created web-") {
+		t.Errorf("new issues should use the new prefix: %s", out)
+	}
+}
+
+func TestCLI_PartialIDs(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	out, _ := runCLI([]string{"create", "Blocker"})
+	blockerID := extractID(out)
+	out, _ = runCLI([]string{"create", "Blocked"})
+	blockedID := extractID(out)
+	blockerHash := strings.TrimPrefix(blockerID, "bl-")
+	blockedHash := strings.TrimPrefix(blockedID, "bl-")
+
+	// Bare hash without the bl- prefix
+	showOut, err := runCLI([]string{"show", blockerHash})
+	if err != nil {
+		t.Fatalf("show by bare hash failed: %v", err)
+	}
+	if !strings.Contains(showOut, "ID:       "+blockerID) {
+		t.Errorf("show should print the full ID: %s", showOut)
+	}
+
+	// Update and --blocked-by with bare hashes
+	updateOut, err := runCLI([]string{"update", blockedHash, "--blocked-by", blockerHash})
+	if err != nil {
+		t.Fatalf("update with partial IDs failed: %v", err)
+	}
+	if !strings.Contains(updateOut, "Updated "+blockedID) {
+		t.Errorf("update should report the full ID: %s", updateOut)
+	}
+	readyOut, _ := runCLI([]string{"ready"})
+	if strings.Contains(readyOut, "Blocked\n") {
+		t.Errorf("blocker added by partial ID should block: %s", readyOut)
+	}
+
+	// Self-reference is caught after resolution
+	if _, err := runCLI([]string{"update", blockedID, "--blocked-by", blockedHash}); err == nil {
+		t.Error("expected self-block error for partial ID")
+	}
+
+	// --unblock with a partial ID
+	if _, err := runCLI([]string{"update", blockedID, "--unblock", blockerHash}); err != nil {
+		t.Fatalf("unblock with partial ID failed: %v", err)
+	}
+	showOut, _ = runCLI([]string{"show", blockedID})
+	if strings.Contains(showOut, "Dependencies:") {
+		t.Errorf("blocker should have been removed: %s", showOut)
+	}
+
+	closeOut, err := runCLI([]string{"close", blockerHash})
+	if err != nil {
+		t.Fatalf("close by bare hash failed: %v", err)
+	}
+	if !strings.Contains(closeOut, "Closed "+blockerID) {
+		t.Errorf("close should report the full ID: %s", closeOut)
+	}
+
+	deleteOut, err := runCLI([]string{"delete", blockedHash, "--confirm"})
+	if err != nil {
+		t.Fatalf("delete by bare hash failed: %v", err)
+	}
+	if !strings.Contains(deleteOut, "Deleted "+blockedID) {
+		t.Errorf("delete should report the full ID: %s", deleteOut)
+	}
+}
+
+func TestCLI_PartialIDs_AmbiguousAndMissing(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	// Create issues until two share a first hash character
+	seen := map[byte]string{}
+	var first, second string
+	for i := 0; first == ""; i++ {
+		out, _ := runCLI([]string{"create", fmt.Sprintf("Issue %d", i)})
+		id := extractID(out)
+		c := strings.TrimPrefix(id, "bl-")[0]
+		if other, ok := seen[c]; ok {
+			first, second = other, id
+		}
+		seen[c] = id
+	}
+	partial := first[:len("bl-")+1]
+
+	_, err := runCLI([]string{"show", partial})
+	if err == nil {
+		t.Fatal("expected ambiguity error")
+	}
+	if !strings.Contains(err.Error(), first) || !strings.Contains(err.Error(), second) {
+		t.Errorf("ambiguity error should list candidates, got: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"show", "zzzzzz"},
+		{"update", "zzzzzz", "--title", "x"},
+		{"close", "zzzzzz"},
+		{"delete", "zzzzzz", "--confirm"},
+		{"update", first, "--blocked-by", "zzzzzz"},
+	} {
+		_, err := runCLI(args)
+		if err == nil || !strings.Contains(err.Error(), "issue not found") {
+			t.Errorf("%v: expected issue not found, got %v", args, err)
+		}
+	}
+}
+
+func TestCLI_Create_Parent(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	out, _ := runCLI([]string{"create", "Checkout redesign", "--type", "epic"})
+	epicID := extractID(out)
+	epicHash := strings.TrimPrefix(epicID, "bl-")
+
+	out, err := runCLI([]string{"create", "New cart page", "--parent", epicID})
+	if err != nil {
+		t.Fatalf("create --parent failed: %v", err)
+	}
+	if !strings.Contains(out, "Created "+epicID+".1:") {
+		t.Errorf("expected child ID %s.1, got: %s", epicID, out)
+	}
+
+	// Parent given as a bare hash
+	out, _ = runCLI([]string{"create", "Payment form", "--parent", epicHash})
+	if !strings.Contains(out, "Created "+epicID+".2:") {
+		t.Errorf("expected child ID %s.2, got: %s", epicID, out)
+	}
+
+	showOut, _ := runCLI([]string{"show", epicID + ".2"})
+	if !strings.Contains(showOut, "Parent:   "+epicID) {
+		t.Errorf("show should name the parent: %s", showOut)
+	}
+
+	// The bare hash still names the epic, not one of its children
+	showOut, err = runCLI([]string{"show", epicHash})
+	if err != nil || !strings.Contains(showOut, "Title:    Checkout redesign") {
+		t.Errorf("bare hash should resolve to the epic: %v\n%s", err, showOut)
+	}
+
+	if _, err := runCLI([]string{"create", "Orphan", "--parent", "bl-zzzz"}); err == nil {
+		t.Error("expected error for missing parent")
+	}
+}
+
+func TestCLI_Config(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	out, err := runCLI([]string{"config", "get", "defaults.priority"})
+	if err != nil || strings.TrimSpace(out) != "2" {
+		t.Errorf("config get default = %q, %v", out, err)
+	}
+
+	if _, err := runCLI([]string{"config", "set", "defaults.priority", "1"}); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	runCLI([]string{"config", "set", "defaults.type", "bug"})
+	runCLI([]string{"config", "set", "defaults.resolution", "wontfix"})
+
+	listOut, _ := runCLI([]string{"config", "list"})
+	for _, want := range []string{"defaults.priority    1\n", "output.format        text  (default)"} {
+		if !strings.Contains(listOut, want) {
+			t.Errorf("config list missing %q:\n%s", want, listOut)
+		}
+	}
+
+	if _, err := runCLI([]string{"config", "set", "defaults.priority", "9"}); err == nil {
+		t.Error("invalid value should be rejected")
+	}
+	if _, err := runCLI([]string{"config", "get", "bogus"}); err == nil {
+		t.Error("unknown key should be rejected")
+	}
+
+	// create and close use the configured defaults
+	out, _ = runCLI([]string{"create", "Configured"})
+	id := extractID(out)
+	showOut, _ := runCLI([]string{"show", id})
+	if !strings.Contains(showOut, "Priority: P1") || !strings.Contains(showOut, "Type:     bug") {
+		t.Errorf("create should use configured defaults:\n%s", showOut)
+	}
+	out, _ = runCLI([]string{"create", "Explicit", "--priority", "3", "--type", "task"})
+	showOut, _ = runCLI([]string{"show", extractID(out)})
+	if !strings.Contains(showOut, "Priority: P3") || !strings.Contains(showOut, "Type:     task") {
+		t.Errorf("flags should override configured defaults:\n%s", showOut)
+	}
+
+	runCLI([]string{"close", id})
+	showOut, _ = runCLI([]string{"show", id})
+	if !strings.Contains(showOut, "Resolution: wontfix") {
+		t.Errorf("close should use configured resolution:\n%s", showOut)
+	}
+}
+
+func TestCLI_Config_OutputFormat(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	out, _ := runCLI([]string{"create", "Task"})
+	id := extractID(out)
+
+	runCLI([]string{"config", "set", "output.format", "json"})
+
+	for _, args := range [][]string{{"list"}, {"ready"}, {"show", id}} {
+		out, err := runCLI(args)
+		if err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		if !strings.HasPrefix(out, "{") {
+			t.Errorf("%v should print JSON by default, got: %s", args, out)
+		}
+	}
+
+	out, _ = runCLI([]string{"list", "--json=false"})
+	if strings.HasPrefix(out, "{") {
+		t.Errorf("--json=false should print text, got: %s", out)
+	}
+}
+
+func TestCLI_Config_AutoSync(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	syncPath := filepath.Join(beadsDir, syncFileName)
+
+	runCLI([]string{"create", "Before"})
+	if _, err := os.Stat(syncPath); !os.IsNotExist(err) {
+		t.Fatalf("no sync file expected while sync.auto is off, got %v", err)
+	}
+
+	runCLI([]string{"config", "set", "sync.auto", "true"})
+	out, _ := runCLI([]string{"create", "After"})
+	id := extractID(out)
+
+	data, err := os.ReadFile(syncPath)
+	if err != nil {
+		t.Fatalf("sync file not written: %v", err)
+	}
+	if !strings.Contains(string(data), "Before") || !strings.Contains(string(data), "After") {
+		t.Errorf("sync file should contain all issues:\n%s", data)
+	}
+
+	runCLI([]string{"close", id})
+	data, _ = os.ReadFile(syncPath)
+	if !strings.Contains(string(data), `"status":"closed"`) {
+		t.Errorf("sync file should reflect the close:\n%s", data)
+	}
+}
+
+func TestCLI_Info_Actor(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+	runCLI([]string{"config", "set", "actor", "ci-bot"})
+
+	out, _ := runCLI([]string{"info"})
+	if !strings.Contains(out, "Actor:     ci-bot") {
+		t.Errorf("info should show the actor: %s", out)
+	}
+
+	out, _ = runCLI([]string{"create", "Recorded"})
+	id := extractID(out)
+	runCLI([]string{"config", "set", "actor", "reviewer"})
+	runCLI([]string{"close", id})
+	out, _ = runCLI([]string{"show", id})
+	if !strings.Contains(out, " by ci-bot\nUpdated:") || !strings.Contains(out, " by reviewer\n") {
+		t.Errorf("show should say who created and closed the issue: %s", out)
+	}
+}
+
+func TestCLI_CustomTypesAndStatuses(t *testing.T) {
+	setupTestDir(t)
+	runCLI([]string{"init"})
+
+	if _, err := runCLI([]string{"create", "Tidy up", "--type", "chore"}); err == nil {
+		t.Fatal("unknown type should be rejected")
+	}
+
+	runCLI([]string{"config", "set", "types.custom", "chore,spike"})
+	runCLI([]string{"config", "set", "statuses.custom", "review:in_progress"})
+
+	out, err := runCLI([]string{"create", "Tidy up", "--type", "chore"})
+	if err != nil {
+		t.Fatalf("create with custom type failed: %v", err)
+	}
+	id := extractID(out)
+
+	if _, err := runCLI([]string{"update", id, "--status", "review"}); err != nil {
+		t.Fatalf("update to custom status failed: %v", err)
+	}
+
+	listOut, err := runCLI([]string{"list", "--status", "review", "--type", "chore"})
+	if err != nil {
+		t.Fatalf("list with custom filters failed: %v", err)
+	}
+	if !strings.Contains(listOut, "Tidy up") {
+		t.Errorf("custom filters should match: %s", listOut)
+	}
+
+	readyOut, _ := runCLI([]string{"ready"})
+	if !strings.Contains(readyOut, "Tidy up") {
+		t.Errorf("in-progress-like status should be ready: %s", readyOut)
+	}
+
+	_, err = runCLI([]string{"list", "--status", "bogus"})
+	if err == nil || !strings.Contains(err.Error(), "review") {
+		t.Errorf("invalid status error should list custom statuses, got %v", err)
+	}
+
+	helpOut, _ := runCLI([]string{"help"})
+	if !strings.Contains(helpOut, "chore, spike") || !strings.Contains(helpOut, "review (in_progress)") {
+		t.Errorf("help should list the workspace's types and statuses:\n%s", helpOut)
+	}
+}
+
+func TestCLI_Help_LeavesDatabaseAlone(t *testing.T) {
+	setupTestDir(t)
+
+	// No workspace: help does not create one
+	if _, err := runCLI([]string{"--db", "elsewhere/tasks.db", "help"}); err != nil {
+		t.Fatalf("help failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("elsewhere", "tasks.db")); !os.IsNotExist(err) {
+		t.Errorf("help should not create a database: %v", err)
+	}
+
+	// A workspace from an older bl: help does not migrate it
+	os.MkdirAll(beadsDir, 0755)
+	db, err := sql.Open("sqlite3", filepath.Join(beadsDir, dbName))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE issues (
+		id TEXT PRIMARY KEY, title TEXT NOT NULL, description TEXT,
+		status TEXT NOT NULL DEFAULT 'open', priority INTEGER NOT NULL DEFAULT 2,
+		issue_type TEXT NOT NULL DEFAULT 'task', created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL, closed_at DATETIME, resolution TEXT)`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	if _, err := runCLI([]string{"help"}); err != nil {
+		t.Fatalf("help failed: %v", err)
+	}
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil || tables != 1 {
+		t.Errorf("help should not migrate the database: %d tables, %v", tables, err)
+	}
+}
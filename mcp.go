@@ -0,0 +1,559 @@
+package beadslite
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// mcpProtocolVersions are the Model Context Protocol revisions the server
+// speaks, newest first. A client asking for another one is offered the newest.
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpInstructions is sent to clients on initialize as a hint for the model.
+const mcpInstructions = "beads-lite issue tracker. Call ready to find unblocked work, " +
+	"claim an issue before starting on it, and close it when done. " +
+	"IDs may be given as unique prefixes, with or without the workspace prefix."
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// rpcRequest is a JSON-RPC 2.0 request, or a notification if ID is absent.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse is a JSON-RPC 2.0 response carrying either Result or Error.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error member of a JSON-RPC 2.0 response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// MCPServer exposes a Tracker to coding agents as Model Context Protocol tools.
+// It speaks newline-delimited JSON-RPC 2.0, the MCP stdio transport, and
+// handles one request at a time.
+type MCPServer struct {
+	tracker Tracker
+
+	// AfterChange, if set, runs after every tool call that modifies the
+	// tracker. bl mcp uses it to keep sync.auto exports current.
+	AfterChange func() error
+}
+
+// NewMCPServer returns a server for the tools of t.
+func NewMCPServer(t Tracker) *MCPServer {
+	return &MCPServer{tracker: t}
+}
+
+// Serve reads requests from r and writes responses to w until r is
+// exhausted. Malformed requests are answered with JSON-RPC errors rather
+// than ending the session.
+func (s *MCPServer) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	encoder := json.NewEncoder(w)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("read request: %w", readErr)
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			if resp := s.handle(line); resp != nil {
+				if err := encoder.Encode(resp); err != nil {
+					return fmt.Errorf("write response: %w", err)
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
+
+// handle answers one JSON-RPC message. It returns nil for notifications,
+// which get no response.
+func (s *MCPServer) handle(line []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"),
+			Error: &rpcError{Code: rpcParseError, Message: "parse error: " + err.Error()}}
+	}
+	notification := len(req.ID) == 0
+
+	var result any
+	var rpcErr *rpcError
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		rpcErr = &rpcError{Code: rpcInvalidRequest, Message: "invalid request: expected jsonrpc 2.0 and a method"}
+	} else {
+		result, rpcErr = s.dispatch(req.Method, req.Params)
+	}
+
+	if notification {
+		return nil
+	}
+	if rpcErr != nil {
+		return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch runs one MCP method.
+func (s *MCPServer) dispatch(method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+			}
+		}
+		version := mcpProtocolVersions[0]
+		if slices.Contains(mcpProtocolVersions, p.ProtocolVersion) {
+			version = p.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "beads-lite", "version": Version},
+			"instructions":    mcpInstructions,
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		tools, err := s.tools()
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		return s.callTool(params)
+	default:
+		if strings.HasPrefix(method, "notifications/") {
+			return nil, nil
+		}
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// callTool runs a tools/call request. Failures of the tool itself, such as
+// an unknown issue, are reported in the result with isError set so the
+// model can see and react to them.
+func (s *MCPServer) callTool(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	tools, err := s.tools()
+	if err != nil {
+		return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+	}
+	idx := slices.IndexFunc(tools, func(t mcpTool) bool { return t.Name == p.Name })
+	if idx < 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	tool := tools[idx]
+
+	value, err := tool.call(p.Arguments)
+	if err == nil && tool.changes && s.AfterChange != nil {
+		err = s.AfterChange()
+	}
+	if err != nil {
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}, nil
+	}
+
+	text, err := json.Marshal(value)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(text)}},
+		"structuredContent": value,
+	}, nil
+}
+
+// mcpTool is one tool as listed by tools/list, with the function that runs it.
+type mcpTool struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	InputSchema  map[string]any `json:"inputSchema"`
+	OutputSchema map[string]any `json:"outputSchema,omitempty"`
+
+	call    func(args json.RawMessage) (any, error)
+	changes bool // whether a successful call modifies the store
+}
+
+// mcpIssueList is the result of the tools that return several issues.
+type mcpIssueList struct {
+	Issues []IssueExport `json:"issues"`
+}
+
+// tools returns the server's tools. Their schemas follow the export schema
+// and list the workspace's own types and statuses, so they are built per
+// request.
+func (s *MCPServer) tools() ([]mcpTool, error) {
+	vocab, err := s.tracker.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+
+	issue := issueSchema()
+	property := func(name, description string) map[string]any {
+		prop := map[string]any{}
+		for k, v := range issue["properties"].(map[string]any)[name].(map[string]any) {
+			prop[k] = v
+		}
+		prop["description"] = description
+		return prop
+	}
+	idProp := map[string]any{"type": "string", "minLength": 1, "description": "Issue ID or a unique prefix of it"}
+	idList := func(description string) map[string]any {
+		return map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": description}
+	}
+	statusProp := func(description string) map[string]any {
+		prop := property("status", description)
+		prop["enum"] = vocab.Statuses
+		return prop
+	}
+	typeProp := func(description string) map[string]any {
+		prop := property("issue_type", description)
+		prop["enum"] = vocab.Types
+		return prop
+	}
+	limitProp := map[string]any{"type": "integer", "minimum": 1, "description": "Return at most this many issues"}
+	object := func(required []string, properties map[string]any) map[string]any {
+		schema := map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	}
+
+	issueOutput := issueSchema()
+	issueOutput["$defs"] = map[string]any{"dependency": dependencySchema()}
+	listOutput := map[string]any{
+		"type":     "object",
+		"required": []string{"issues"},
+		"properties": map[string]any{
+			"issues": map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/issue"}},
+		},
+		"$defs": map[string]any{"issue": issueSchema(), "dependency": dependencySchema()},
+	}
+
+	return []mcpTool{
+		{
+			Name:        "ready",
+			Description: "List open and in-progress issues that no open issue blocks, most urgent first.",
+			InputSchema: object(nil, map[string]any{
+				"priority":   property("priority", "Only issues with this priority (0 = critical, 4 = lowest)"),
+				"issue_type": typeProp("Only issues of this type"),
+				"limit":      limitProp,
+			}),
+			OutputSchema: listOutput,
+			call:         s.ready,
+		},
+		{
+			Name:        "search",
+			Description: "Find issues whose ID, title, or description contains the query, ignoring case. Omit the query to list every issue.",
+			InputSchema: object(nil, map[string]any{
+				"query":      map[string]any{"type": "string", "description": "Text to look for"},
+				"status":     statusProp("Only issues with this status"),
+				"priority":   property("priority", "Only issues with this priority"),
+				"issue_type": typeProp("Only issues of this type"),
+				"limit":      limitProp,
+			}),
+			OutputSchema: listOutput,
+			call:         s.search,
+		},
+		{
+			Name:         "show",
+			Description:  "Get one issue with its blockers.",
+			InputSchema:  object([]string{"id"}, map[string]any{"id": idProp}),
+			OutputSchema: issueOutput,
+			call:         s.show,
+		},
+		{
+			Name:        "create",
+			Description: "Create an issue. Priority and type default to the workspace settings.",
+			InputSchema: object([]string{"title"}, map[string]any{
+				"title":       property("title", "Short summary of the work"),
+				"description": property("description", "Details, context, and acceptance criteria"),
+				"priority":    property("priority", "0 = critical, 4 = lowest"),
+				"issue_type":  typeProp("Kind of work"),
+				"parent":      map[string]any{"type": "string", "description": "Create as a child of this issue, with ID <parent>.N"},
+				"blocked_by":  idList("Issues that must close before this one is ready (<workspace>:<id> for another workspace)"),
+			}),
+			OutputSchema: issueOutput,
+			call:         s.create,
+			changes:      true,
+		},
+		{
+			Name:        "update",
+			Description: "Change an issue's fields or blockers. Only the given fields change; closed issues cannot be moved back to an open status.",
+			InputSchema: object([]string{"id"}, map[string]any{
+				"id":          idProp,
+				"title":       property("title", "New title"),
+				"description": property("description", "New description"),
+				"status":      statusProp("New status"),
+				"priority":    property("priority", "New priority"),
+				"issue_type":  typeProp("New type"),
+				"blocked_by":  idList("Blockers to add"),
+				"unblock":     idList("Blockers to remove"),
+				"if_version":  property("version", "Fail instead of updating if the issue is no longer at this version"),
+			}),
+			OutputSchema: issueOutput,
+			call:         s.update,
+			changes:      true,
+		},
+		{
+			Name:         "claim",
+			Description:  "Start work on an open issue by moving it to in_progress. Fails if the issue is already in progress or closed, so two agents never take the same issue.",
+			InputSchema:  object([]string{"id"}, map[string]any{"id": idProp}),
+			OutputSchema: issueOutput,
+			call:         s.claim,
+			changes:      true,
+		},
+		{
+			Name:        "close",
+			Description: "Close an issue, unblocking the issues that depend on it.",
+			InputSchema: object([]string{"id"}, map[string]any{
+				"id":         idProp,
+				"resolution": property("resolution", "Why it was closed; defaults to the workspace's defaults.resolution"),
+			}),
+			OutputSchema: issueOutput,
+			call:         s.close,
+			changes:      true,
+		},
+	}, nil
+}
+
+// decodeArgs decodes tool arguments into v, rejecting unknown fields so a
+// misspelled argument is not silently ignored.
+func decodeArgs(raw json.RawMessage, v any) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// resolveIssue resolves a possibly partial ID argument.
+func (s *MCPServer) resolveIssue(ref string) (string, error) {
+	if ref == "" {
+		return "", errors.New("id is required")
+	}
+	id, err := s.tracker.ResolveID(ref)
+	if err != nil {
+		return "", fmt.Errorf("issue %s: %w", ref, err)
+	}
+	return id, nil
+}
+
+// issueResult returns an issue with its dependencies, as exported.
+func (s *MCPServer) issueResult(id string) (IssueExport, error) {
+	issue, err := s.tracker.GetIssue(id)
+	if err != nil {
+		return IssueExport{}, fmt.Errorf("issue %s: %w", id, err)
+	}
+	deps, err := s.tracker.GetDependencies(id)
+	if err != nil {
+		return IssueExport{}, fmt.Errorf("get dependencies: %w", err)
+	}
+	return toIssueExport(issue, deps), nil
+}
+
+// listResult returns issues with their dependencies, as exported.
+func (s *MCPServer) listResult(issues []*Issue) (mcpIssueList, error) {
+	allDeps, err := s.tracker.GetAllDependencies()
+	if err != nil {
+		return mcpIssueList{}, fmt.Errorf("get all dependencies: %w", err)
+	}
+	list := mcpIssueList{Issues: make([]IssueExport, len(issues))}
+	for i, issue := range issues {
+		list.Issues[i] = toIssueExport(issue, allDeps[issue.ID])
+	}
+	return list, nil
+}
+
+// findIssues validates filter and returns the issues it selects, from
+// ready work only if ready is set.
+func (s *MCPServer) findIssues(filter IssueFilter, ready bool) (mcpIssueList, error) {
+	vocab, err := s.tracker.Vocabulary()
+	if err != nil {
+		return mcpIssueList{}, err
+	}
+	if err := filter.Validate(vocab); err != nil {
+		return mcpIssueList{}, err
+	}
+
+	var issues []*Issue
+	if ready {
+		if issues, err = s.tracker.ReadyIssues(filter); err != nil {
+			return mcpIssueList{}, fmt.Errorf("failed to get ready work: %w", err)
+		}
+	} else if issues, err = s.tracker.FindIssues(filter); err != nil {
+		return mcpIssueList{}, err
+	}
+	return s.listResult(issues)
+}
+
+func (s *MCPServer) ready(raw json.RawMessage) (any, error) {
+	var args struct {
+		Priority *int      `json:"priority"`
+		Type     IssueType `json:"issue_type"`
+		Limit    int       `json:"limit"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	return s.findIssues(IssueFilter{Type: args.Type, Priority: args.Priority, Limit: args.Limit}, true)
+}
+
+func (s *MCPServer) search(raw json.RawMessage) (any, error) {
+	var args struct {
+		Query    string    `json:"query"`
+		Status   Status    `json:"status"`
+		Priority *int      `json:"priority"`
+		Type     IssueType `json:"issue_type"`
+		Limit    int       `json:"limit"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	return s.findIssues(IssueFilter{
+		Status:   args.Status,
+		Type:     args.Type,
+		Priority: args.Priority,
+		Query:    args.Query,
+		Limit:    args.Limit,
+	}, false)
+}
+
+func (s *MCPServer) show(raw json.RawMessage) (any, error) {
+	var args struct {
+		ID string `json:"id"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveIssue(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueResult(id)
+}
+
+func (s *MCPServer) create(raw json.RawMessage) (any, error) {
+	var args CreateRequest
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	issue, err := Create(s.tracker, args)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueResult(issue.ID)
+}
+
+func (s *MCPServer) update(raw json.RawMessage) (any, error) {
+	var args struct {
+		ID string `json:"id"`
+		UpdateRequest
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveIssue(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Update(s.tracker, id, args.UpdateRequest); err != nil {
+		return nil, err
+	}
+	return s.issueResult(id)
+}
+
+func (s *MCPServer) claim(raw json.RawMessage) (any, error) {
+	var args struct {
+		ID string `json:"id"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveIssue(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tracker.ClaimIssue(id); err != nil {
+		return nil, err
+	}
+	return s.issueResult(id)
+}
+
+func (s *MCPServer) close(raw json.RawMessage) (any, error) {
+	var args struct {
+		ID         string     `json:"id"`
+		Resolution Resolution `json:"resolution"`
+	}
+	if err := decodeArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	id, err := s.resolveIssue(args.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := args.Resolution
+	if resolution == "" {
+		settings, err := s.tracker.Settings()
+		if err != nil {
+			return nil, err
+		}
+		resolution = settings.DefaultResolution
+	} else if !resolution.Valid() {
+		return nil, fmt.Errorf("invalid resolution: %q (must be done, wontfix, or duplicate)", resolution)
+	}
+
+	if err := s.tracker.CloseIssue(id, resolution); err != nil {
+		return nil, fmt.Errorf("failed to close: %w", err)
+	}
+	return s.issueResult(id)
+}
@@ -0,0 +1,440 @@
+package beadslite
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mcpClient drives an MCPServer over in-memory pipes the way an agent would:
+// one JSON-RPC message per line in each direction.
+type mcpClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	nextID int
+}
+
+// newMCPClient starts server in the background and returns a client for it.
+// The session is closed, and Serve's result checked, when the test ends.
+func newMCPClient(t *testing.T, server *MCPServer) *mcpClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		err := server.Serve(inR, outW)
+		outW.Close()
+		done <- err
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		io.Copy(io.Discard, outR)
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+
+	return &mcpClient{t: t, in: inW, out: bufio.NewReader(outR)}
+}
+
+// send writes one raw line to the server.
+func (c *mcpClient) send(line string) {
+	c.t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		c.t.Fatalf("send: %v", err)
+	}
+}
+
+// receive reads the next response from the server.
+func (c *mcpClient) receive() rpcTestResponse {
+	c.t.Helper()
+	line, err := c.out.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("receive: %v", err)
+	}
+	var resp rpcTestResponse
+	if err := json.Unmarshal(line, &resp); err != nil {
+		c.t.Fatalf("decode response %s: %v", line, err)
+	}
+	if resp.JSONRPC != "2.0" {
+		c.t.Errorf("response without jsonrpc 2.0: %s", line)
+	}
+	return resp
+}
+
+// rpcTestResponse is a decoded response, keeping the result raw.
+type rpcTestResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpcError       `json:"error"`
+}
+
+// call sends a request and returns its response, checking that the
+// response answers this request.
+func (c *mcpClient) call(method string, params any) rpcTestResponse {
+	c.t.Helper()
+	c.nextID++
+	req := map[string]any{"jsonrpc": "2.0", "id": c.nextID, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		c.t.Fatalf("encode request: %v", err)
+	}
+	c.send(string(data))
+
+	resp := c.receive()
+	if string(resp.ID) != strconv.Itoa(c.nextID) {
+		c.t.Fatalf("response id %s, want %d", resp.ID, c.nextID)
+	}
+	return resp
+}
+
+// notify sends a notification, which gets no response.
+func (c *mcpClient) notify(method string) {
+	c.t.Helper()
+	c.send(`{"jsonrpc":"2.0","method":"` + method + `"}`)
+}
+
+// toolResult is the decoded result of a tools/call.
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+	IsError           bool            `json:"isError"`
+}
+
+// text returns the result's text content.
+func (r toolResult) text() string {
+	var parts []string
+	for _, c := range r.Content {
+		parts = append(parts, c.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// callTool calls a tool and returns its result, failing on protocol errors.
+func (c *mcpClient) callTool(name string, args any) toolResult {
+	c.t.Helper()
+	resp := c.call("tools/call", map[string]any{"name": name, "arguments": args})
+	if resp.Error != nil {
+		c.t.Fatalf("tools/call %s: %v", name, resp.Error)
+	}
+	var result toolResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		c.t.Fatalf("decode tool result: %v", err)
+	}
+	return result
+}
+
+// issue calls a tool that returns one issue and decodes it, failing if the
+// tool reports an error.
+func (c *mcpClient) issue(name string, args any) IssueExport {
+	c.t.Helper()
+	result := c.callTool(name, args)
+	if result.IsError {
+		c.t.Fatalf("%s failed: %s", name, result.text())
+	}
+	var issue IssueExport
+	if err := json.Unmarshal(result.StructuredContent, &issue); err != nil {
+		c.t.Fatalf("decode issue: %v", err)
+	}
+	var fromText IssueExport
+	if err := json.Unmarshal([]byte(result.text()), &fromText); err != nil || fromText.ID != issue.ID {
+		c.t.Errorf("text content should carry the same issue as JSON: %s", result.text())
+	}
+	return issue
+}
+
+// issueIDs calls a tool that returns a list of issues and returns their IDs.
+func (c *mcpClient) issueIDs(name string, args any) []string {
+	c.t.Helper()
+	result := c.callTool(name, args)
+	if result.IsError {
+		c.t.Fatalf("%s failed: %s", name, result.text())
+	}
+	var list mcpIssueList
+	if err := json.Unmarshal(result.StructuredContent, &list); err != nil {
+		c.t.Fatalf("decode issue list: %v", err)
+	}
+	if list.Issues == nil {
+		c.t.Errorf("%s should return an issues array, got %s", name, result.StructuredContent)
+	}
+	ids := make([]string, len(list.Issues))
+	for i, issue := range list.Issues {
+		ids[i] = issue.ID
+	}
+	return ids
+}
+
+func TestMCP_Initialize(t *testing.T) {
+	client := newMCPClient(t, NewMCPServer(newTestStore(t)))
+
+	resp := client.call("initialize", map[string]any{
+		"protocolVersion": "2025-03-26",
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "test", "version": "1"},
+	})
+	if resp.Error != nil {
+		t.Fatalf("initialize: %v", resp.Error)
+	}
+	var result struct {
+		ProtocolVersion string                     `json:"protocolVersion"`
+		Capabilities    map[string]json.RawMessage `json:"capabilities"`
+		ServerInfo      struct{ Name string }      `json:"serverInfo"`
+	}
+	json.Unmarshal(resp.Result, &result)
+	if result.ProtocolVersion != "2025-03-26" {
+		t.Errorf("should accept a supported client version, got %q", result.ProtocolVersion)
+	}
+	if _, ok := result.Capabilities["tools"]; !ok {
+		t.Error("capabilities should include tools")
+	}
+	if result.ServerInfo.Name != "beads-lite" {
+		t.Errorf("serverInfo.name = %q", result.ServerInfo.Name)
+	}
+
+	// The initialized notification gets no response: the next response read
+	// must answer the ping.
+	client.notify("notifications/initialized")
+	if resp := client.call("ping", nil); resp.Error != nil {
+		t.Errorf("ping: %v", resp.Error)
+	}
+
+	resp = client.call("initialize", map[string]any{"protocolVersion": "1999-01-01"})
+	json.Unmarshal(resp.Result, &result)
+	if result.ProtocolVersion != mcpProtocolVersions[0] {
+		t.Errorf("unknown client version should get the newest, got %q", result.ProtocolVersion)
+	}
+}
+
+func TestMCP_ToolsList(t *testing.T) {
+	store := newTestStore(t)
+	store.SetSetting(configCustomTypes, "chore")
+	client := newMCPClient(t, NewMCPServer(store))
+
+	resp := client.call("tools/list", nil)
+	if resp.Error != nil {
+		t.Fatalf("tools/list: %v", resp.Error)
+	}
+	var result struct {
+		Tools []struct {
+			Name         string         `json:"name"`
+			Description  string         `json:"description"`
+			InputSchema  map[string]any `json:"inputSchema"`
+			OutputSchema map[string]any `json:"outputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		t.Fatalf("decode tools: %v", err)
+	}
+
+	var names []string
+	for _, tool := range result.Tools {
+		names = append(names, tool.Name)
+		if tool.Description == "" {
+			t.Errorf("%s has no description", tool.Name)
+		}
+		if tool.InputSchema["type"] != "object" || tool.OutputSchema["type"] != "object" {
+			t.Errorf("%s schemas should describe objects", tool.Name)
+		}
+	}
+	want := []string{"ready", "search", "show", "create", "update", "claim", "close"}
+	if !slices.Equal(names, want) {
+		t.Errorf("tools = %v, want %v", names, want)
+	}
+
+	// Type enums come from the workspace vocabulary
+	create := result.Tools[slices.Index(names, "create")]
+	issueType := create.InputSchema["properties"].(map[string]any)["issue_type"].(map[string]any)
+	if !slices.Contains(issueType["enum"].([]any), any("chore")) {
+		t.Errorf("create issue_type enum should include the custom type: %v", issueType["enum"])
+	}
+	if required := create.InputSchema["required"].([]any); !slices.Equal(required, []any{"title"}) {
+		t.Errorf("create should require only title, got %v", required)
+	}
+}
+
+func TestMCP_IssueLifecycle(t *testing.T) {
+	store := newTestStore(t)
+	client := newMCPClient(t, NewMCPServer(store))
+
+	tests := client.issue("create", map[string]any{"title": "Write tests", "priority": 1})
+	deploy := client.issue("create", map[string]any{
+		"title":       "Deploy",
+		"description": "Ship it",
+		"issue_type":  "feature",
+		"blocked_by":  []string{tests.ID},
+	})
+	if deploy.Type != IssueTypeFeature || deploy.Priority != 2 || deploy.Description != "Ship it" {
+		t.Errorf("unexpected created issue: %+v", deploy)
+	}
+	if len(deploy.Dependencies) != 1 || deploy.Dependencies[0].DependsOn != tests.ID {
+		t.Errorf("create should record blockers: %+v", deploy.Dependencies)
+	}
+
+	if ids := client.issueIDs("ready", nil); !slices.Equal(ids, []string{tests.ID}) {
+		t.Errorf("ready = %v, want only %s", ids, tests.ID)
+	}
+
+	claimed := client.issue("claim", map[string]any{"id": tests.ID})
+	if claimed.Status != StatusInProgress {
+		t.Errorf("claim should move to in_progress, got %s", claimed.Status)
+	}
+
+	closed := client.issue("close", map[string]any{"id": tests.ID, "resolution": "done"})
+	if closed.Status != StatusClosed || closed.ClosedAt == nil || closed.Resolution != ResolutionDone {
+		t.Errorf("unexpected closed issue: %+v", closed)
+	}
+	if ids := client.issueIDs("ready", nil); !slices.Equal(ids, []string{deploy.ID}) {
+		t.Errorf("ready after close = %v, want %s", ids, deploy.ID)
+	}
+
+	// Partial IDs work, and only the given fields change
+	updated := client.issue("update", map[string]any{
+		"id":      strings.TrimPrefix(deploy.ID, "bl-"),
+		"title":   "Deploy to prod",
+		"unblock": []string{tests.ID},
+	})
+	if updated.Title != "Deploy to prod" || updated.Description != "Ship it" || updated.Type != IssueTypeFeature {
+		t.Errorf("unexpected updated issue: %+v", updated)
+	}
+	if len(updated.Dependencies) != 0 {
+		t.Errorf("unblock should remove the blocker: %+v", updated.Dependencies)
+	}
+
+	shown := client.issue("show", map[string]any{"id": deploy.ID})
+	if shown.Title != "Deploy to prod" {
+		t.Errorf("show = %+v", shown)
+	}
+	got, _ := store.GetIssue(deploy.ID)
+	if got.Title != "Deploy to prod" {
+		t.Errorf("changes should be in the store, got %q", got.Title)
+	}
+}
+
+func TestMCP_Claim_AlreadyClaimed(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Contended")
+	store.CreateIssue(issue)
+	client := newMCPClient(t, NewMCPServer(store))
+
+	client.issue("claim", map[string]any{"id": issue.ID})
+	result := client.callTool("claim", map[string]any{"id": issue.ID})
+	if !result.IsError || !strings.Contains(result.text(), "already claimed") {
+		t.Errorf("second claim should fail, got %+v", result)
+	}
+}
+
+func TestMCP_Search(t *testing.T) {
+	store := newTestStore(t)
+	login := NewIssue("Fix login bug")
+	login.Type = IssueTypeBug
+	signup := NewIssue("Signup page")
+	signup.Description = "Reuse the LOGIN form"
+	other := NewIssue("Unrelated")
+	store.CreateIssue(login)
+	store.CreateIssue(signup)
+	store.CreateIssue(other)
+	client := newMCPClient(t, NewMCPServer(store))
+
+	ids := client.issueIDs("search", map[string]any{"query": "login"})
+	if len(ids) != 2 || !slices.Contains(ids, login.ID) || !slices.Contains(ids, signup.ID) {
+		t.Errorf("search should match title and description ignoring case, got %v", ids)
+	}
+	if ids := client.issueIDs("search", map[string]any{"query": "login", "issue_type": "bug"}); !slices.Equal(ids, []string{login.ID}) {
+		t.Errorf("search with type filter = %v", ids)
+	}
+	if ids := client.issueIDs("search", map[string]any{"limit": 1}); len(ids) != 1 {
+		t.Errorf("limit should cap results, got %v", ids)
+	}
+	if ids := client.issueIDs("search", map[string]any{"query": "nothing like this"}); len(ids) != 0 {
+		t.Errorf("expected no matches, got %v", ids)
+	}
+}
+
+func TestMCP_Errors(t *testing.T) {
+	store := newTestStore(t)
+	closed := NewIssue("Done already")
+	store.CreateIssue(closed)
+	store.CloseIssue(closed.ID, ResolutionDone)
+	client := newMCPClient(t, NewMCPServer(store))
+
+	// Tool failures are results the model can read
+	toolErrors := []struct {
+		tool string
+		args any
+		want string
+	}{
+		{"show", map[string]any{"id": "bl-zzzz"}, "not found"},
+		{"show", map[string]any{}, "id is required"},
+		{"create", map[string]any{"title": " "}, "title is required"},
+		{"create", map[string]any{"title": "x", "issue_type": "saga"}, "invalid type"},
+		{"create", map[string]any{"title": "x", "assignee": "me"}, "unknown field"},
+		{"update", map[string]any{"id": closed.ID, "status": "open"}, "use reopen"},
+		{"search", map[string]any{"status": "blocked"}, "invalid status"},
+		{"close", map[string]any{"id": closed.ID, "resolution": "meh"}, "invalid resolution"},
+	}
+	for _, tt := range toolErrors {
+		result := client.callTool(tt.tool, tt.args)
+		if !result.IsError || !strings.Contains(result.text(), tt.want) {
+			t.Errorf("%s %v: want error containing %q, got %+v", tt.tool, tt.args, tt.want, result)
+		}
+	}
+
+	// Protocol errors are JSON-RPC errors
+	if resp := client.call("tools/call", map[string]any{"name": "nope"}); resp.Error == nil || resp.Error.Code != rpcInvalidParams {
+		t.Errorf("unknown tool: got %+v", resp.Error)
+	}
+	if resp := client.call("resources/list", nil); resp.Error == nil || resp.Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method: got %+v", resp.Error)
+	}
+	client.send(`{not json`)
+	if resp := client.receive(); resp.Error == nil || resp.Error.Code != rpcParseError || string(resp.ID) != "null" {
+		t.Errorf("parse error: got %+v", resp)
+	}
+	client.send(`{"id":99,"method":"ping"}`)
+	if resp := client.receive(); resp.Error == nil || resp.Error.Code != rpcInvalidRequest || string(resp.ID) != "99" {
+		t.Errorf("missing jsonrpc version: got %+v", resp)
+	}
+
+	// The session survives all of the above
+	if resp := client.call("ping", nil); resp.Error != nil {
+		t.Errorf("ping after errors: %v", resp.Error)
+	}
+}
+
+func TestMCP_AfterChange(t *testing.T) {
+	store := newTestStore(t)
+	server := NewMCPServer(store)
+	changes := 0
+	server.AfterChange = func() error {
+		changes++
+		return nil
+	}
+	client := newMCPClient(t, server)
+
+	issue := client.issue("create", map[string]any{"title": "Tracked"})
+	client.issueIDs("ready", nil)
+	client.issue("show", map[string]any{"id": issue.ID})
+	client.callTool("show", map[string]any{"id": "bl-none"})
+	if changes != 1 {
+		t.Errorf("only create should count as a change so far, got %d", changes)
+	}
+
+	client.issue("close", map[string]any{"id": issue.ID})
+	client.callTool("close", map[string]any{"id": "bl-none"})
+	if changes != 2 {
+		t.Errorf("failed calls should not count as changes, got %d", changes)
+	}
+}
@@ -0,0 +1,420 @@
+package beadslite
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryTracker is a Tracker that keeps its issues in memory, for testing
+// code written against Tracker without a database. It follows the same
+// rules as Store: the status workflow, ID resolution, child IDs, and ready
+// work. It always has the default vocabulary and settings, and it has no
+// other workspaces, so blockers in them are rejected.
+//
+// A MemoryTracker is safe for concurrent use. Issues and dependencies are
+// copied in and out, so callers cannot change its state by accident.
+type MemoryTracker struct {
+	mu       sync.Mutex
+	settings Settings
+	issues   map[string]*Issue
+	deps     map[string][]*Dependency // issue ID -> its dependencies
+	children map[string]int           // parent ID -> last child number
+}
+
+var _ Tracker = (*MemoryTracker)(nil)
+
+// NewMemoryTracker returns an empty tracker.
+func NewMemoryTracker() *MemoryTracker {
+	return &MemoryTracker{
+		settings: Settings{
+			DefaultPriority:   2,
+			DefaultType:       IssueTypeTask,
+			DefaultResolution: ResolutionDone,
+			IDPrefix:          defaultIDPrefix,
+			IDLength:          defaultIDLength,
+			OutputFormat:      OutputText,
+		},
+		issues:   make(map[string]*Issue),
+		deps:     make(map[string][]*Dependency),
+		children: make(map[string]int),
+	}
+}
+
+// copyIssue returns a copy of issue that shares no memory with it.
+func copyIssue(issue *Issue) *Issue {
+	c := *issue
+	if issue.ClosedAt != nil {
+		closedAt := *issue.ClosedAt
+		c.ClosedAt = &closedAt
+	}
+	if issue.DeletedAt != nil {
+		deletedAt := *issue.DeletedAt
+		c.DeletedAt = &deletedAt
+	}
+	c.Extra = maps.Clone(issue.Extra)
+	return &c
+}
+
+// copyDependencies returns copies of deps.
+func copyDependencies(deps []*Dependency) []*Dependency {
+	copies := make([]*Dependency, len(deps))
+	for i, dep := range deps {
+		c := *dep
+		copies[i] = &c
+	}
+	return copies
+}
+
+// NewIssue returns an unsaved issue with an unused ID.
+func (m *MemoryTracker) NewIssue(title string) (*Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issue := NewIssue(title)
+	length := adaptiveIDLength(len(m.issues), m.settings.IDLength)
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		if attempt > 0 && attempt%attemptsPerLength == 0 && length < maxIDLength {
+			length++
+		}
+		id := generateHashID(m.settings.IDPrefix, title, "", issue.CreatedAt.Add(time.Duration(attempt)), length)
+		if m.issues[id] == nil {
+			issue.ID = id
+			return issue, nil
+		}
+	}
+	return nil, errors.New("no free ID found")
+}
+
+// NewChildIssue returns an unsaved issue with the next child ID of parentID.
+func (m *MemoryTracker) NewChildIssue(parentID, title string) (*Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.issues[parentID] == nil {
+		return nil, fmt.Errorf("parent %s: %w", parentID, ErrIssueNotFound)
+	}
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		m.children[parentID]++
+		id := childID(parentID, m.children[parentID])
+		if m.issues[id] == nil {
+			issue := NewIssue(title)
+			issue.ID = id
+			return issue, nil
+		}
+	}
+	return nil, errors.New("no free child ID found")
+}
+
+// CreateIssue saves a new issue.
+func (m *MemoryTracker) CreateIssue(issue *Issue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vocab := DefaultVocabulary()
+	if err := issue.ValidateIn(vocab); err != nil {
+		return err
+	}
+	if m.issues[issue.ID] != nil {
+		return fmt.Errorf("insert issue %s: %w", issue.ID, ErrIDExists)
+	}
+	normalizeClosedFields(issue, vocab, issue.UpdatedAt)
+	issue.Version = 1
+	m.issues[issue.ID] = copyIssue(issue)
+
+	// Child IDs created directly must not be handed out again
+	if parentID, n, ok := ParseChildID(issue.ID); ok && n > m.children[parentID] {
+		m.children[parentID] = n
+	}
+	return nil
+}
+
+// GetIssue returns the issue with the given ID.
+func (m *MemoryTracker) GetIssue(id string) (*Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issue := m.issues[id]
+	if issue == nil {
+		return nil, ErrIssueNotFound
+	}
+	return copyIssue(issue), nil
+}
+
+// ResolveID expands a partial ID with the same rules as Store.ResolveID.
+func (m *MemoryTracker) ResolveID(partial string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if partial == "" {
+		return "", ErrIssueNotFound
+	}
+	ids := make([]string, 0, len(m.issues))
+	for id := range m.issues {
+		ids = append(ids, id)
+	}
+	return resolveIDAmong(partial, ids)
+}
+
+// UpdateIssue saves changes to an issue, following the status workflow,
+// and fails with ErrConflict if issue.Version is neither 0 nor the stored
+// version.
+func (m *MemoryTracker) UpdateIssue(issue *Issue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vocab := DefaultVocabulary()
+	if err := issue.ValidateIn(vocab); err != nil {
+		return err
+	}
+	current := m.issues[issue.ID]
+	if current == nil {
+		return ErrIssueNotFound
+	}
+	if issue.Version != 0 && issue.Version != current.Version {
+		return fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, issue.ID, current.Version, issue.Version)
+	}
+	if err := checkTransition(vocab, current.Status, issue.Status); err != nil {
+		return err
+	}
+	if vocab.IsClosed(issue.Status) && !vocab.IsClosed(current.Status) && issue.Resolution == "" {
+		issue.Resolution = ResolutionDone
+	}
+
+	issue.CreatedAt = current.CreatedAt
+	issue.UpdatedAt = time.Now()
+	issue.Version = current.Version + 1
+	normalizeClosedFields(issue, vocab, issue.UpdatedAt)
+	m.issues[issue.ID] = copyIssue(issue)
+	return nil
+}
+
+// CloseIssue closes an issue with the given resolution. Closing an already
+// closed issue updates its resolution but keeps its closed_at.
+func (m *MemoryTracker) CloseIssue(id string, resolution Resolution) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issue := m.issues[id]
+	if issue == nil {
+		return ErrIssueNotFound
+	}
+	now := time.Now()
+	issue.Status = StatusClosed
+	issue.UpdatedAt = now
+	if issue.ClosedAt == nil {
+		issue.ClosedAt = &now
+	}
+	issue.Resolution = resolution
+	issue.Version++
+	return nil
+}
+
+// ReopenIssue moves a closed issue back to open and clears its closed_at
+// and resolution.
+func (m *MemoryTracker) ReopenIssue(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issue := m.issues[id]
+	if issue == nil {
+		return ErrIssueNotFound
+	}
+	if !DefaultVocabulary().IsClosed(issue.Status) {
+		return fmt.Errorf("%w: %s is %s, not closed", ErrInvalidTransition, id, issue.Status)
+	}
+	issue.Status = StatusOpen
+	issue.UpdatedAt = time.Now()
+	issue.ClosedAt = nil
+	issue.Resolution = ""
+	issue.Version++
+	return nil
+}
+
+// ClaimIssue moves an open issue to in_progress.
+func (m *MemoryTracker) ClaimIssue(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	issue := m.issues[id]
+	if issue == nil {
+		return ErrIssueNotFound
+	}
+	if DefaultVocabulary().Category(issue.Status) != StatusOpen {
+		return fmt.Errorf("%w: %s is %s", ErrAlreadyClaimed, id, issue.Status)
+	}
+	issue.Status = StatusInProgress
+	issue.UpdatedAt = time.Now()
+	issue.Version++
+	return nil
+}
+
+// DeleteIssue removes an issue and every dependency on either side of it.
+func (m *MemoryTracker) DeleteIssue(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.issues[id] == nil {
+		return ErrIssueNotFound
+	}
+	delete(m.issues, id)
+	delete(m.deps, id)
+	for issueID, deps := range m.deps {
+		kept := deps[:0]
+		for _, dep := range deps {
+			if dep.DependsOnID != id {
+				kept = append(kept, dep)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.deps, issueID)
+		} else {
+			m.deps[issueID] = kept
+		}
+	}
+	return nil
+}
+
+// sorted returns copies of the issues that keep passes, ordered like
+// Store.ListIssues.
+func (m *MemoryTracker) sorted(keep func(*Issue) bool) []*Issue {
+	var issues []*Issue
+	for _, issue := range m.issues {
+		if keep(issue) {
+			issues = append(issues, copyIssue(issue))
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+	return issues
+}
+
+// FindIssues returns the issues matching filter, most urgent first.
+func (m *MemoryTracker) FindIssues(filter IssueFilter) ([]*Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return filter.Apply(m.sorted(func(*Issue) bool { return true })), nil
+}
+
+// ReadyIssues returns the open and in-progress issues that no open issue
+// blocks and that match filter, most urgent first.
+func (m *MemoryTracker) ReadyIssues(filter IssueFilter) ([]*Issue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vocab := DefaultVocabulary()
+	ready := m.sorted(func(issue *Issue) bool {
+		if vocab.Category(issue.Status) == StatusClosed {
+			return false
+		}
+		for _, dep := range m.deps[issue.ID] {
+			blocker := m.issues[dep.DependsOnID]
+			if dep.Type == DepBlocks && blocker != nil && !vocab.IsClosed(blocker.Status) {
+				return false
+			}
+		}
+		return true
+	})
+	return filter.Apply(ready), nil
+}
+
+// GetDependencies returns the dependencies of one issue.
+func (m *MemoryTracker) GetDependencies(issueID string) ([]*Dependency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.deps[issueID]) == 0 {
+		return nil, nil
+	}
+	return copyDependencies(m.deps[issueID]), nil
+}
+
+// GetAllDependencies returns every dependency, keyed by issue ID.
+func (m *MemoryTracker) GetAllDependencies() (map[string][]*Dependency, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	all := make(map[string][]*Dependency, len(m.deps))
+	for id, deps := range m.deps {
+		all[id] = copyDependencies(deps)
+	}
+	return all, nil
+}
+
+// AddBlocker makes blocker, which may be a partial ID, block issueID.
+func (m *MemoryTracker) AddBlocker(issueID, blocker string) error {
+	if workspace, _ := ParseIssueRef(blocker); workspace != "" {
+		return fmt.Errorf("blocker issue %s: a memory tracker has no other workspaces", blocker)
+	}
+	blockerID, err := m.ResolveID(blocker)
+	if err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blocker, err)
+	}
+	if blockerID == issueID {
+		return ErrSelfDependency
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.issues[issueID] == nil {
+		return fmt.Errorf("issue %s: %w", issueID, ErrIssueNotFound)
+	}
+	for _, dep := range m.deps[issueID] {
+		if dep.DependsOnID == blockerID && dep.Type == DepBlocks {
+			return fmt.Errorf("blocker issue %s: %w", blockerID, ErrDependencyExists)
+		}
+	}
+	m.deps[issueID] = append(m.deps[issueID], NewDependency(issueID, blockerID, DepBlocks))
+	return nil
+}
+
+// RemoveBlocker removes one of issueID's blockers, matching blocker against
+// the current blockers as Store.RemoveBlocker does.
+func (m *MemoryTracker) RemoveBlocker(issueID, blocker string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blockerID, err := resolveIDAmong(blocker, blockerIDs(m.deps[issueID]))
+	if err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blocker, err)
+	}
+	var kept []*Dependency
+	for _, dep := range m.deps[issueID] {
+		if dep.DependsOnID != blockerID || dep.Type != DepBlocks {
+			kept = append(kept, dep)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.deps, issueID)
+	} else {
+		m.deps[issueID] = kept
+	}
+	return nil
+}
+
+// Settings returns the tracker's settings, which are always the defaults.
+func (m *MemoryTracker) Settings() (*Settings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	settings := m.settings
+	return &settings, nil
+}
+
+// Vocabulary returns the default issue types and statuses.
+func (m *MemoryTracker) Vocabulary() (*Vocabulary, error) {
+	return DefaultVocabulary(), nil
+}
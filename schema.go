@@ -0,0 +1,107 @@
+package beadslite
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ExportSchema returns a JSON Schema (draft 2020-12) describing one line of a
+// JSONL export: either the optional header record or an issue record. Issue
+// records allow additional properties, since newer exporters may add fields
+// that older importers preserve without understanding.
+func ExportSchema() map[string]any {
+	header := map[string]any{
+		"type":     "object",
+		"required": []string{"format", "format_version", "exported_at"},
+		"properties": map[string]any{
+			"format":           map[string]any{"const": FormatName},
+			"format_version":   map[string]any{"type": "integer", "minimum": 1},
+			"exporter_version": map[string]any{"type": "string"},
+			"id_prefix":        map[string]any{"type": "string"},
+			"exported_at":      timestampSchema(),
+		},
+	}
+
+	issue := issueSchema()
+	issue["not"] = map[string]any{"required": []string{"format"}}
+
+	return map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         "https://github.com/kylesnowschwartz/beads-lite/schema/export-v1.json",
+		"title":       "beads-lite JSONL export record",
+		"description": "One line of a beads-lite JSONL export (format version 1).",
+		"oneOf": []any{
+			map[string]any{"$ref": "#/$defs/header"},
+			map[string]any{"$ref": "#/$defs/issue"},
+		},
+		"$defs": map[string]any{
+			"header":     header,
+			"issue":      issue,
+			"dependency": dependencySchema(),
+		},
+	}
+}
+
+// timestampSchema describes an RFC 3339 timestamp.
+func timestampSchema() map[string]any {
+	return map[string]any{"type": "string", "format": "date-time"}
+}
+
+// issueSchema describes one issue as exported, with its blockers. Its
+// dependencies refer to "#/$defs/dependency", which the enclosing schema
+// must define with dependencySchema.
+func issueSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id", "title", "status", "priority", "issue_type", "created_at", "updated_at"},
+		"properties": map[string]any{
+			"id":          map[string]any{"type": "string", "minLength": 1},
+			"title":       map[string]any{"type": "string", "minLength": 1},
+			"description": map[string]any{"type": "string"},
+			"status": map[string]any{
+				"type": "string", "minLength": 1,
+				"description": "open, in_progress, closed, or a custom status of the exporting workspace",
+			},
+			"priority": map[string]any{"type": "integer", "minimum": 0, "maximum": 4},
+			"issue_type": map[string]any{
+				"type": "string", "minLength": 1,
+				"description": "task, bug, feature, epic, or a custom type of the exporting workspace",
+			},
+			"created_at": timestampSchema(),
+			"updated_at": timestampSchema(),
+			"closed_at":  timestampSchema(),
+			"resolution": map[string]any{"enum": []Resolution{ResolutionDone, ResolutionWontfix, ResolutionDuplicate}},
+			"created_by": map[string]any{"type": "string", "description": "actor setting of whoever created the issue"},
+			"updated_by": map[string]any{"type": "string", "description": "actor setting of whoever changed the issue last"},
+			"deleted_at": timestampSchema(),
+			"version": map[string]any{
+				"type": "integer", "minimum": 1,
+				"description": "write count of the issue in the serving database; absent from export files and ignored on import",
+			},
+			"dependencies": map[string]any{
+				"type":  []string{"array", "null"},
+				"items": map[string]any{"$ref": "#/$defs/dependency"},
+			},
+		},
+		"additionalProperties": true,
+	}
+}
+
+// dependencySchema describes one entry of an issue's dependencies.
+func dependencySchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"depends_on", "type"},
+		"properties": map[string]any{
+			"depends_on": map[string]any{"type": "string", "minLength": 1},
+			"type":       map[string]any{"enum": []DepType{DepBlocks}},
+		},
+	}
+}
+
+// WriteExportSchema writes ExportSchema to w as indented JSON.
+func WriteExportSchema(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ExportSchema())
+}
@@ -1,32 +1,85 @@
 package beadslite
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
 	"time"
 
-	_ "github.com/ncruces/go-sqlite3/driver"
+	"github.com/ncruces/go-sqlite3"
+	"github.com/ncruces/go-sqlite3/driver"
 	_ "github.com/ncruces/go-sqlite3/embed"
 )
 
 // ErrIssueNotFound is returned when an issue does not exist in the database.
 var ErrIssueNotFound = errors.New("issue not found")
 
+// ErrIDExists is returned by CreateIssue when another issue already has the
+// new issue's ID.
+var ErrIDExists = errors.New("issue ID already in use")
+
+// ErrConflict is returned by UpdateIssue when the issue has been written
+// since the caller read it, so saving it would undo someone else's change.
+var ErrConflict = errors.New("issue changed since it was read")
+
 // Store provides SQLite-backed storage for issues and dependencies.
+//
+// Every method that reads or writes the database has a ...Context variant
+// that runs its queries under ctx, so a caller can cancel them or give them a
+// deadline. The plain methods use context.Background().
 type Store struct {
 	db *sql.DB
+	q  querier // runs the queries: db, or tx in a StoreTx
+	tx *sql.Tx // the transaction of a StoreTx, nil otherwise
+}
+
+// querier is what Store methods run their queries on. It is implemented by
+// *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// StoreTx is a Store whose methods all run in one database transaction, on
+// one connection. It is only valid inside the function passed to
+// WithTransaction.
+type StoreTx struct {
+	*Store
 }
 
+// How long a connection waits for another process's lock before SQLite
+// gives up with SQLITE_BUSY, and how writes that still hit it are retried.
+const (
+	busyTimeout = 5 * time.Second
+	busyRetries = 5
+	busyBackoff = 50 * time.Millisecond
+)
+
 // NewStore creates a new Store with the given database path.
 // Use ":memory:" for an in-memory database.
+//
+// File databases are switched to WAL mode, so agents reading the tracker do
+// not block one writing to it, and every connection enforces foreign keys
+// and waits up to busyTimeout for locks held by other processes.
 func NewStore(dbPath string) (*Store, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := driver.Open(dbPath, initConn)
 	if err != nil {
 		return nil, fmt.Errorf("open database %s: %w", dbPath, err)
 	}
 
-	store := &Store{db: db}
+	// Persistent in the file; in-memory databases stay in memory mode
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("enable WAL: %w", err)
+	}
+
+	store := &Store{db: db, q: db}
 	if err := store.initSchema(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("init schema: %w", err)
@@ -35,30 +88,97 @@ func NewStore(dbPath string) (*Store, error) {
 	return store, nil
 }
 
-// Close closes the database connection.
+// initConn configures each new connection to the database.
+func initConn(conn *sqlite3.Conn) error {
+	if err := conn.BusyTimeout(busyTimeout); err != nil {
+		return err
+	}
+	return conn.Exec(`PRAGMA foreign_keys = ON`)
+}
+
+// Close closes the database connection. The Store of a StoreTx cannot be
+// closed; the transaction ends when its function returns.
 func (s *Store) Close() error {
+	if s.tx != nil {
+		return errors.New("close: store is part of a transaction")
+	}
 	return s.db.Close()
 }
 
-// WithTransaction executes the given function within a database transaction.
-// If fn returns an error, the transaction is rolled back. Otherwise, it is committed.
-func (s *Store) WithTransaction(fn func() error) error {
-	if _, err := s.db.Exec("BEGIN IMMEDIATE"); err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+// WithTransaction executes fn within a database transaction. Everything fn
+// does through tx runs on the transaction's connection, and is rolled back
+// if fn returns an error or panics. Otherwise it is committed. The
+// transaction takes the write lock when it begins, so other writers wait
+// for it rather than fail halfway through.
+//
+// fn must use tx, not s: s runs on other connections, which wait for the
+// transaction to end before they can write. Calling WithTransaction on a
+// StoreTx runs fn in the existing transaction.
+func (s *Store) WithTransaction(fn func(tx *StoreTx) error) error {
+	return s.WithTransactionContext(context.Background(), fn)
+}
+
+// WithTransactionContext is WithTransaction with a context. If ctx is done
+// by the time fn returns, the transaction is rolled back instead of
+// committed.
+func (s *Store) WithTransactionContext(ctx context.Context, fn func(tx *StoreTx) error) error {
+	if s.tx != nil {
+		return fn(&StoreTx{s})
 	}
 
-	if err := fn(); err != nil {
-		s.db.Exec("ROLLBACK")
+	// The driver begins serializable transactions with BEGIN IMMEDIATE
+	var sqlTx *sql.Tx
+	err := retryBusy(ctx, func() (err error) {
+		sqlTx, err = s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
 		return err
+	})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
 	}
+	defer sqlTx.Rollback() // no-op once committed
 
-	if _, err := s.db.Exec("COMMIT"); err != nil {
-		s.db.Exec("ROLLBACK")
+	if err := fn(&StoreTx{&Store{db: s.db, q: sqlTx, tx: sqlTx}}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
 	return nil
 }
 
+// exec runs a statement that writes to the database, retrying it if the
+// database stays locked by another process for longer than busyTimeout.
+func (s *Store) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := retryBusy(ctx, func() (err error) {
+		result, err = s.q.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// retryBusy calls fn until it returns anything but SQLITE_BUSY, up to
+// busyRetries more times, waiting twice as long (with jitter) before each
+// retry. It gives up early when ctx is done.
+func retryBusy(ctx context.Context, fn func() error) error {
+	wait := busyBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if attempt == busyRetries || !errors.Is(err, sqlite3.BUSY) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait/2 + rand.N(wait)):
+		}
+		wait *= 2
+	}
+}
+
 func (s *Store) initSchema() error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS issues (
@@ -71,7 +191,12 @@ func (s *Store) initSchema() error {
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
 		closed_at DATETIME,
-		resolution TEXT
+		resolution TEXT,
+		extra TEXT,
+		version INTEGER NOT NULL DEFAULT 1,
+		deleted_at DATETIME, -- set while the issue is in the trash
+		created_by TEXT,
+		updated_by TEXT
 	);
 
 	CREATE TABLE IF NOT EXISTS dependencies (
@@ -86,37 +211,212 @@ func (s *Store) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_deps_type ON dependencies(type, depends_on_id);
 	CREATE INDEX IF NOT EXISTS idx_issues_status ON issues(status);
+
+	CREATE TABLE IF NOT EXISTS remote_workspaces (
+		name TEXT PRIMARY KEY,
+		path TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+
+	-- Dependencies on issues in other workspaces, referenced as <workspace>:<id>.
+	-- Kept apart from dependencies so its foreign keys stay meaningful.
+	CREATE TABLE IF NOT EXISTS external_dependencies (
+		issue_id TEXT NOT NULL,
+		workspace TEXT NOT NULL,
+		depends_on_id TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'blocks',
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (issue_id, workspace, depends_on_id, type),
+		FOREIGN KEY (issue_id) REFERENCES issues(id)
+	);
+
+	-- Highest child number handed out under each parent, so numbers are never
+	-- reused even after a child is deleted.
+	CREATE TABLE IF NOT EXISTS child_counters (
+		parent_id TEXT PRIMARY KEY,
+		last INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS config (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
 	`
 	if _, err := s.db.Exec(schema); err != nil {
 		return fmt.Errorf("exec schema: %w", err)
 	}
+	return s.migrate()
+}
+
+// migrate upgrades databases created by older versions to the current schema.
+// CREATE TABLE IF NOT EXISTS leaves existing tables alone, so columns added
+// after the first release must be added here as well.
+func (s *Store) migrate() error {
+	for _, col := range addedIssueColumns {
+		if err := s.addColumnIfMissing("issues", col.name, col.decl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addedIssueColumns are the issues columns added after the first release, in
+// the order they were added, with the value an issue from before each one
+// reads as.
+var addedIssueColumns = []struct {
+	name, decl, fallback string
+}{
+	{"extra", "TEXT", "NULL"},
+	{"version", "INTEGER NOT NULL DEFAULT 1", "1"},
+	{"deleted_at", "DATETIME", "NULL"},
+	{"created_by", "TEXT", "NULL"},
+	{"updated_by", "TEXT", "NULL"},
+}
+
+// addColumnIfMissing adds a column to a table unless it already exists.
+func (s *Store) addColumnIfMissing(table, column, decl string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    bool
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
+// issueColumns lists the issues columns in the order scanIssue expects.
+const issueColumns = `id, title, description, status, priority, issue_type,
+	created_at, updated_at, closed_at, COALESCE(resolution, ''), COALESCE(extra, ''), version, deleted_at,
+	COALESCE(created_by, ''), COALESCE(updated_by, '')`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanIssue reads one row selected with issueColumns.
+func scanIssue(row rowScanner) (*Issue, error) {
+	issue := &Issue{}
+	var extra string
+	if err := row.Scan(&issue.ID, &issue.Title, &issue.Description, &issue.Status, &issue.Priority,
+		&issue.Type, &issue.CreatedAt, &issue.UpdatedAt, &issue.ClosedAt, &issue.Resolution, &extra,
+		&issue.Version, &issue.DeletedAt, &issue.CreatedBy, &issue.UpdatedBy); err != nil {
+		return nil, err
+	}
+	if extra != "" {
+		if err := json.Unmarshal([]byte(extra), &issue.Extra); err != nil {
+			return nil, fmt.Errorf("issue %s: decode extra fields: %w", issue.ID, err)
+		}
+	}
+	return issue, nil
+}
+
+// encodeExtra serializes an issue's preserved unknown fields for storage.
+func encodeExtra(extra map[string]json.RawMessage) (any, error) {
+	if len(extra) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(extra)
+	if err != nil {
+		return nil, fmt.Errorf("encode extra fields: %w", err)
+	}
+	return string(data), nil
+}
+
 // CreateIssue inserts a new issue into the database.
 func (s *Store) CreateIssue(issue *Issue) error {
-	if err := issue.Validate(); err != nil {
+	return s.CreateIssueContext(context.Background(), issue)
+}
+
+// CreateIssueContext is CreateIssue with a context.
+func (s *Store) CreateIssueContext(ctx context.Context, issue *Issue) error {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := issue.ValidateIn(vocab); err != nil {
 		return err
 	}
+	normalizeClosedFields(issue, vocab, issue.UpdatedAt)
+	extra, err := encodeExtra(issue.Extra)
+	if err != nil {
+		return err
+	}
+	// Imported issues keep the actors they came with
+	if issue.CreatedBy == "" {
+		if issue.CreatedBy, err = s.actor(ctx); err != nil {
+			return err
+		}
+	}
+	if issue.UpdatedBy == "" {
+		issue.UpdatedBy = issue.CreatedBy
+	}
 
-	if _, err := s.db.Exec(`
-		INSERT INTO issues (id, title, description, status, priority, issue_type, created_at, updated_at, closed_at, resolution)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	if _, err := s.exec(ctx, `
+		INSERT INTO issues (id, title, description, status, priority, issue_type, created_at, updated_at, closed_at, resolution, extra, version, deleted_at, created_by, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?)`,
 		issue.ID, issue.Title, issue.Description, issue.Status, issue.Priority, issue.Type,
-		issue.CreatedAt, issue.UpdatedAt, issue.ClosedAt, issue.Resolution); err != nil {
+		issue.CreatedAt, issue.UpdatedAt, issue.ClosedAt, issue.Resolution, extra, issue.DeletedAt,
+		issue.CreatedBy, issue.UpdatedBy); err != nil {
+		if errors.Is(err, sqlite3.CONSTRAINT_PRIMARYKEY) {
+			return fmt.Errorf("insert issue %s: %w", issue.ID, ErrIDExists)
+		}
 		return fmt.Errorf("insert issue: %w", err)
 	}
+	issue.Version = 1
+
+	// Child IDs created elsewhere (e.g. imported) must not be handed out again
+	if parentID, n, ok := ParseChildID(issue.ID); ok {
+		if _, err := s.exec(ctx, `
+			INSERT INTO child_counters (parent_id, last) VALUES (?, ?)
+			ON CONFLICT(parent_id) DO UPDATE SET last = MAX(last, excluded.last)`,
+			parentID, n); err != nil {
+			return fmt.Errorf("update child counter: %w", err)
+		}
+	}
 	return nil
 }
 
-// GetIssue retrieves an issue by ID.
+// GetIssue retrieves an issue by ID. Issues in the trash are not found.
 func (s *Store) GetIssue(id string) (*Issue, error) {
-	issue := &Issue{}
-	err := s.db.QueryRow(`
-		SELECT id, title, description, status, priority, issue_type, created_at, updated_at, closed_at, COALESCE(resolution, '')
-		FROM issues WHERE id = ?`, id).Scan(
-		&issue.ID, &issue.Title, &issue.Description, &issue.Status, &issue.Priority,
-		&issue.Type, &issue.CreatedAt, &issue.UpdatedAt, &issue.ClosedAt, &issue.Resolution)
+	return s.GetIssueContext(context.Background(), id)
+}
+
+// GetIssueContext is GetIssue with a context.
+func (s *Store) GetIssueContext(ctx context.Context, id string) (*Issue, error) {
+	return s.getIssue(ctx, id, false)
+}
+
+// getIssue retrieves an issue by ID, including one in the trash if trash
+// is set.
+func (s *Store) getIssue(ctx context.Context, id string, trash bool) (*Issue, error) {
+	issue, err := scanIssue(s.q.QueryRowContext(ctx, `
+		SELECT `+issueColumns+`
+		FROM issues WHERE id = ? AND (? OR deleted_at IS NULL)`, id, trash))
 
 	if err == sql.ErrNoRows {
 		return nil, ErrIssueNotFound
@@ -124,40 +424,280 @@ func (s *Store) GetIssue(id string) (*Issue, error) {
 	return issue, err
 }
 
-// UpdateIssue updates an existing issue.
+// UpdateIssue updates an existing issue. Status changes must follow the
+// workflow: a closed issue can only be reopened with ReopenIssue, and closing
+// one this way fills in closed_at and, if empty, resolution as CloseIssue
+// would.
+//
+// The issue is only saved if its Version is still the stored one, so that
+// a change made since it was read is not silently overwritten; otherwise
+// UpdateIssue fails with ErrConflict. A Version of 0 skips the check. On
+// success issue.Version is the new version. A missing issue, or one in the
+// trash, fails with ErrIssueNotFound.
 func (s *Store) UpdateIssue(issue *Issue) error {
-	if err := issue.Validate(); err != nil {
+	return s.UpdateIssueContext(context.Background(), issue)
+}
+
+// UpdateIssueContext is UpdateIssue with a context.
+func (s *Store) UpdateIssueContext(ctx context.Context, issue *Issue) error {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := issue.ValidateIn(vocab); err != nil {
+		return err
+	}
+	current, err := s.GetIssueContext(ctx, issue.ID)
+	if err != nil {
+		return err
+	}
+	issue.DeletedAt = nil // only DeleteIssue and RestoreIssue move issues in and out of the trash
+	if err := checkTransition(vocab, current.Status, issue.Status); err != nil {
+		return err
+	}
+	if vocab.IsClosed(issue.Status) && !vocab.IsClosed(current.Status) {
+		if err := s.checkCanClose(ctx, issue.ID, vocab); err != nil {
+			return err
+		}
+		if issue.Resolution == "" {
+			issue.Resolution = ResolutionDone
+		}
+	}
+	actor, err := s.actor(ctx)
+	if err != nil {
 		return err
 	}
+	return s.writeIssue(ctx, issue, vocab, actor)
+}
 
-	issue.UpdatedAt = time.Now()
-	if _, err := s.db.Exec(`
-		UPDATE issues SET title = ?, description = ?, status = ?, priority = ?,
-		issue_type = ?, updated_at = ?, closed_at = ?, resolution = ?
-		WHERE id = ?`,
-		issue.Title, issue.Description, issue.Status, issue.Priority,
-		issue.Type, issue.UpdatedAt, issue.ClosedAt, issue.Resolution, issue.ID); err != nil {
+// replaceIssue overwrites an existing issue without applying the workflow,
+// for imports that bring the issue's state over from elsewhere. The record's
+// updated_by is kept, as CreateIssue keeps it for new issues.
+func (s *Store) replaceIssue(ctx context.Context, issue *Issue) error {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err := issue.ValidateIn(vocab); err != nil {
+		return err
+	}
+	actor := issue.UpdatedBy
+	if actor == "" {
+		if actor, err = s.actor(ctx); err != nil {
+			return err
+		}
+	}
+	return s.writeIssue(ctx, issue, vocab, actor)
+}
+
+// writeIssue stores every field of an already validated issue as changed by
+// actor and bumps its version, provided issue.Version is the stored version
+// or 0.
+func (s *Store) writeIssue(ctx context.Context, issue *Issue, vocab *Vocabulary, actor string) error {
+	extra, err := encodeExtra(issue.Extra)
+	if err != nil {
+		return err
+	}
+
+	updatedAt := time.Now()
+	normalizeClosedFields(issue, vocab, updatedAt)
+	var version int64
+	err = retryBusy(ctx, func() error {
+		return s.q.QueryRowContext(ctx, `
+			UPDATE issues SET title = ?, description = ?, status = ?, priority = ?,
+			issue_type = ?, updated_at = ?, closed_at = ?, resolution = ?, extra = ?,
+			deleted_at = ?, updated_by = ?, version = version + 1
+			WHERE id = ? AND ? IN (0, version)
+			RETURNING version`,
+			issue.Title, issue.Description, issue.Status, issue.Priority,
+			issue.Type, updatedAt, issue.ClosedAt, issue.Resolution, extra,
+			issue.DeletedAt, actor, issue.ID, issue.Version).Scan(&version)
+	})
+	if err == sql.ErrNoRows {
+		return s.versionConflict(ctx, issue)
+	}
+	if err != nil {
 		return fmt.Errorf("update issue: %w", err)
 	}
+	issue.UpdatedAt = updatedAt
+	issue.UpdatedBy = actor
+	issue.Version = version
 	return nil
 }
 
-// CloseIssue marks an issue as closed with the given resolution.
+// actor returns the actor setting that changes are recorded under.
+func (s *Store) actor(ctx context.Context) (string, error) {
+	return s.SettingContext(ctx, configActor)
+}
+
+// versionConflict explains why writeIssue matched no row: either the issue
+// has moved past issue.Version, or it has been removed since it was read.
+func (s *Store) versionConflict(ctx context.Context, issue *Issue) error {
+	var current int64
+	err := s.q.QueryRowContext(ctx, `SELECT version FROM issues WHERE id = ?`, issue.ID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return ErrIssueNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("update issue: %w", err)
+	}
+	return fmt.Errorf("%w: %s is at version %d, not %d", ErrConflict, issue.ID, current, issue.Version)
+}
+
+// CloseIssue marks an issue as closed with the given resolution. Closing an
+// already closed issue updates its resolution but keeps its closed_at.
 func (s *Store) CloseIssue(id string, resolution Resolution) error {
+	return s.CloseIssueContext(context.Background(), id, resolution)
+}
+
+// CloseIssueContext is CloseIssue with a context.
+func (s *Store) CloseIssueContext(ctx context.Context, id string, resolution Resolution) error {
+	return s.closeIssue(ctx, id, resolution, 0)
+}
+
+// closeIssue closes an issue provided it is still at version, or whatever
+// its version if version is 0. It fails with ErrConflict otherwise.
+func (s *Store) closeIssue(ctx context.Context, id string, resolution Resolution, version int64) error {
+	current, err := s.GetIssueContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !vocab.IsClosed(current.Status) {
+		if err := s.checkCanClose(ctx, id, vocab); err != nil {
+			return err
+		}
+	}
+
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return err
+	}
 	now := time.Now()
-	if _, err := s.db.Exec(`
-		UPDATE issues SET status = ?, updated_at = ?, closed_at = ?, resolution = ?
-		WHERE id = ?`, StatusClosed, now, now, resolution, id); err != nil {
+	result, err := s.exec(ctx, `
+		UPDATE issues SET status = ?, updated_at = ?, closed_at = COALESCE(closed_at, ?), resolution = ?,
+		updated_by = ?, version = version + 1
+		WHERE id = ? AND ? IN (0, version)`, StatusClosed, now, now, resolution, actor, id, version)
+	if err != nil {
+		return fmt.Errorf("close issue: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
 		return fmt.Errorf("close issue: %w", err)
+	} else if n == 0 {
+		return s.versionConflict(ctx, &Issue{ID: id, Version: version})
 	}
 	return nil
 }
 
-// ListIssues returns all issues.
+// AmbiguousIDError is returned by ResolveID when a partial ID matches more
+// than one issue.
+type AmbiguousIDError struct {
+	Partial    string
+	Candidates []string // sorted
+}
+
+func (e *AmbiguousIDError) Error() string {
+	return fmt.Sprintf("ambiguous ID %q, could be %s", e.Partial, strings.Join(e.Candidates, ", "))
+}
+
+// ResolveID expands a partial issue ID to the full ID of the one issue it
+// identifies. partial may be a full ID, a unique prefix of one ("bl-a3"), or
+// a unique prefix of the hash alone ("a3f8", for when the "bl-" is dropped).
+// It returns ErrIssueNotFound if nothing matches and an *AmbiguousIDError if
+// several issues do.
+func (s *Store) ResolveID(partial string) (string, error) {
+	return s.ResolveIDContext(context.Background(), partial)
+}
+
+// ResolveIDContext is ResolveID with a context.
+func (s *Store) ResolveIDContext(ctx context.Context, partial string) (string, error) {
+	if partial == "" {
+		return "", ErrIssueNotFound
+	}
+	escaped := likeEscaper.Replace(partial)
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT id FROM issues
+		WHERE (id LIKE ? ESCAPE '\' OR id LIKE ? ESCAPE '\') AND deleted_at IS NULL`,
+		escaped+"%", "%-"+escaped+"%")
+	if err != nil {
+		return "", fmt.Errorf("resolve id: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return "", err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return resolveIDAmong(partial, ids)
+}
+
+// likeEscaper escapes the LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// resolveIDAmong applies ResolveID's matching rules to a list of IDs, which
+// may include "<workspace>:<id>" references.
+func resolveIDAmong(partial string, ids []string) (string, error) {
+	// A complete ID or hash ("a3f8") beats longer IDs it is a prefix of,
+	// such as the child IDs "bl-a3f8.1" and "bl-a3f8.2".
+	var exact, matches []string
+	for _, id := range ids {
+		if id == partial {
+			return id, nil
+		}
+		_, local := ParseIssueRef(id)
+		hash := local
+		if idx := strings.Index(local, "-"); idx >= 0 {
+			hash = local[idx+1:]
+		}
+		if local == partial || hash == partial {
+			exact = append(exact, id)
+		}
+		if strings.HasPrefix(id, partial) || strings.HasPrefix(local, partial) || strings.HasPrefix(hash, partial) {
+			matches = append(matches, id)
+		}
+	}
+	if len(exact) == 1 {
+		return exact[0], nil
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", ErrIssueNotFound
+	case 1:
+		return matches[0], nil
+	default:
+		sort.Strings(matches)
+		return "", &AmbiguousIDError{Partial: partial, Candidates: matches}
+	}
+}
+
+// ListIssues returns all issues that are not in the trash.
 func (s *Store) ListIssues() ([]*Issue, error) {
-	rows, err := s.db.Query(`
-		SELECT id, title, description, status, priority, issue_type, created_at, updated_at, closed_at, COALESCE(resolution, '')
-		FROM issues ORDER BY priority ASC, created_at ASC`)
+	return s.ListIssuesContext(context.Background())
+}
+
+// ListIssuesContext is ListIssues with a context.
+func (s *Store) ListIssuesContext(ctx context.Context) ([]*Issue, error) {
+	return s.listIssues(ctx, false)
+}
+
+// listIssues returns all issues, including those in the trash if trash is
+// set.
+func (s *Store) listIssues(ctx context.Context, trash bool) ([]*Issue, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+issueColumns+`
+		FROM issues WHERE ? OR deleted_at IS NULL
+		ORDER BY priority ASC, created_at ASC`, trash)
 	if err != nil {
 		return nil, err
 	}
@@ -166,23 +706,204 @@ func (s *Store) ListIssues() ([]*Issue, error) {
 	return scanIssues(rows)
 }
 
-// AddDependency creates a dependency between two issues.
+// SearchIssues returns the issues whose ID, title, or description contains
+// query, ignoring case, ordered like ListIssues.
+func (s *Store) SearchIssues(query string) ([]*Issue, error) {
+	return s.SearchIssuesContext(context.Background(), query)
+}
+
+// SearchIssuesContext is SearchIssues with a context.
+func (s *Store) SearchIssuesContext(ctx context.Context, query string) ([]*Issue, error) {
+	pattern := "%" + likeEscaper.Replace(query) + "%"
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+issueColumns+`
+		FROM issues
+		WHERE (id LIKE ? ESCAPE '\' OR title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\')
+		AND deleted_at IS NULL
+		ORDER BY priority ASC, created_at ASC`, pattern, pattern, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("search issues: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIssues(rows)
+}
+
+// FindIssues returns the issues matching filter, ordered like ListIssues.
+func (s *Store) FindIssues(filter IssueFilter) ([]*Issue, error) {
+	return s.FindIssuesContext(context.Background(), filter)
+}
+
+// FindIssuesContext is FindIssues with a context.
+func (s *Store) FindIssuesContext(ctx context.Context, filter IssueFilter) ([]*Issue, error) {
+	var issues []*Issue
+	var err error
+	if filter.Query != "" {
+		issues, err = s.SearchIssuesContext(ctx, filter.Query)
+	} else {
+		issues, err = s.ListIssuesContext(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(issues), nil
+}
+
+// ReadyIssues returns the ready work (see GetReadyWork) matching filter.
+func (s *Store) ReadyIssues(filter IssueFilter) ([]*Issue, error) {
+	return s.ReadyIssuesContext(context.Background(), filter)
+}
+
+// ReadyIssuesContext is ReadyIssues with a context.
+func (s *Store) ReadyIssuesContext(ctx context.Context, filter IssueFilter) ([]*Issue, error) {
+	issues, err := s.GetReadyWorkContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(issues), nil
+}
+
+// AddBlocker makes blocker block issueID. blocker may be a partial ID or a
+// "<workspace>:<id>" reference to an issue in a registered workspace.
+func (s *Store) AddBlocker(issueID, blocker string) error {
+	return s.AddBlockerContext(context.Background(), issueID, blocker)
+}
+
+// AddBlockerContext is AddBlocker with a context.
+func (s *Store) AddBlockerContext(ctx context.Context, issueID, blocker string) error {
+	var blockerID string
+	var err error
+	if workspace, _ := ParseIssueRef(blocker); workspace != "" {
+		blockerID, err = s.ResolveRemoteIDContext(ctx, blocker)
+	} else {
+		blockerID, err = s.ResolveIDContext(ctx, blocker)
+	}
+	if err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blocker, err)
+	}
+	if blockerID == issueID {
+		return ErrSelfDependency
+	}
+	if err := s.AddDependencyContext(ctx, issueID, blockerID, DepBlocks); err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blockerID, err)
+	}
+	return nil
+}
+
+// RemoveBlocker removes one of issueID's blockers. blocker is matched
+// against the current blockers, so a dependency on an issue that can no
+// longer be looked up can still be removed.
+func (s *Store) RemoveBlocker(issueID, blocker string) error {
+	return s.RemoveBlockerContext(context.Background(), issueID, blocker)
+}
+
+// RemoveBlockerContext is RemoveBlocker with a context.
+func (s *Store) RemoveBlockerContext(ctx context.Context, issueID, blocker string) error {
+	deps, err := s.GetDependenciesContext(ctx, issueID)
+	if err != nil {
+		return fmt.Errorf("get dependencies: %w", err)
+	}
+	blockerID, err := resolveIDAmong(blocker, blockerIDs(deps))
+	if err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blocker, err)
+	}
+	if err := s.RemoveDependencyContext(ctx, issueID, blockerID, DepBlocks); err != nil {
+		return fmt.Errorf("blocker issue %s: %w", blockerID, err)
+	}
+	return nil
+}
+
+// blockerIDs returns the IDs of the blocks dependencies among deps.
+func blockerIDs(deps []*Dependency) []string {
+	var ids []string
+	for _, dep := range deps {
+		if dep.Type == DepBlocks {
+			ids = append(ids, dep.DependsOnID)
+		}
+	}
+	return ids
+}
+
+// AddDependency creates a dependency between two issues. dependsOnID may be a
+// "<workspace>:<id>" reference to an issue in a registered workspace.
 func (s *Store) AddDependency(issueID, dependsOnID string, depType DepType) error {
+	return s.AddDependencyContext(context.Background(), issueID, dependsOnID, depType)
+}
+
+// AddDependencyContext is AddDependency with a context.
+func (s *Store) AddDependencyContext(ctx context.Context, issueID, dependsOnID string, depType DepType) error {
+	return s.addDependency(ctx, issueID, dependsOnID, depType, false)
+}
+
+// addDependency adds a dependency, allowing either end to be in the trash if
+// trash is set, as imports of trashed issues need.
+func (s *Store) addDependency(ctx context.Context, issueID, dependsOnID string, depType DepType, trash bool) error {
 	dep := NewDependency(issueID, dependsOnID, depType)
 	if err := dep.Validate(); err != nil {
 		return err
 	}
+	if err := s.requireIssue(ctx, issueID, trash); err != nil {
+		return err
+	}
 
-	_, err := s.db.Exec(`
+	if workspace, remoteID := ParseIssueRef(dependsOnID); workspace != "" {
+		_, err := s.exec(ctx, `
+			INSERT INTO external_dependencies (issue_id, workspace, depends_on_id, type, created_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			dep.IssueID, workspace, remoteID, dep.Type, dep.CreatedAt)
+		return dependencyInsertError(err)
+	}
+
+	if err := s.requireIssue(ctx, dependsOnID, trash); err != nil {
+		return err
+	}
+	_, err := s.exec(ctx, `
 		INSERT INTO dependencies (issue_id, depends_on_id, type, created_at)
 		VALUES (?, ?, ?, ?)`,
 		dep.IssueID, dep.DependsOnID, dep.Type, dep.CreatedAt)
+	return dependencyInsertError(err)
+}
+
+// dependencyInsertError reports an insert that hit an existing dependency as
+// ErrDependencyExists.
+func dependencyInsertError(err error) error {
+	if errors.Is(err, sqlite3.CONSTRAINT_PRIMARYKEY) {
+		return ErrDependencyExists
+	}
 	return err
 }
 
+// requireIssue fails with ErrIssueNotFound unless the issue exists and,
+// unless trash is set, is not in the trash. The foreign keys would refuse a
+// dependency on a missing issue anyway, but not say which end is missing.
+func (s *Store) requireIssue(ctx context.Context, id string, trash bool) error {
+	var exists bool
+	if err := s.q.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM issues WHERE id = ? AND (? OR deleted_at IS NULL))`,
+		id, trash).Scan(&exists); err != nil {
+		return fmt.Errorf("check issue %s: %w", id, err)
+	}
+	if !exists {
+		return fmt.Errorf("issue %s: %w", id, ErrIssueNotFound)
+	}
+	return nil
+}
+
 // RemoveDependency removes a dependency.
 func (s *Store) RemoveDependency(issueID, dependsOnID string, depType DepType) error {
-	_, err := s.db.Exec(`
+	return s.RemoveDependencyContext(context.Background(), issueID, dependsOnID, depType)
+}
+
+// RemoveDependencyContext is RemoveDependency with a context.
+func (s *Store) RemoveDependencyContext(ctx context.Context, issueID, dependsOnID string, depType DepType) error {
+	if workspace, remoteID := ParseIssueRef(dependsOnID); workspace != "" {
+		_, err := s.exec(ctx, `
+			DELETE FROM external_dependencies WHERE issue_id = ? AND workspace = ? AND depends_on_id = ? AND type = ?`,
+			issueID, workspace, remoteID, depType)
+		return err
+	}
+
+	_, err := s.exec(ctx, `
 		DELETE FROM dependencies WHERE issue_id = ? AND depends_on_id = ? AND type = ?`,
 		issueID, dependsOnID, depType)
 	return err
@@ -190,15 +911,44 @@ func (s *Store) RemoveDependency(issueID, dependsOnID string, depType DepType) e
 
 // RemoveAllDependencies removes all dependencies where the issue is the dependent.
 func (s *Store) RemoveAllDependencies(issueID string) error {
-	_, err := s.db.Exec(`DELETE FROM dependencies WHERE issue_id = ?`, issueID)
+	return s.RemoveAllDependenciesContext(context.Background(), issueID)
+}
+
+// RemoveAllDependenciesContext is RemoveAllDependencies with a context.
+func (s *Store) RemoveAllDependenciesContext(ctx context.Context, issueID string) error {
+	if _, err := s.exec(ctx, `DELETE FROM dependencies WHERE issue_id = ?`, issueID); err != nil {
+		return err
+	}
+	_, err := s.exec(ctx, `DELETE FROM external_dependencies WHERE issue_id = ?`, issueID)
 	return err
 }
 
+// trashedIDs selects the IDs of the issues in the trash.
+const trashedIDs = `(SELECT id FROM issues WHERE deleted_at IS NOT NULL)`
+
+// allDependencySelect selects local and cross-workspace dependencies alike,
+// with the latter's target written as "<workspace>:<id>".
+const allDependencySelect = `
+	SELECT issue_id, depends_on_id, type, created_at FROM dependencies
+	UNION ALL
+	SELECT issue_id, workspace || ':' || depends_on_id, type, created_at FROM external_dependencies`
+
+// dependencySelect is allDependencySelect without the dependencies of
+// issues in the trash or on them, which are kept for RestoreIssue but
+// otherwise treated as gone.
+const dependencySelect = `
+	SELECT * FROM (` + allDependencySelect + `)
+	WHERE issue_id NOT IN ` + trashedIDs + ` AND depends_on_id NOT IN ` + trashedIDs
+
 // GetDependencies returns all dependencies for an issue.
 func (s *Store) GetDependencies(issueID string) ([]*Dependency, error) {
-	rows, err := s.db.Query(`
-		SELECT issue_id, depends_on_id, type, created_at
-		FROM dependencies WHERE issue_id = ?`, issueID)
+	return s.GetDependenciesContext(context.Background(), issueID)
+}
+
+// GetDependenciesContext is GetDependencies with a context.
+func (s *Store) GetDependenciesContext(ctx context.Context, issueID string) ([]*Dependency, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT * FROM (`+dependencySelect+`) WHERE issue_id = ?`, issueID)
 	if err != nil {
 		return nil, err
 	}
@@ -215,38 +965,64 @@ func (s *Store) GetDependencies(issueID string) ([]*Dependency, error) {
 	return deps, rows.Err()
 }
 
-// GetReadyWork returns issues that are open and not blocked.
+// GetReadyWork returns issues that are open and not blocked. Blockers in other
+// workspaces are checked by reading those workspaces' databases.
 func (s *Store) GetReadyWork() ([]*Issue, error) {
+	return s.GetReadyWorkContext(context.Background())
+}
+
+// GetReadyWorkContext is GetReadyWork with a context.
+func (s *Store) GetReadyWorkContext(ctx context.Context) ([]*Issue, error) {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := vocab.StatusesIn(StatusOpen, StatusInProgress)
+	closed := vocab.StatusesIn(StatusClosed)
+
 	query := `
-		SELECT i.id, i.title, i.description, i.status, i.priority, i.issue_type,
-		       i.created_at, i.updated_at, i.closed_at, COALESCE(i.resolution, '')
+		SELECT ` + issueColumns + `
 		FROM issues i
-		WHERE i.status IN ('open', 'in_progress')
+		WHERE i.status IN (` + placeholders(len(active)) + `)
+		AND i.deleted_at IS NULL
 		AND i.id NOT IN (
 			SELECT DISTINCT d.issue_id
 			FROM dependencies d
 			JOIN issues blocker ON d.depends_on_id = blocker.id
 			WHERE d.type = 'blocks'
-			  AND blocker.status != 'closed'
+			  AND blocker.deleted_at IS NULL
+			  AND blocker.status NOT IN (` + placeholders(len(closed)) + `)
 		)
 		ORDER BY i.priority ASC, i.created_at ASC
 	`
 
-	rows, err := s.db.Query(query)
+	var args []any
+	for _, status := range append(active, closed...) {
+		args = append(args, status)
+	}
+	rows, err := s.q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	issues, err := scanIssues(rows)
+	rows.Close()
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	return scanIssues(rows)
+	return s.filterExternallyBlocked(ctx, issues)
+}
+
+// placeholders returns n comma-separated SQL parameter placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
 
 func scanIssues(rows *sql.Rows) ([]*Issue, error) {
 	var issues []*Issue
 	for rows.Next() {
-		issue := &Issue{}
-		if err := rows.Scan(&issue.ID, &issue.Title, &issue.Description, &issue.Status,
-			&issue.Priority, &issue.Type, &issue.CreatedAt, &issue.UpdatedAt, &issue.ClosedAt, &issue.Resolution); err != nil {
+		issue, err := scanIssue(rows)
+		if err != nil {
 			return nil, err
 		}
 		issues = append(issues, issue)
@@ -257,9 +1033,22 @@ func scanIssues(rows *sql.Rows) ([]*Issue, error) {
 // GetAllDependencies returns all dependencies in the database, keyed by issue_id.
 // Used for efficient tree building without N+1 queries.
 func (s *Store) GetAllDependencies() (map[string][]*Dependency, error) {
-	rows, err := s.db.Query(`
-		SELECT issue_id, depends_on_id, type, created_at
-		FROM dependencies`)
+	return s.GetAllDependenciesContext(context.Background())
+}
+
+// GetAllDependenciesContext is GetAllDependencies with a context.
+func (s *Store) GetAllDependenciesContext(ctx context.Context) (map[string][]*Dependency, error) {
+	return s.allDependencies(ctx, false)
+}
+
+// allDependencies returns all dependencies keyed by issue_id, including
+// those of issues in the trash if trash is set.
+func (s *Store) allDependencies(ctx context.Context, trash bool) (map[string][]*Dependency, error) {
+	query := dependencySelect
+	if trash {
+		query = allDependencySelect
+	}
+	rows, err := s.q.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -276,33 +1065,33 @@ func (s *Store) GetAllDependencies() (map[string][]*Dependency, error) {
 	return result, rows.Err()
 }
 
-// DeleteIssue removes an issue and all its dependencies from the database.
+// DeleteIssue moves an issue to the trash. From then on it is left out of
+// lists, ready work and exports, and its dependencies on either side no
+// longer count, but nothing is erased: RestoreIssue brings the issue back
+// with its dependencies, and PurgeTrash removes it for good.
 func (s *Store) DeleteIssue(id string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
+	return s.DeleteIssueContext(context.Background(), id)
+}
 
-	// Delete dependencies where this issue is involved (either side)
-	_, err = tx.Exec(`DELETE FROM dependencies WHERE issue_id = ? OR depends_on_id = ?`, id, id)
+// DeleteIssueContext is DeleteIssue with a context.
+func (s *Store) DeleteIssueContext(ctx context.Context, id string) error {
+	actor, err := s.actor(ctx)
 	if err != nil {
 		return err
 	}
-
-	// Delete the issue itself
-	result, err := tx.Exec(`DELETE FROM issues WHERE id = ?`, id)
+	now := time.Now()
+	result, err := s.exec(ctx, `
+		UPDATE issues SET deleted_at = ?, updated_at = ?, updated_by = ?, version = version + 1
+		WHERE id = ? AND deleted_at IS NULL`, now, now, actor, id)
 	if err != nil {
-		return err
+		return fmt.Errorf("delete issue: %w", err)
 	}
-
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
 	if rows == 0 {
-		return errors.New("issue not found")
+		return ErrIssueNotFound
 	}
-
-	return tx.Commit()
+	return nil
 }
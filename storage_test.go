@@ -1,10 +1,21 @@
 package beadslite
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/ncruces/go-sqlite3"
 )
 
 func TestNewStore(t *testing.T) {
@@ -249,11 +260,10 @@ func TestStoreUpdateIssueNonExistent(t *testing.T) {
 	// Create an issue object without storing it
 	issue := NewIssue("Non-existent Issue")
 
-	// Update should succeed (SQL UPDATE affects 0 rows, which is not an error)
-	// This documents current behavior: silent success on non-existent ID
+	// Like every Tracker, the store reports the missing issue
 	err := store.UpdateIssue(issue)
-	if err != nil {
-		t.Errorf("UpdateIssue() on non-existent ID should not error: %v", err)
+	if !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("UpdateIssue() on non-existent ID = %v, want ErrIssueNotFound", err)
 	}
 
 	// Verify issue was NOT created (update doesn't insert)
@@ -360,16 +370,16 @@ func TestStoreWithTransactionRollback(t *testing.T) {
 
 	// Execute transaction that creates an issue then fails
 	testErr := fmt.Errorf("intentional test failure")
-	err := store.WithTransaction(func() error {
+	err := store.WithTransaction(func(tx *StoreTx) error {
 		// Create another issue inside transaction
 		newIssue := NewIssue("Transaction Issue")
-		if err := store.CreateIssue(newIssue); err != nil {
+		if err := tx.CreateIssue(newIssue); err != nil {
 			return err
 		}
 
 		// Update existing issue
 		issue.Title = "Modified Title"
-		if err := store.UpdateIssue(issue); err != nil {
+		if err := tx.UpdateIssue(issue); err != nil {
 			return err
 		}
 
@@ -395,6 +405,160 @@ func TestStoreWithTransactionRollback(t *testing.T) {
 	}
 }
 
+func TestStoreTx_CommitsAndNests(t *testing.T) {
+	store, _ := newFileStore(t)
+
+	outer, inner := NewIssue("Outer"), NewIssue("Inner")
+	err := store.WithTransaction(func(tx *StoreTx) error {
+		if err := tx.CreateIssue(outer); err != nil {
+			return err
+		}
+		if err := tx.Close(); err == nil {
+			t.Error("closing a transaction's store should fail")
+		}
+		// A nested transaction joins the outer one
+		return tx.WithTransaction(func(nested *StoreTx) error {
+			if _, err := nested.GetIssue(outer.ID); err != nil {
+				return fmt.Errorf("nested transaction cannot see outer write: %w", err)
+			}
+			return nested.CreateIssue(inner)
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTransaction() error = %v", err)
+	}
+
+	for _, id := range []string{outer.ID, inner.ID} {
+		if _, err := store.GetIssue(id); err != nil {
+			t.Errorf("GetIssue(%s) after commit: %v", id, err)
+		}
+	}
+}
+
+func TestStoreWithTransactionContext_CanceledRollsBack(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := store.WithTransactionContext(ctx, func(tx *StoreTx) error {
+		if err := tx.CreateIssue(NewIssue("Never committed")); err != nil {
+			return err
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithTransactionContext() error = %v, want context.Canceled", err)
+	}
+
+	issues, _ := store.ListIssues()
+	if len(issues) != 0 {
+		t.Errorf("After cancel: got %d issues, want 0", len(issues))
+	}
+}
+
+func TestStoreContext_Canceled(t *testing.T) {
+	store := newTestStore(t)
+	defer store.Close()
+
+	issue := NewIssue("Existing")
+	if err := store.CreateIssue(issue); err != nil {
+		t.Fatalf("CreateIssue() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.GetIssueContext(ctx, issue.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetIssueContext() error = %v, want context.Canceled", err)
+	}
+	if _, err := store.ListIssuesContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListIssuesContext() error = %v, want context.Canceled", err)
+	}
+	if err := store.CreateIssueContext(ctx, NewIssue("Too late")); !errors.Is(err, context.Canceled) {
+		t.Errorf("CreateIssueContext() error = %v, want context.Canceled", err)
+	}
+	if err := store.CloseIssueContext(ctx, issue.ID, ResolutionDone); !errors.Is(err, context.Canceled) {
+		t.Errorf("CloseIssueContext() error = %v, want context.Canceled", err)
+	}
+
+	// Nothing was changed, and the store still works with a live context
+	got, err := store.GetIssueContext(context.Background(), issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssueContext() error = %v", err)
+	}
+	if got.Status != StatusOpen {
+		t.Errorf("Status = %s, want open", got.Status)
+	}
+}
+
+func TestNewStore_MigratesOldSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// A database created before the extra column existed
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE issues (
+		id TEXT PRIMARY KEY, title TEXT NOT NULL, description TEXT,
+		status TEXT NOT NULL DEFAULT 'open', priority INTEGER NOT NULL DEFAULT 2,
+		issue_type TEXT NOT NULL DEFAULT 'task', created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL, closed_at DATETIME, resolution TEXT)`); err != nil {
+		t.Fatalf("create old schema: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO issues (id, title, description, created_at, updated_at)
+		VALUES ('bl-old1', 'Before Migration', '', '2024-01-01T00:00:00Z', '2024-01-01T00:00:00Z')`); err != nil {
+		t.Fatalf("insert old issue: %v", err)
+	}
+	db.Close()
+
+	store, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	defer store.Close()
+
+	issue := NewIssue("After Migration")
+	issue.Extra = map[string]json.RawMessage{"assignee": json.RawMessage(`"bob"`)}
+	if err := store.CreateIssue(issue); err != nil {
+		t.Fatalf("CreateIssue() error = %v", err)
+	}
+	got, err := store.GetIssue(issue.ID)
+	if err != nil {
+		t.Fatalf("GetIssue() error = %v", err)
+	}
+	if string(got.Extra["assignee"]) != `"bob"` {
+		t.Errorf("Extra = %v, want assignee bob", got.Extra)
+	}
+
+	// Issues from before versions start at 1 and can be updated as usual
+	old, err := store.GetIssue("bl-old1")
+	if err != nil || old.Version != 1 {
+		t.Fatalf("old issue = %+v, %v; want version 1", old, err)
+	}
+	old.Priority = 0
+	if err := store.UpdateIssue(old); err != nil || old.Version != 2 {
+		t.Errorf("UpdateIssue(old) = %v, version %d; want version 2", err, old.Version)
+	}
+}
+
+func TestStoreUpdateIssue_VersionZeroSkipsCheck(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Unversioned")
+	store.CreateIssue(issue)
+	store.CloseIssue(issue.ID, ResolutionDone)
+
+	blind := &Issue{ID: issue.ID, Title: "Blind write", Status: StatusClosed, Priority: 2,
+		Type: IssueTypeTask, CreatedAt: issue.CreatedAt}
+	if err := store.UpdateIssue(blind); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+	got, _ := store.GetIssue(issue.ID)
+	if got.Title != "Blind write" || got.Version != 3 || blind.Version != 3 {
+		t.Errorf("after blind write = %+v (caller's version %d), want version 3", got, blind.Version)
+	}
+}
+
 // Helper to create a test store with in-memory database
 func newTestStore(t *testing.T) *Store {
 	t.Helper()
@@ -404,3 +568,317 @@ func newTestStore(t *testing.T) *Store {
 	}
 	return store
 }
+
+func TestStoreSearchIssues(t *testing.T) {
+	store := newTestStore(t)
+
+	login := NewIssue("Fix Login bug")
+	signup := NewIssue("Signup")
+	signup.Description = "shares the login form"
+	literal := NewIssue("100% done_ish")
+	store.CreateIssue(login)
+	store.CreateIssue(signup)
+	store.CreateIssue(literal)
+
+	ids := func(issues []*Issue) []string {
+		var out []string
+		for _, issue := range issues {
+			out = append(out, issue.ID)
+		}
+		return out
+	}
+
+	got, err := store.SearchIssues("LOGIN")
+	if err != nil {
+		t.Fatalf("SearchIssues: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("expected title and description matches, got %v", ids(got))
+	}
+
+	// LIKE wildcards in the query match literally
+	got, _ = store.SearchIssues("0%")
+	if len(got) != 1 || got[0].ID != literal.ID {
+		t.Errorf("%% should match literally, got %v", ids(got))
+	}
+	got, _ = store.SearchIssues("e_i")
+	if len(got) != 1 || got[0].ID != literal.ID {
+		t.Errorf("_ should match literally, got %v", ids(got))
+	}
+
+	got, _ = store.SearchIssues(login.ID)
+	if len(got) != 1 || got[0].ID != login.ID {
+		t.Errorf("search should match IDs, got %v", ids(got))
+	}
+
+	got, _ = store.SearchIssues("")
+	if len(got) != 3 {
+		t.Errorf("an empty query should match everything, got %v", ids(got))
+	}
+}
+
+func TestStoreResolveID(t *testing.T) {
+	store := newTestStore(t)
+	for _, id := range []string{"bl-a3f8", "bl-a3zz", "bl-b100", "bl-b100.1", "api-c7d2", "bl-x_y1"} {
+		issue := NewIssue("Issue " + id)
+		issue.ID = id
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue(%s): %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		partial   string
+		want      string
+		notFound  bool
+		ambiguous []string
+	}{
+		{partial: "bl-a3f8", want: "bl-a3f8"},
+		{partial: "bl-a3f", want: "bl-a3f8"},
+		{partial: "a3f8", want: "bl-a3f8"},
+		{partial: "c7", want: "api-c7d2"},
+		{partial: "api-c", want: "api-c7d2"},
+		{partial: "bl-b100", want: "bl-b100"}, // exact match beats longer IDs
+		{partial: "bl-a3", ambiguous: []string{"bl-a3f8", "bl-a3zz"}},
+		{partial: "a3", ambiguous: []string{"bl-a3f8", "bl-a3zz"}},
+		{partial: "bl-x_", want: "bl-x_y1"},
+		{partial: "bl-_", notFound: true}, // _ is not a wildcard
+		{partial: "zzzz", notFound: true},
+		{partial: "", notFound: true},
+	}
+	for _, tt := range tests {
+		got, err := store.ResolveID(tt.partial)
+		switch {
+		case tt.notFound:
+			if !errors.Is(err, ErrIssueNotFound) {
+				t.Errorf("ResolveID(%q) error = %v, want ErrIssueNotFound", tt.partial, err)
+			}
+		case tt.ambiguous != nil:
+			var ambiguous *AmbiguousIDError
+			if !errors.As(err, &ambiguous) {
+				t.Errorf("ResolveID(%q) error = %v, want AmbiguousIDError", tt.partial, err)
+			} else if !reflect.DeepEqual(ambiguous.Candidates, tt.ambiguous) {
+				t.Errorf("ResolveID(%q) candidates = %v, want %v", tt.partial, ambiguous.Candidates, tt.ambiguous)
+			}
+		default:
+			if err != nil || got != tt.want {
+				t.Errorf("ResolveID(%q) = %q, %v; want %q", tt.partial, got, err, tt.want)
+			}
+		}
+	}
+}
+
+func TestStoreDeleteIssue_NotFound(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.DeleteIssue("bl-none"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("expected ErrIssueNotFound, got %v", err)
+	}
+}
+
+func TestNewStore_ConnectionSettings(t *testing.T) {
+	store, _ := newFileStore(t)
+
+	var mode string
+	if err := store.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+
+	// Every connection in the pool, not just the first, enforces foreign keys
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conn, err := store.db.Conn(context.Background())
+		if err != nil {
+			t.Fatalf("Conn: %v", err)
+		}
+		defer conn.Close()
+		conns[i] = conn
+	}
+	for i, conn := range conns {
+		var fk int
+		if err := conn.QueryRowContext(context.Background(), `PRAGMA foreign_keys`).Scan(&fk); err != nil || fk != 1 {
+			t.Errorf("conn %d: foreign_keys = %d, %v; want 1", i, fk, err)
+		}
+	}
+
+	issue := NewIssue("Blocked")
+	store.CreateIssue(issue)
+	if err := store.AddDependency(issue.ID, "bl-none", DepBlocks); err == nil {
+		t.Error("expected a dependency on a missing issue to fail")
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	calls := 0
+	err := retryBusy(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("insert issue: %w", sqlite3.BUSY)
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("retryBusy = %v after %d calls, want nil after 3", err, calls)
+	}
+
+	calls = 0
+	err = retryBusy(context.Background(), func() error {
+		calls++
+		return sqlite3.CONSTRAINT
+	})
+	if !errors.Is(err, sqlite3.CONSTRAINT) || calls != 1 {
+		t.Errorf("retryBusy = %v after %d calls, want CONSTRAINT after 1", err, calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	err = retryBusy(ctx, func() error {
+		calls++
+		return sqlite3.BUSY
+	})
+	if !errors.Is(err, sqlite3.BUSY) || calls != 1 {
+		t.Errorf("retryBusy = %v after %d calls, want BUSY after 1 when canceled", err, calls)
+	}
+}
+
+// Environment for TestStoreConcurrentAgents_Process, which the stress test
+// runs as separate processes.
+const (
+	stressDBEnv   = "BL_STRESS_DB"
+	stressPoolEnv = "BL_STRESS_POOL"
+)
+
+// stressWorker acts like an agent: it creates issues of its own, then works
+// through pool in random order, closing every issue it manages to claim.
+// It reports each issue it created or claimed to report.
+func stressWorker(dbPath, name string, pool []string, report func(event, id string)) error {
+	store, err := NewStore(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	for i := 0; i < 10; i++ {
+		issue := NewIssue(fmt.Sprintf("%s task %d", name, i))
+		if err := store.CreateIssue(issue); err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		report("created", issue.ID)
+	}
+
+	order := append([]string(nil), pool...)
+	rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	for _, id := range order {
+		err := store.ClaimIssue(id)
+		if errors.Is(err, ErrAlreadyClaimed) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("claim %s: %w", id, err)
+		}
+		report("claimed", id)
+		if err := store.CloseIssue(id, ResolutionDone); err != nil {
+			return fmt.Errorf("close %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// TestStoreConcurrentAgents_Process is a worker process for
+// TestStoreConcurrentAgents; it does nothing when run directly.
+func TestStoreConcurrentAgents_Process(t *testing.T) {
+	dbPath := os.Getenv(stressDBEnv)
+	if dbPath == "" {
+		t.Skip("run by TestStoreConcurrentAgents")
+	}
+	err := stressWorker(dbPath, fmt.Sprintf("process %d", os.Getpid()), strings.Split(os.Getenv(stressPoolEnv), ","),
+		func(event, id string) { fmt.Printf("%s %s\n", event, id) })
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreConcurrentAgents(t *testing.T) {
+	store, dbPath := newFileStore(t)
+
+	var pool []string
+	for i := 0; i < 60; i++ {
+		issue := NewIssue(fmt.Sprintf("Pool %d", i))
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+		pool = append(pool, issue.ID)
+	}
+
+	var (
+		mu      sync.Mutex
+		created []string
+		claims  = map[string]int{}
+	)
+	report := func(event, id string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if event == "created" {
+			created = append(created, id)
+		} else {
+			claims[id]++
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 12)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- stressWorker(dbPath, fmt.Sprintf("goroutine %d", i), pool, report)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestStoreConcurrentAgents_Process$")
+			cmd.Env = append(os.Environ(), stressDBEnv+"="+dbPath, stressPoolEnv+"="+strings.Join(pool, ","))
+			out, err := cmd.Output()
+			if err != nil {
+				errs <- fmt.Errorf("process %d: %v\n%s", i, err, out)
+				return
+			}
+			for _, line := range strings.Split(string(out), "\n") {
+				if event, id, ok := strings.Cut(line, " "); ok && (event == "created" || event == "claimed") {
+					report(event, id)
+				}
+			}
+			errs <- nil
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No created issue went missing
+	if len(created) != 12*10 {
+		t.Errorf("workers reported %d created issues, want %d", len(created), 12*10)
+	}
+	for _, id := range created {
+		if _, err := store.GetIssue(id); err != nil {
+			t.Errorf("created issue %s: %v", id, err)
+		}
+	}
+
+	// Every pool issue was claimed exactly once, and its close stuck
+	for _, id := range pool {
+		if claims[id] != 1 {
+			t.Errorf("%s claimed %d times, want 1", id, claims[id])
+		}
+		issue, err := store.GetIssue(id)
+		if err != nil || issue.Status != StatusClosed || issue.ClosedAt == nil {
+			t.Errorf("%s = %+v, %v; want closed", id, issue, err)
+		}
+	}
+}
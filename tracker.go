@@ -0,0 +1,301 @@
+package beadslite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tracker is the issue tracking API that the bl commands, the MCP server,
+// and embedding Go programs are built on. *Store implements it on SQLite;
+// MemoryTracker implements it in memory for tests of code that uses it.
+//
+// Methods that take an issue ID expect a full ID; ResolveID expands a
+// partial one. Missing issues are reported with ErrIssueNotFound.
+type Tracker interface {
+	// NewIssue returns an unsaved issue with a fresh ID.
+	NewIssue(title string) (*Issue, error)
+	// NewChildIssue returns an unsaved issue with the next child ID of parentID.
+	NewChildIssue(parentID, title string) (*Issue, error)
+	// CreateIssue saves a new issue. It fails with ErrIDExists if the ID
+	// has been taken since it was handed out.
+	CreateIssue(issue *Issue) error
+	// GetIssue returns the issue with the given ID.
+	GetIssue(id string) (*Issue, error)
+	// ResolveID expands a unique prefix of an ID, or of its hash, to the full ID.
+	ResolveID(partial string) (string, error)
+	// UpdateIssue saves changes to an issue, following the status workflow.
+	// It fails with ErrConflict if issue.Version is set and the issue has
+	// been written since.
+	UpdateIssue(issue *Issue) error
+	// CloseIssue closes an issue with the given resolution.
+	CloseIssue(id string, resolution Resolution) error
+	// ReopenIssue moves a closed issue back to open.
+	ReopenIssue(id string) error
+	// ClaimIssue moves an open issue to in_progress, failing with
+	// ErrAlreadyClaimed if it is not open.
+	ClaimIssue(id string) error
+	// DeleteIssue removes an issue and its dependencies. *Store moves the
+	// issue to a trash it can be restored from; MemoryTracker has no trash.
+	DeleteIssue(id string) error
+
+	// FindIssues returns the issues matching filter, most urgent first.
+	FindIssues(filter IssueFilter) ([]*Issue, error)
+	// ReadyIssues returns the open issues that no open issue blocks and
+	// that match filter, most urgent first.
+	ReadyIssues(filter IssueFilter) ([]*Issue, error)
+
+	// GetDependencies returns the dependencies of one issue.
+	GetDependencies(issueID string) ([]*Dependency, error)
+	// GetAllDependencies returns every dependency, keyed by issue ID.
+	GetAllDependencies() (map[string][]*Dependency, error)
+	// AddBlocker makes blocker, which may be a partial ID, block issueID.
+	AddBlocker(issueID, blocker string) error
+	// RemoveBlocker removes one of issueID's blockers, which may be given
+	// as a partial ID.
+	RemoveBlocker(issueID, blocker string) error
+
+	// Settings returns the effective workspace settings.
+	Settings() (*Settings, error)
+	// Vocabulary returns the issue types and statuses the workspace allows.
+	Vocabulary() (*Vocabulary, error)
+}
+
+var _ Tracker = (*Store)(nil)
+
+// IssueFilter selects issues. The zero value matches every issue.
+type IssueFilter struct {
+	Status     Status
+	Type       IssueType
+	Priority   *int // nil for any priority
+	Resolution Resolution
+	Query      string // matched against ID, title, and description, ignoring case
+	Limit      int    // 0 for no limit
+}
+
+// Validate checks the filter's values against a workspace vocabulary.
+func (f IssueFilter) Validate(v *Vocabulary) error {
+	if f.Status != "" && !v.ValidStatus(f.Status) {
+		return fmt.Errorf("invalid status: %q (valid: %s)", f.Status, v.StatusList())
+	}
+	if f.Priority != nil && (*f.Priority < 0 || *f.Priority > 4) {
+		return fmt.Errorf("invalid priority: %d (valid: 0-4)", *f.Priority)
+	}
+	if f.Type != "" && !v.ValidType(f.Type) {
+		return fmt.Errorf("invalid type: %q (valid: %s)", f.Type, v.TypeList())
+	}
+	if f.Resolution != "" && !f.Resolution.Valid() {
+		return fmt.Errorf("invalid resolution: %q (valid: done, wontfix, duplicate)", f.Resolution)
+	}
+	if f.Limit < 0 {
+		return fmt.Errorf("invalid limit: %d", f.Limit)
+	}
+	return nil
+}
+
+// Match reports whether an issue passes the filter's field tests. It does
+// not apply Limit.
+func (f IssueFilter) Match(issue *Issue) bool {
+	if f.Status != "" && issue.Status != f.Status {
+		return false
+	}
+	if f.Priority != nil && issue.Priority != *f.Priority {
+		return false
+	}
+	if f.Type != "" && issue.Type != f.Type {
+		return false
+	}
+	if f.Resolution != "" && issue.Resolution != f.Resolution {
+		return false
+	}
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(issue.ID), query) &&
+			!strings.Contains(strings.ToLower(issue.Title), query) &&
+			!strings.Contains(strings.ToLower(issue.Description), query) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the issues that match the filter, up to its limit.
+func (f IssueFilter) Apply(issues []*Issue) []*Issue {
+	var matched []*Issue
+	for _, issue := range issues {
+		if f.Limit > 0 && len(matched) == f.Limit {
+			break
+		}
+		if f.Match(issue) {
+			matched = append(matched, issue)
+		}
+	}
+	return matched
+}
+
+// CreateRequest describes an issue for Create. Priority and Type default
+// to the workspace settings.
+type CreateRequest struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Priority    *int      `json:"priority"`
+	Type        IssueType `json:"issue_type"`
+	Parent      string    `json:"parent"`     // ID or partial ID of the parent issue
+	BlockedBy   []string  `json:"blocked_by"` // IDs or partial IDs of blockers
+}
+
+// maxCreateAttempts bounds how often Create allocates a new ID after losing
+// the one it had to another writer.
+const maxCreateAttempts = 5
+
+// Create creates an issue and its blockers the way bl create does.
+func Create(t Tracker, req CreateRequest) (*Issue, error) {
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, errors.New("title is required")
+	}
+	vocab, err := t.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+	if err := (IssueFilter{Type: req.Type, Priority: req.Priority}).Validate(vocab); err != nil {
+		return nil, err
+	}
+
+	var parentID string
+	if req.Parent != "" {
+		if parentID, err = t.ResolveID(req.Parent); err != nil {
+			return nil, fmt.Errorf("parent issue %s: %w", req.Parent, err)
+		}
+	}
+	settings, err := t.Settings()
+	if err != nil {
+		return nil, err
+	}
+
+	var issue *Issue
+	for attempt := 1; ; attempt++ {
+		if parentID != "" {
+			issue, err = t.NewChildIssue(parentID, req.Title)
+		} else {
+			issue, err = t.NewIssue(req.Title)
+		}
+		if err != nil {
+			return nil, err
+		}
+		issue.Description = req.Description
+		issue.Priority = settings.DefaultPriority
+		if req.Priority != nil {
+			issue.Priority = *req.Priority
+		}
+		issue.Type = settings.DefaultType
+		if req.Type != "" {
+			issue.Type = req.Type
+		}
+
+		// Another writer can take the ID between handing it out and saving
+		// the issue; start over with a fresh one
+		err = t.CreateIssue(issue)
+		if errors.Is(err, ErrIDExists) && attempt < maxCreateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create issue: %w", err)
+		}
+		break
+	}
+	for _, blocker := range req.BlockedBy {
+		if err := t.AddBlocker(issue.ID, blocker); err != nil {
+			return issue, err
+		}
+	}
+	return issue, nil
+}
+
+// UpdateRequest is a partial update for Update. Fields left empty are not
+// changed.
+type UpdateRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Status      Status    `json:"status"`
+	Priority    *int      `json:"priority"`
+	Type        IssueType `json:"issue_type"`
+	BlockedBy   []string  `json:"blocked_by"` // blockers to add
+	Unblock     []string  `json:"unblock"`    // blockers to remove
+
+	// IfVersion, if set, makes the update fail with ErrConflict unless the
+	// issue is still at this version. Versions start at 1.
+	IfVersion *int64 `json:"if_version"`
+}
+
+// maxUpdateAttempts bounds how often Update starts over after losing a race
+// with another writer.
+const maxUpdateAttempts = 5
+
+// Update applies a partial update to the issue with the given ID the way
+// bl update does, and returns the updated issue. Only the fields set in req
+// are changed: if another writer saves the issue first, Update reapplies
+// them to the new version rather than overwriting its changes, unless
+// req.IfVersion asks for the version the caller saw.
+func Update(t Tracker, id string, req UpdateRequest) (*Issue, error) {
+	vocab, err := t.Vocabulary()
+	if err != nil {
+		return nil, err
+	}
+	if err := (IssueFilter{Status: req.Status, Type: req.Type, Priority: req.Priority}).Validate(vocab); err != nil {
+		return nil, err
+	}
+	// A zero version would make UpdateIssue skip the check altogether
+	if req.IfVersion != nil && *req.IfVersion < 1 {
+		return nil, fmt.Errorf("invalid if_version %d: versions start at 1", *req.IfVersion)
+	}
+
+	for attempt := 1; ; attempt++ {
+		issue, err := t.GetIssue(id)
+		if err != nil {
+			return nil, fmt.Errorf("issue %s: %w", id, err)
+		}
+		if req.IfVersion != nil {
+			issue.Version = *req.IfVersion
+		}
+		req.apply(issue)
+
+		err = t.UpdateIssue(issue)
+		if errors.Is(err, ErrConflict) && req.IfVersion == nil && attempt < maxUpdateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update: %w", err)
+		}
+		break
+	}
+	for _, blocker := range req.BlockedBy {
+		if err := t.AddBlocker(id, blocker); err != nil {
+			return nil, err
+		}
+	}
+	for _, blocker := range req.Unblock {
+		if err := t.RemoveBlocker(id, blocker); err != nil {
+			return nil, err
+		}
+	}
+	return t.GetIssue(id)
+}
+
+// apply sets the fields that req changes on issue.
+func (req *UpdateRequest) apply(issue *Issue) {
+	if req.Title != nil {
+		issue.Title = *req.Title
+	}
+	if req.Description != nil {
+		issue.Description = *req.Description
+	}
+	if req.Status != "" {
+		issue.Status = req.Status
+	}
+	if req.Priority != nil {
+		issue.Priority = *req.Priority
+	}
+	if req.Type != "" {
+		issue.Type = req.Type
+	}
+}
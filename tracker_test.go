@@ -0,0 +1,481 @@
+package beadslite
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// racingTracker saves a change to an issue just before its next
+// UpdateIssue call goes through, as another agent might, and can take the
+// ID of the next issue passed to CreateIssue first.
+type racingTracker struct {
+	Tracker
+	race       func()
+	raceCreate func(issue *Issue)
+}
+
+func (r *racingTracker) CreateIssue(issue *Issue) error {
+	if race := r.raceCreate; race != nil {
+		r.raceCreate = nil
+		race(issue)
+	}
+	return r.Tracker.CreateIssue(issue)
+}
+
+func (r *racingTracker) UpdateIssue(issue *Issue) error {
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+	return r.Tracker.UpdateIssue(issue)
+}
+
+// testTracker checks the behavior every Tracker must share.
+func testTracker(t *testing.T, newTracker func(t *testing.T) Tracker) {
+	intp := func(n int) *int { return &n }
+	strp := func(s string) *string { return &s }
+	versionp := func(v int64) *int64 { return &v }
+	ids := func(issues []*Issue) []string {
+		var ids []string
+		for _, issue := range issues {
+			ids = append(ids, issue.ID)
+		}
+		return ids
+	}
+
+	t.Run("CreateAndGet", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, err := Create(tr, CreateRequest{Title: "Write docs", Description: "All of them", Priority: intp(1), Type: IssueTypeFeature})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if !strings.HasPrefix(issue.ID, "bl-") {
+			t.Errorf("ID = %q, want bl- prefix", issue.ID)
+		}
+
+		got, err := tr.GetIssue(issue.ID)
+		if err != nil {
+			t.Fatalf("GetIssue: %v", err)
+		}
+		if got.Title != "Write docs" || got.Description != "All of them" || got.Priority != 1 ||
+			got.Type != IssueTypeFeature || got.Status != StatusOpen {
+			t.Errorf("GetIssue = %+v", got)
+		}
+
+		if _, err := tr.GetIssue("bl-nope"); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("GetIssue(missing) error = %v, want ErrIssueNotFound", err)
+		}
+		if _, err := Create(tr, CreateRequest{Title: "  "}); err == nil {
+			t.Error("Create with blank title should fail")
+		}
+		if _, err := Create(tr, CreateRequest{Title: "x", Type: "saga"}); err == nil || !strings.Contains(err.Error(), "invalid type") {
+			t.Errorf("Create with bad type error = %v", err)
+		}
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, err := Create(tr, CreateRequest{Title: "Defaults"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		settings, err := tr.Settings()
+		if err != nil {
+			t.Fatalf("Settings: %v", err)
+		}
+		if issue.Priority != settings.DefaultPriority || issue.Type != settings.DefaultType {
+			t.Errorf("issue = P%d %s, want P%d %s", issue.Priority, issue.Type, settings.DefaultPriority, settings.DefaultType)
+		}
+	})
+
+	t.Run("ResolveID", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Resolve me"})
+		hash := strings.TrimPrefix(issue.ID, "bl-")
+		for _, ref := range []string{issue.ID, hash} {
+			if id, err := tr.ResolveID(ref); err != nil || id != issue.ID {
+				t.Errorf("ResolveID(%q) = %q, %v; want %q", ref, id, err, issue.ID)
+			}
+		}
+		if _, err := tr.ResolveID("zzzz"); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("ResolveID(unknown) error = %v, want ErrIssueNotFound", err)
+		}
+		if _, err := tr.ResolveID(""); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("ResolveID(\"\") error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("ChildIssues", func(t *testing.T) {
+		tr := newTracker(t)
+		parent, _ := Create(tr, CreateRequest{Title: "Epic", Type: IssueTypeEpic})
+		first, err := Create(tr, CreateRequest{Title: "Step one", Parent: parent.ID})
+		if err != nil {
+			t.Fatalf("Create child: %v", err)
+		}
+		second, _ := Create(tr, CreateRequest{Title: "Step two", Parent: parent.ID})
+		if first.ID != parent.ID+".1" || second.ID != parent.ID+".2" {
+			t.Errorf("child IDs = %s, %s", first.ID, second.ID)
+		}
+		// A complete parent ID beats its children, which it is a prefix of
+		if id, err := tr.ResolveID(parent.ID); err != nil || id != parent.ID {
+			t.Errorf("ResolveID(parent) = %q, %v", id, err)
+		}
+		if _, err := tr.NewChildIssue("bl-nope", "Orphan"); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("NewChildIssue(missing parent) error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("UpdateFollowsWorkflow", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Old title"})
+		updated, err := Update(tr, issue.ID, UpdateRequest{Title: strp("New title"), Status: StatusInProgress, Priority: intp(0)})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if updated.Title != "New title" || updated.Status != StatusInProgress || updated.Priority != 0 {
+			t.Errorf("Update = %+v", updated)
+		}
+		if !updated.CreatedAt.Equal(issue.CreatedAt) {
+			t.Errorf("CreatedAt changed from %v to %v", issue.CreatedAt, updated.CreatedAt)
+		}
+
+		closed, err := Update(tr, issue.ID, UpdateRequest{Status: StatusClosed})
+		if err != nil {
+			t.Fatalf("Update to closed: %v", err)
+		}
+		if closed.ClosedAt == nil || closed.Resolution != ResolutionDone {
+			t.Errorf("closing via update: closed_at %v, resolution %q", closed.ClosedAt, closed.Resolution)
+		}
+		if _, err := Update(tr, issue.ID, UpdateRequest{Status: StatusOpen}); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("leaving closed error = %v, want ErrInvalidTransition", err)
+		}
+		if _, err := Update(tr, "bl-nope", UpdateRequest{Title: strp("x")}); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("Update(missing) error = %v, want ErrIssueNotFound", err)
+		}
+		if err := tr.UpdateIssue(NewIssue("Never saved")); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("UpdateIssue(missing) error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("CloseAndReopen", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Close me"})
+		if err := tr.ReopenIssue(issue.ID); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("reopening an open issue error = %v, want ErrInvalidTransition", err)
+		}
+		if err := tr.CloseIssue(issue.ID, ResolutionWontfix); err != nil {
+			t.Fatalf("CloseIssue: %v", err)
+		}
+		got, _ := tr.GetIssue(issue.ID)
+		if got.Status != StatusClosed || got.Resolution != ResolutionWontfix || got.ClosedAt == nil {
+			t.Errorf("closed issue = %+v", got)
+		}
+
+		if err := tr.ReopenIssue(issue.ID); err != nil {
+			t.Fatalf("ReopenIssue: %v", err)
+		}
+		got, _ = tr.GetIssue(issue.ID)
+		if got.Status != StatusOpen || got.Resolution != "" || got.ClosedAt != nil {
+			t.Errorf("reopened issue = %+v", got)
+		}
+		if err := tr.CloseIssue("bl-nope", ResolutionDone); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("CloseIssue(missing) error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Claim me"})
+		if err := tr.ClaimIssue(issue.ID); err != nil {
+			t.Fatalf("ClaimIssue: %v", err)
+		}
+		if got, _ := tr.GetIssue(issue.ID); got.Status != StatusInProgress {
+			t.Errorf("status = %s, want in_progress", got.Status)
+		}
+		if err := tr.ClaimIssue(issue.ID); !errors.Is(err, ErrAlreadyClaimed) {
+			t.Errorf("second claim error = %v, want ErrAlreadyClaimed", err)
+		}
+		if err := tr.ClaimIssue("bl-nope"); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("ClaimIssue(missing) error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Versioned"})
+		if issue.Version != 1 {
+			t.Errorf("created version = %d, want 1", issue.Version)
+		}
+
+		mine, _ := tr.GetIssue(issue.ID)
+		theirs, _ := tr.GetIssue(issue.ID)
+		theirs.Priority = 0
+		if err := tr.UpdateIssue(theirs); err != nil {
+			t.Fatalf("UpdateIssue: %v", err)
+		}
+		if theirs.Version != 2 {
+			t.Errorf("version after update = %d, want 2", theirs.Version)
+		}
+		mine.Title = "Stale"
+		if err := tr.UpdateIssue(mine); !errors.Is(err, ErrConflict) {
+			t.Errorf("stale UpdateIssue error = %v, want ErrConflict", err)
+		}
+		if got, _ := tr.GetIssue(issue.ID); got.Title != "Versioned" || got.Priority != 0 {
+			t.Errorf("after conflict = %+v, want their change only", got)
+		}
+
+		// Every kind of write moves the version on
+		tr.ClaimIssue(issue.ID)
+		tr.CloseIssue(issue.ID, ResolutionDone)
+		tr.ReopenIssue(issue.ID)
+		if got, _ := tr.GetIssue(issue.ID); got.Version != 5 {
+			t.Errorf("version after claim, close, reopen = %d, want 5", got.Version)
+		}
+
+		if _, err := Update(tr, issue.ID, UpdateRequest{Title: strp("x"), IfVersion: versionp(4)}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Update(IfVersion: 4) error = %v, want ErrConflict", err)
+		}
+		if _, err := Update(tr, issue.ID, UpdateRequest{Title: strp("x"), IfVersion: versionp(0)}); err == nil || errors.Is(err, ErrConflict) {
+			t.Errorf("Update(IfVersion: 0) error = %v, want a validation error", err)
+		}
+		updated, err := Update(tr, issue.ID, UpdateRequest{Title: strp("Pinned"), IfVersion: versionp(5)})
+		if err != nil || updated.Title != "Pinned" || updated.Version != 6 {
+			t.Errorf("Update(IfVersion: 5) = %+v, %v", updated, err)
+		}
+	})
+
+	t.Run("UpdateKeepsConcurrentChanges", func(t *testing.T) {
+		tr := newTracker(t)
+		issue, _ := Create(tr, CreateRequest{Title: "Shared"})
+
+		// Another agent changes the priority between Update's read and write
+		racing := &racingTracker{Tracker: tr, race: func() {
+			other, _ := tr.GetIssue(issue.ID)
+			other.Priority = 0
+			if err := tr.UpdateIssue(other); err != nil {
+				t.Errorf("concurrent UpdateIssue: %v", err)
+			}
+		}}
+		updated, err := Update(racing, issue.ID, UpdateRequest{Title: strp("Renamed")})
+		if err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if updated.Title != "Renamed" || updated.Priority != 0 {
+			t.Errorf("Update = %+v, want both changes", updated)
+		}
+
+		racing.race = func() {
+			other, _ := tr.GetIssue(issue.ID)
+			other.Priority = 1
+			tr.UpdateIssue(other)
+		}
+		if _, err := Update(racing, issue.ID, UpdateRequest{Title: strp("Pinned"), IfVersion: versionp(updated.Version)}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Update(IfVersion) racing error = %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("CreateRetriesTakenID", func(t *testing.T) {
+		tr := newTracker(t)
+
+		// Another agent saves an issue under the ID Create was handed
+		var taken string
+		racing := &racingTracker{Tracker: tr, raceCreate: func(issue *Issue) {
+			taken = issue.ID
+			other := NewIssue("Other")
+			other.ID = issue.ID
+			if err := tr.CreateIssue(other); err != nil {
+				t.Errorf("concurrent CreateIssue: %v", err)
+			}
+		}}
+		issue, err := Create(racing, CreateRequest{Title: "Mine"})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if issue.ID == taken {
+			t.Errorf("Create reused the taken ID %s", taken)
+		}
+		if other, _ := tr.GetIssue(taken); other == nil || other.Title != "Other" {
+			t.Errorf("issue under the taken ID = %+v, want the other agent's", other)
+		}
+		if err := tr.CreateIssue(issue); !errors.Is(err, ErrIDExists) {
+			t.Errorf("CreateIssue of an existing ID = %v, want ErrIDExists", err)
+		}
+	})
+
+	t.Run("BlockersAndReadyWork", func(t *testing.T) {
+		tr := newTracker(t)
+		blocker, _ := Create(tr, CreateRequest{Title: "Blocker", Priority: intp(0)})
+		blocked, err := Create(tr, CreateRequest{Title: "Blocked", Priority: intp(1), BlockedBy: []string{blocker.ID}})
+		if err != nil {
+			t.Fatalf("Create blocked: %v", err)
+		}
+		free, _ := Create(tr, CreateRequest{Title: "Free", Priority: intp(2)})
+
+		deps, err := tr.GetDependencies(blocked.ID)
+		if err != nil || len(deps) != 1 || deps[0].DependsOnID != blocker.ID || deps[0].Type != DepBlocks {
+			t.Fatalf("GetDependencies = %v, %v", deps, err)
+		}
+		all, _ := tr.GetAllDependencies()
+		if len(all) != 1 || len(all[blocked.ID]) != 1 {
+			t.Errorf("GetAllDependencies = %v", all)
+		}
+		if err := tr.AddBlocker(blocked.ID, blocked.ID); !errors.Is(err, ErrSelfDependency) {
+			t.Errorf("AddBlocker(self) error = %v, want ErrSelfDependency", err)
+		}
+		if err := tr.AddBlocker(blocked.ID, blocker.ID); !errors.Is(err, ErrDependencyExists) {
+			t.Errorf("AddBlocker(again) error = %v, want ErrDependencyExists", err)
+		}
+		if err := tr.AddBlocker(blocked.ID, "bl-nope"); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("AddBlocker(missing) error = %v, want ErrIssueNotFound", err)
+		}
+
+		ready, _ := tr.ReadyIssues(IssueFilter{})
+		if want := []string{blocker.ID, free.ID}; !reflect.DeepEqual(ids(ready), want) {
+			t.Errorf("ready = %v, want %v", ids(ready), want)
+		}
+		ready, _ = tr.ReadyIssues(IssueFilter{Priority: intp(2)})
+		if want := []string{free.ID}; !reflect.DeepEqual(ids(ready), want) {
+			t.Errorf("ready P2 = %v, want %v", ids(ready), want)
+		}
+
+		tr.CloseIssue(blocker.ID, ResolutionDone)
+		ready, _ = tr.ReadyIssues(IssueFilter{})
+		if want := []string{blocked.ID, free.ID}; !reflect.DeepEqual(ids(ready), want) {
+			t.Errorf("ready after closing blocker = %v, want %v", ids(ready), want)
+		}
+
+		// Blockers are removed by partial ID, matched among current blockers
+		hash := strings.TrimPrefix(blocker.ID, "bl-")
+		if _, err := Update(tr, blocked.ID, UpdateRequest{Unblock: []string{hash}}); err != nil {
+			t.Fatalf("Update unblock: %v", err)
+		}
+		if deps, _ := tr.GetDependencies(blocked.ID); len(deps) != 0 {
+			t.Errorf("dependencies after unblock = %v", deps)
+		}
+		if err := tr.RemoveBlocker(blocked.ID, blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("RemoveBlocker(not a blocker) error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("FindIssues", func(t *testing.T) {
+		tr := newTracker(t)
+		bug, _ := Create(tr, CreateRequest{Title: "Crash on start", Type: IssueTypeBug, Priority: intp(0)})
+		task, _ := Create(tr, CreateRequest{Title: "Tidy up", Description: "the startup code", Priority: intp(3)})
+		closed, _ := Create(tr, CreateRequest{Title: "Old bug", Type: IssueTypeBug, Priority: intp(4)})
+		tr.CloseIssue(closed.ID, ResolutionDuplicate)
+
+		tests := []struct {
+			name   string
+			filter IssueFilter
+			want   []string
+		}{
+			{"all", IssueFilter{}, []string{bug.ID, task.ID, closed.ID}},
+			{"type", IssueFilter{Type: IssueTypeBug}, []string{bug.ID, closed.ID}},
+			{"status", IssueFilter{Status: StatusOpen}, []string{bug.ID, task.ID}},
+			{"priority", IssueFilter{Priority: intp(3)}, []string{task.ID}},
+			{"resolution", IssueFilter{Resolution: ResolutionDuplicate}, []string{closed.ID}},
+			{"query", IssueFilter{Query: "START"}, []string{bug.ID, task.ID}},
+			{"limit", IssueFilter{Limit: 2}, []string{bug.ID, task.ID}},
+		}
+		for _, tt := range tests {
+			got, err := tr.FindIssues(tt.filter)
+			if err != nil {
+				t.Fatalf("%s: FindIssues: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("%s: FindIssues = %v, want %v", tt.name, ids(got), tt.want)
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		tr := newTracker(t)
+		blocker, _ := Create(tr, CreateRequest{Title: "Blocker"})
+		blocked, _ := Create(tr, CreateRequest{Title: "Blocked", BlockedBy: []string{blocker.ID}})
+		if err := tr.DeleteIssue(blocker.ID); err != nil {
+			t.Fatalf("DeleteIssue: %v", err)
+		}
+		if _, err := tr.GetIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("deleted issue error = %v, want ErrIssueNotFound", err)
+		}
+		if deps, _ := tr.GetDependencies(blocked.ID); len(deps) != 0 {
+			t.Errorf("dependencies on deleted issue remain: %v", deps)
+		}
+		if err := tr.DeleteIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+			t.Errorf("second delete error = %v, want ErrIssueNotFound", err)
+		}
+	})
+
+	t.Run("Vocabulary", func(t *testing.T) {
+		tr := newTracker(t)
+		vocab, err := tr.Vocabulary()
+		if err != nil {
+			t.Fatalf("Vocabulary: %v", err)
+		}
+		if !vocab.ValidType(IssueTypeBug) || !vocab.ValidStatus(StatusInProgress) {
+			t.Errorf("vocabulary lacks the built-in types and statuses: %+v", vocab)
+		}
+	})
+}
+
+func TestStore_Tracker(t *testing.T) {
+	testTracker(t, func(t *testing.T) Tracker { return newTestStore(t) })
+}
+
+func TestMemoryTracker(t *testing.T) {
+	testTracker(t, func(t *testing.T) Tracker { return NewMemoryTracker() })
+}
+
+func TestMemoryTracker_ReturnsCopies(t *testing.T) {
+	tr := NewMemoryTracker()
+	issue, err := Create(tr, CreateRequest{Title: "Original"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	issue.Title = "Changed by caller"
+
+	got, _ := tr.GetIssue(issue.ID)
+	if got.Title != "Original" {
+		t.Errorf("title = %q after changing the created issue", got.Title)
+	}
+	got.Title = "Changed again"
+	if again, _ := tr.GetIssue(issue.ID); again.Title != "Original" {
+		t.Errorf("title = %q after changing a fetched issue", again.Title)
+	}
+}
+
+func TestMemoryTracker_RejectsRemoteBlockers(t *testing.T) {
+	tr := NewMemoryTracker()
+	issue, _ := Create(tr, CreateRequest{Title: "Local"})
+	if err := tr.AddBlocker(issue.ID, "other:bl-a1b2"); err == nil {
+		t.Error("AddBlocker with a workspace reference should fail")
+	}
+}
+
+func TestIssueFilter_Validate(t *testing.T) {
+	vocab := DefaultVocabulary()
+	five, zero := 5, 0
+	tests := []struct {
+		filter IssueFilter
+		want   string // substring of the error, or "" for none
+	}{
+		{IssueFilter{}, ""},
+		{IssueFilter{Status: StatusClosed, Type: IssueTypeBug, Priority: &zero, Resolution: ResolutionDone}, ""},
+		{IssueFilter{Status: "blocked"}, "invalid status"},
+		{IssueFilter{Type: "saga"}, "invalid type"},
+		{IssueFilter{Priority: &five}, "invalid priority"},
+		{IssueFilter{Resolution: "meh"}, "invalid resolution"},
+		{IssueFilter{Limit: -1}, "invalid limit"},
+	}
+	for _, tt := range tests {
+		err := tt.filter.Validate(vocab)
+		if tt.want == "" && err != nil {
+			t.Errorf("Validate(%+v) = %v", tt.filter, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("Validate(%+v) = %v, want error containing %q", tt.filter, err, tt.want)
+		}
+	}
+}
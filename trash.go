@@ -0,0 +1,101 @@
+package beadslite
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ListTrash returns the issues in the trash, most recently deleted first.
+func (s *Store) ListTrash() ([]*Issue, error) {
+	return s.ListTrashContext(context.Background())
+}
+
+// ListTrashContext is ListTrash with a context.
+func (s *Store) ListTrashContext(ctx context.Context) ([]*Issue, error) {
+	rows, err := s.q.QueryContext(ctx, `
+		SELECT `+issueColumns+`
+		FROM issues WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("list trash: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIssues(rows)
+}
+
+// RestoreIssue takes an issue out of the trash, with the dependencies it had
+// when it was deleted. It returns ErrIssueNotFound if the issue is not in the
+// trash.
+func (s *Store) RestoreIssue(id string) error {
+	return s.RestoreIssueContext(context.Background(), id)
+}
+
+// RestoreIssueContext is RestoreIssue with a context.
+func (s *Store) RestoreIssueContext(ctx context.Context, id string) error {
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := s.exec(ctx, `
+		UPDATE issues SET deleted_at = NULL, updated_at = ?, updated_by = ?, version = version + 1
+		WHERE id = ? AND deleted_at IS NOT NULL`, time.Now(), actor, id)
+	if err != nil {
+		return fmt.Errorf("restore issue: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("%s is not in the trash: %w", id, ErrIssueNotFound)
+	}
+	return nil
+}
+
+// PurgeTrash permanently removes the issues that were moved to the trash
+// before the given time, and their dependencies on either side. A zero time
+// purges the whole trash. It returns the number of issues removed.
+func (s *Store) PurgeTrash(before time.Time) (int, error) {
+	return s.PurgeTrashContext(context.Background(), before)
+}
+
+// PurgeTrashContext is PurgeTrash with a context.
+func (s *Store) PurgeTrashContext(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	err := s.WithTransactionContext(ctx, func(tx *StoreTx) error {
+		trash, err := tx.ListTrashContext(ctx)
+		if err != nil {
+			return err
+		}
+		for _, issue := range trash {
+			if !before.IsZero() && !issue.DeletedAt.Before(before) {
+				continue
+			}
+			if err := tx.purgeIssue(ctx, issue.ID); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// purgeIssue deletes an issue and every dependency that involves it.
+func (s *Store) purgeIssue(ctx context.Context, id string) error {
+	if _, err := s.exec(ctx, `DELETE FROM dependencies WHERE issue_id = ? OR depends_on_id = ?`, id, id); err != nil {
+		return fmt.Errorf("purge %s: %w", id, err)
+	}
+	if _, err := s.exec(ctx, `DELETE FROM external_dependencies WHERE issue_id = ?`, id); err != nil {
+		return fmt.Errorf("purge %s: %w", id, err)
+	}
+	if _, err := s.exec(ctx, `DELETE FROM issues WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("purge %s: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package beadslite
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// trashFixture creates a blocker with a remote dependency and an issue it
+// blocks, then moves the blocker to the trash.
+func trashFixture(t *testing.T, store *Store) (blocker, blocked *Issue) {
+	t.Helper()
+	blocker, blocked = NewIssue("Blocker"), NewIssue("Blocked")
+	for _, issue := range []*Issue{blocker, blocked} {
+		if err := store.CreateIssue(issue); err != nil {
+			t.Fatalf("CreateIssue: %v", err)
+		}
+	}
+	if err := store.AddDependency(blocked.ID, blocker.ID, DepBlocks); err != nil {
+		t.Fatalf("AddDependency: %v", err)
+	}
+	if err := store.AddDependency(blocker.ID, "api:bl-a1b2", DepBlocks); err != nil {
+		t.Fatalf("AddDependency remote: %v", err)
+	}
+	if err := store.DeleteIssue(blocker.ID); err != nil {
+		t.Fatalf("DeleteIssue: %v", err)
+	}
+	return blocker, blocked
+}
+
+func TestStoreDeleteIssue_MovesToTrash(t *testing.T) {
+	store := newTestStore(t)
+	blocker, blocked := trashFixture(t, store)
+
+	if _, err := store.GetIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("GetIssue of trashed issue = %v, want ErrIssueNotFound", err)
+	}
+	if _, err := store.ResolveID(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("ResolveID of trashed issue = %v, want ErrIssueNotFound", err)
+	}
+	if issues, _ := store.ListIssues(); len(issues) != 1 || issues[0].ID != blocked.ID {
+		t.Errorf("ListIssues = %v, want only %s", issues, blocked.ID)
+	}
+	if ready, _ := store.GetReadyWork(); len(ready) != 1 || ready[0].ID != blocked.ID {
+		t.Errorf("GetReadyWork = %v, want %s, no longer blocked", ready, blocked.ID)
+	}
+	if deps, _ := store.GetDependencies(blocked.ID); len(deps) != 0 {
+		t.Errorf("dependencies on a trashed issue should be hidden: %+v", deps)
+	}
+	if all, _ := store.GetAllDependencies(); len(all) != 0 {
+		t.Errorf("GetAllDependencies = %v, want none", all)
+	}
+	if err := store.ClaimIssue(blocker.ID); err == nil {
+		t.Error("expected claiming a trashed issue to fail")
+	}
+	if err := store.DeleteIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("second DeleteIssue = %v, want ErrIssueNotFound", err)
+	}
+	if err := store.AddDependency(blocked.ID, blocker.ID, DepBlocks); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("dependency on a trashed issue = %v, want ErrIssueNotFound", err)
+	}
+	if err := store.AddDependency(blocker.ID, blocked.ID, DepBlocks); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("dependency of a trashed issue = %v, want ErrIssueNotFound", err)
+	}
+
+	trash, err := store.ListTrash()
+	if err != nil || len(trash) != 1 || trash[0].ID != blocker.ID || trash[0].DeletedAt == nil {
+		t.Fatalf("ListTrash = %v, %v", trash, err)
+	}
+}
+
+func TestStoreRestoreIssue(t *testing.T) {
+	store := newTestStore(t)
+	blocker, blocked := trashFixture(t, store)
+
+	if err := store.RestoreIssue(blocker.ID); err != nil {
+		t.Fatalf("RestoreIssue: %v", err)
+	}
+	restored, err := store.GetIssue(blocker.ID)
+	if err != nil || restored.DeletedAt != nil || restored.Version != 3 {
+		t.Errorf("restored issue = %+v, %v", restored, err)
+	}
+	if deps, _ := store.GetDependencies(blocked.ID); len(deps) != 1 || deps[0].DependsOnID != blocker.ID {
+		t.Errorf("restored blocker should block again: %+v", deps)
+	}
+	if deps, _ := store.GetDependencies(blocker.ID); len(deps) != 1 || deps[0].DependsOnID != "api:bl-a1b2" {
+		t.Errorf("restored issue should keep its remote dependency: %+v", deps)
+	}
+	if err := store.RestoreIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("restoring a live issue = %v, want ErrIssueNotFound", err)
+	}
+}
+
+func TestStorePurgeTrash(t *testing.T) {
+	store := newTestStore(t)
+	blocker, blocked := trashFixture(t, store)
+	old := NewIssue("Old")
+	store.CreateIssue(old)
+	store.DeleteIssue(old.ID)
+	if _, err := store.db.Exec(`UPDATE issues SET deleted_at = ? WHERE id = ?`,
+		time.Now().Add(-40*24*time.Hour), old.ID); err != nil {
+		t.Fatalf("age deletion: %v", err)
+	}
+
+	purged, err := store.PurgeTrash(time.Now().Add(-30 * 24 * time.Hour))
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash(30 days ago) = %d, %v; want 1", purged, err)
+	}
+	if trash, _ := store.ListTrash(); len(trash) != 1 || trash[0].ID != blocker.ID {
+		t.Errorf("trash after purge = %v, want only %s", trash, blocker.ID)
+	}
+
+	if purged, err := store.PurgeTrash(time.Time{}); err != nil || purged != 1 {
+		t.Fatalf("PurgeTrash(all) = %d, %v; want 1", purged, err)
+	}
+	if err := store.RestoreIssue(blocker.ID); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("restoring a purged issue = %v, want ErrIssueNotFound", err)
+	}
+	var edges int
+	store.db.QueryRow(`SELECT (SELECT COUNT(*) FROM dependencies) + (SELECT COUNT(*) FROM external_dependencies)`).Scan(&edges)
+	if edges != 0 {
+		t.Errorf("purge left %d dependencies behind", edges)
+	}
+	if problems, _ := store.CheckIntegrity(); len(problems) != 0 {
+		t.Errorf("CheckIntegrity after purge = %v", problemStrings(problems))
+	}
+	if _, err := store.GetIssue(blocked.ID); err != nil {
+		t.Errorf("purge should keep live issues: %v", err)
+	}
+}
+
+func TestExportImport_Trash(t *testing.T) {
+	store := newTestStore(t)
+	blocker, blocked := trashFixture(t, store)
+
+	var buf bytes.Buffer
+	if err := ExportToJSONLWithOptions(store, &buf, ExportOptions{Priority: -1}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if strings.Contains(buf.String(), blocker.ID) {
+		t.Errorf("export should leave out the trash:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := ExportToJSONLWithOptions(store, &buf, ExportOptions{Priority: -1, IncludeTrash: true}); err != nil {
+		t.Fatalf("export with trash: %v", err)
+	}
+	other := newTestStore(t)
+	if _, err := ImportFromJSONL(other, strings.NewReader(buf.String())); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if trash, _ := other.ListTrash(); len(trash) != 1 || trash[0].ID != blocker.ID {
+		t.Errorf("imported trash = %v, want %s", trash, blocker.ID)
+	}
+	if err := other.RestoreIssue(blocker.ID); err != nil {
+		t.Fatalf("RestoreIssue after import: %v", err)
+	}
+	if deps, _ := other.GetDependencies(blocked.ID); len(deps) != 1 || deps[0].DependsOnID != blocker.ID {
+		t.Errorf("imported dependency on the trashed issue = %+v", deps)
+	}
+}
@@ -0,0 +1,192 @@
+package beadslite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Config keys for custom issue types and statuses.
+const (
+	configCustomTypes    = "types.custom"    // e.g. "chore,spike,docs"
+	configCustomStatuses = "statuses.custom" // e.g. "review:in_progress,icebox:open"
+)
+
+// builtinStatuses and builtinTypes are accepted by every workspace.
+var (
+	builtinStatuses = []Status{StatusOpen, StatusInProgress, StatusClosed}
+	builtinTypes    = []IssueType{IssueTypeTask, IssueTypeBug, IssueTypeFeature, IssueTypeEpic}
+)
+
+// Vocabulary is the set of issue types and statuses a workspace accepts: the
+// built-in ones plus any it defines in its config. Every status belongs to a
+// category, one of the built-in statuses, which decides how it behaves:
+// open-like and in-progress-like issues can be ready, and only closed-like
+// blockers stop blocking.
+type Vocabulary struct {
+	Types      []IssueType
+	Statuses   []Status
+	categories map[Status]Status
+}
+
+// DefaultVocabulary returns the vocabulary of a workspace with no custom
+// types or statuses.
+func DefaultVocabulary() *Vocabulary {
+	v := &Vocabulary{
+		Types:      append([]IssueType(nil), builtinTypes...),
+		Statuses:   append([]Status(nil), builtinStatuses...),
+		categories: make(map[Status]Status),
+	}
+	for _, status := range builtinStatuses {
+		v.categories[status] = status
+	}
+	return v
+}
+
+// ValidType reports whether t is a built-in or custom type.
+func (v *Vocabulary) ValidType(t IssueType) bool {
+	for _, known := range v.Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidStatus reports whether s is a built-in or custom status.
+func (v *Vocabulary) ValidStatus(s Status) bool {
+	_, ok := v.categories[s]
+	return ok
+}
+
+// Category returns the built-in status that s behaves like. Unknown statuses
+// are treated as open, so nothing disappears from view.
+func (v *Vocabulary) Category(s Status) Status {
+	if category, ok := v.categories[s]; ok {
+		return category
+	}
+	return StatusOpen
+}
+
+// IsClosed reports whether s is closed-like.
+func (v *Vocabulary) IsClosed(s Status) bool {
+	return v.Category(s) == StatusClosed
+}
+
+// StatusesIn returns every status in the given categories, in vocabulary order.
+func (v *Vocabulary) StatusesIn(categories ...Status) []Status {
+	var statuses []Status
+	for _, status := range v.Statuses {
+		for _, category := range categories {
+			if v.categories[status] == category {
+				statuses = append(statuses, status)
+				break
+			}
+		}
+	}
+	return statuses
+}
+
+// TypeList returns the valid types as a comma-separated list for messages.
+func (v *Vocabulary) TypeList() string {
+	names := make([]string, len(v.Types))
+	for i, t := range v.Types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// StatusList returns the valid statuses as a comma-separated list for messages.
+func (v *Vocabulary) StatusList() string {
+	names := make([]string, len(v.Statuses))
+	for i, s := range v.Statuses {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Custom reports whether the vocabulary has anything beyond the built-ins.
+func (v *Vocabulary) Custom() bool {
+	return len(v.Types) > len(builtinTypes) || len(v.Statuses) > len(builtinStatuses)
+}
+
+// Vocabulary loads the workspace's types and statuses from its config.
+func (s *Store) Vocabulary() (*Vocabulary, error) {
+	return s.VocabularyContext(context.Background())
+}
+
+// VocabularyContext is Vocabulary with a context.
+func (s *Store) VocabularyContext(ctx context.Context) (*Vocabulary, error) {
+	types, err := s.GetConfigContext(ctx, configCustomTypes)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := s.GetConfigContext(ctx, configCustomStatuses)
+	if err != nil {
+		return nil, err
+	}
+	return newVocabulary(types, statuses)
+}
+
+// newVocabulary extends the default vocabulary with custom types and statuses
+// in their config formats.
+func newVocabulary(types, statuses string) (*Vocabulary, error) {
+	v := DefaultVocabulary()
+
+	for _, name := range splitList(types) {
+		if err := validateVocabularyName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", configCustomTypes, err)
+		}
+		if v.ValidType(IssueType(name)) {
+			return nil, fmt.Errorf("%s: type %q is already defined", configCustomTypes, name)
+		}
+		v.Types = append(v.Types, IssueType(name))
+	}
+
+	for _, entry := range splitList(statuses) {
+		name, category, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s: %q needs a category, e.g. %s:in_progress", configCustomStatuses, entry, entry)
+		}
+		if err := validateVocabularyName(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", configCustomStatuses, err)
+		}
+		if v.ValidStatus(Status(name)) {
+			return nil, fmt.Errorf("%s: status %q is already defined", configCustomStatuses, name)
+		}
+		if !Status(category).Valid() {
+			return nil, fmt.Errorf("%s: invalid category %q for %s (valid: open, in_progress, closed)", configCustomStatuses, category, name)
+		}
+		v.Statuses = append(v.Statuses, Status(name))
+		v.categories[Status(name)] = Status(category)
+	}
+	return v, nil
+}
+
+// splitList splits a comma-separated config value, dropping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validateVocabularyName checks that a custom type or status name is a
+// lowercase word that can be typed on the command line.
+func validateVocabularyName(name string) error {
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case (c >= '0' && c <= '9' || c == '_' || c == '-') && i > 0:
+		default:
+			return fmt.Errorf("invalid name %q: use lowercase letters, digits, '_' or '-'", name)
+		}
+	}
+	if name == "" {
+		return fmt.Errorf("invalid name %q: cannot be empty", name)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package beadslite
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDefaultVocabulary(t *testing.T) {
+	v := DefaultVocabulary()
+
+	for _, status := range []Status{StatusOpen, StatusInProgress, StatusClosed} {
+		if !v.ValidStatus(status) || v.Category(status) != status {
+			t.Errorf("built-in status %s should be valid and its own category", status)
+		}
+	}
+	if v.ValidStatus("review") || v.ValidType("chore") {
+		t.Error("custom values should not be valid by default")
+	}
+	if v.Custom() {
+		t.Error("default vocabulary should not report custom entries")
+	}
+}
+
+func TestNewVocabulary(t *testing.T) {
+	v, err := newVocabulary("chore, spike,docs", "review:in_progress,icebox:open,cancelled:closed")
+	if err != nil {
+		t.Fatalf("newVocabulary: %v", err)
+	}
+
+	if !v.ValidType("chore") || !v.ValidType("docs") || !v.ValidType(IssueTypeTask) {
+		t.Errorf("expected built-in and custom types, got %v", v.Types)
+	}
+	if got := v.TypeList(); got != "task, bug, feature, epic, chore, spike, docs" {
+		t.Errorf("TypeList() = %q", got)
+	}
+
+	categories := map[Status]Status{
+		"review":    StatusInProgress,
+		"icebox":    StatusOpen,
+		"cancelled": StatusClosed,
+		StatusOpen:  StatusOpen,
+	}
+	for status, want := range categories {
+		if got := v.Category(status); got != want {
+			t.Errorf("Category(%s) = %s, want %s", status, got, want)
+		}
+	}
+	if !v.IsClosed("cancelled") || v.IsClosed("review") {
+		t.Error("IsClosed should follow the category")
+	}
+
+	active := v.StatusesIn(StatusOpen, StatusInProgress)
+	want := []Status{StatusOpen, StatusInProgress, "review", "icebox"}
+	if !reflect.DeepEqual(active, want) {
+		t.Errorf("StatusesIn(open, in_progress) = %v, want %v", active, want)
+	}
+}
+
+func TestNewVocabulary_Invalid(t *testing.T) {
+	tests := []struct {
+		types, statuses, wantErr string
+	}{
+		{"Chore", "", "invalid name"},
+		{"task", "", "already defined"},
+		{"chore,chore", "", "already defined"},
+		{"", "review", "needs a category"},
+		{"", "review:done", "invalid category"},
+		{"", "closed:open", "already defined"},
+		{"", "in review:in_progress", "invalid name"},
+	}
+	for _, tt := range tests {
+		_, err := newVocabulary(tt.types, tt.statuses)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("newVocabulary(%q, %q) error = %v, want %q", tt.types, tt.statuses, err, tt.wantErr)
+		}
+	}
+}
+
+func TestStoreCustomVocabulary(t *testing.T) {
+	store := newTestStore(t)
+
+	chore := NewIssue("Chore")
+	chore.Type = "chore"
+	if err := store.CreateIssue(chore); err == nil {
+		t.Fatal("custom type should be rejected before it is configured")
+	}
+
+	if err := store.SetSetting(configCustomTypes, "chore"); err != nil {
+		t.Fatalf("SetSetting(types): %v", err)
+	}
+	if err := store.SetSetting(configCustomStatuses, "review:in_progress,icebox:open,cancelled:closed"); err != nil {
+		t.Fatalf("SetSetting(statuses): %v", err)
+	}
+	if err := store.CreateIssue(chore); err != nil {
+		t.Fatalf("CreateIssue(chore): %v", err)
+	}
+	if err := store.SetSetting(configDefaultType, "chore"); err != nil {
+		t.Errorf("custom type should be accepted as the default: %v", err)
+	}
+
+	reviewed := NewIssue("In review")
+	reviewed.Status = "review"
+	store.CreateIssue(reviewed)
+
+	cancelledBlocker := NewIssue("Cancelled blocker")
+	cancelledBlocker.Status = "cancelled"
+	store.CreateIssue(cancelledBlocker)
+	iceboxBlocker := NewIssue("Icebox blocker")
+	iceboxBlocker.Status = "icebox"
+	store.CreateIssue(iceboxBlocker)
+
+	unblocked := NewIssue("Blocked by cancelled")
+	store.CreateIssue(unblocked)
+	store.AddDependency(unblocked.ID, cancelledBlocker.ID, DepBlocks)
+	blocked := NewIssue("Blocked by icebox")
+	store.CreateIssue(blocked)
+	store.AddDependency(blocked.ID, iceboxBlocker.ID, DepBlocks)
+
+	ready, err := store.GetReadyWork()
+	if err != nil {
+		t.Fatalf("GetReadyWork: %v", err)
+	}
+	got := map[string]bool{}
+	for _, issue := range ready {
+		got[issue.Title] = true
+	}
+	for _, title := range []string{"Chore", "In review", "Icebox blocker", "Blocked by cancelled"} {
+		if !got[title] {
+			t.Errorf("%q should be ready", title)
+		}
+	}
+	for _, title := range []string{"Cancelled blocker", "Blocked by icebox"} {
+		if got[title] {
+			t.Errorf("%q should not be ready", title)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package beadslite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// EventType says what kind of change an Event reports.
+type EventType string
+
+const (
+	EventCreated           EventType = "created"
+	EventUpdated           EventType = "updated"
+	EventClosed            EventType = "closed"
+	EventReopened          EventType = "reopened"
+	EventTrashed           EventType = "trashed"  // moved to the trash
+	EventRestored          EventType = "restored" // taken out of the trash
+	EventDeleted           EventType = "deleted"  // removed for good, such as by purging the trash
+	EventDependencyAdded   EventType = "dependency_added"
+	EventDependencyRemoved EventType = "dependency_removed"
+)
+
+// Event is one change to a workspace's issues, as seen by a Watcher.
+type Event struct {
+	Type      EventType `json:"type"`
+	IssueID   string    `json:"issue_id"`
+	DependsOn string    `json:"depends_on,omitempty"` // dependency events only
+	Time      time.Time `json:"time"`                 // when the change was noticed
+
+	// Issue is the issue's state after the change, with deleted_at set for
+	// issues in the trash. It is nil for deleted issues and for dependency
+	// events on issues deleted since.
+	Issue *IssueExport `json:"issue,omitempty"`
+}
+
+// watchInterval is how often Watch checks the database for changes.
+const watchInterval = 250 * time.Millisecond
+
+// Watcher reports changes to a workspace's issues, including those made by
+// other processes using the same database file. It polls SQLite's
+// data_version, which changes whenever another connection commits, and
+// diffs the issues and dependencies against what it saw last time. It
+// needs a file database: every connection to ":memory:" is its own database.
+type Watcher struct {
+	store   *Store
+	conn    *sql.Conn // dedicated, so that data_version counts every other writer
+	version int64
+	issues  map[string]*Issue          // including those in the trash
+	deps    map[string]map[string]bool // issue ID -> blocker IDs, outside the trash
+}
+
+// NewWatcher starts watching store from its current state. Close releases
+// its connection.
+func (s *Store) NewWatcher(ctx context.Context) (*Watcher, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+	w := &Watcher{store: s, conn: conn}
+	if w.version, err = w.dataVersion(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if w.issues, w.deps, err = w.snapshot(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Close stops the watcher.
+func (w *Watcher) Close() error {
+	return w.conn.Close()
+}
+
+func (w *Watcher) dataVersion(ctx context.Context) (int64, error) {
+	var version int64
+	if err := w.conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("watch: read data_version: %w", err)
+	}
+	return version, nil
+}
+
+// snapshot reads every issue, in the trash or not, and every dependency
+// between issues outside the trash.
+func (w *Watcher) snapshot(ctx context.Context) (map[string]*Issue, map[string]map[string]bool, error) {
+	list, err := w.store.listIssues(ctx, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("watch: list issues: %w", err)
+	}
+	allDeps, err := w.store.GetAllDependenciesContext(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("watch: list dependencies: %w", err)
+	}
+
+	issues := make(map[string]*Issue, len(list))
+	for _, issue := range list {
+		issues[issue.ID] = issue
+	}
+	deps := make(map[string]map[string]bool, len(allDeps))
+	for id, list := range allDeps {
+		deps[id] = make(map[string]bool, len(list))
+		for _, dep := range list {
+			deps[id][dep.DependsOnID] = true
+		}
+	}
+	return issues, deps, nil
+}
+
+// Poll returns the changes since the last call, or since the watcher was
+// created, in issue ID order. It returns no events without reading the
+// issues if nothing has been committed since.
+func (w *Watcher) Poll(ctx context.Context) ([]Event, error) {
+	version, err := w.dataVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == w.version {
+		return nil, nil
+	}
+	w.version = version
+
+	issues, deps, err := w.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vocab, err := w.store.VocabularyContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	events := diffSnapshots(w.issues, w.deps, issues, deps, vocab, time.Now())
+	w.issues, w.deps = issues, deps
+	return events, nil
+}
+
+// diffSnapshots lists the events that turn the old state into the new one.
+func diffSnapshots(oldIssues map[string]*Issue, oldDeps map[string]map[string]bool,
+	newIssues map[string]*Issue, newDeps map[string]map[string]bool, vocab *Vocabulary, now time.Time) []Event {
+	ids := map[string]bool{}
+	for id := range oldIssues {
+		ids[id] = true
+	}
+	for id := range newIssues {
+		ids[id] = true
+	}
+	for id := range oldDeps {
+		ids[id] = true
+	}
+	for id := range newDeps {
+		ids[id] = true
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	// Moving an issue in or out of the trash hides or shows its dependencies
+	// on both sides, which is part of the trashed or restored event
+	moved := map[string]bool{}
+	for _, id := range sorted {
+		before, after := oldIssues[id], newIssues[id]
+		if before != nil && after != nil && (before.DeletedAt == nil) != (after.DeletedAt == nil) {
+			moved[id] = true
+		}
+	}
+
+	var events []Event
+	for _, id := range sorted {
+		before, after := oldIssues[id], newIssues[id]
+		var current *IssueExport
+		if after != nil {
+			var blockers []*Dependency
+			for blocker := range newDeps[id] {
+				blockers = append(blockers, &Dependency{IssueID: id, DependsOnID: blocker, Type: DepBlocks})
+			}
+			sort.Slice(blockers, func(i, j int) bool { return blockers[i].DependsOnID < blockers[j].DependsOnID })
+			export := toIssueExport(after, blockers)
+			current = &export
+		}
+		event := func(t EventType, dependsOn string) Event {
+			return Event{Type: t, IssueID: id, DependsOn: dependsOn, Time: now, Issue: current}
+		}
+
+		switch {
+		case before == nil && after != nil && after.DeletedAt != nil:
+			events = append(events, event(EventTrashed, ""))
+		case before == nil && after != nil:
+			events = append(events, event(EventCreated, ""))
+		case before != nil && after == nil:
+			events = append(events, event(EventDeleted, ""))
+		case moved[id] && after.DeletedAt != nil:
+			events = append(events, event(EventTrashed, ""))
+		case moved[id]:
+			events = append(events, event(EventRestored, ""))
+		case before != nil && after != nil && !before.UpdatedAt.Equal(after.UpdatedAt):
+			wasClosed, isClosed := vocab.IsClosed(before.Status), vocab.IsClosed(after.Status)
+			switch {
+			case !wasClosed && isClosed:
+				events = append(events, event(EventClosed, ""))
+			case wasClosed && !isClosed:
+				events = append(events, event(EventReopened, ""))
+			default:
+				events = append(events, event(EventUpdated, ""))
+			}
+		}
+
+		// Dependencies of a created, deleted, trashed or restored issue are
+		// part of that event
+		if before == nil || after == nil || moved[id] {
+			continue
+		}
+		for _, blocker := range sortedKeys(newDeps[id]) {
+			if !oldDeps[id][blocker] && !moved[blocker] {
+				events = append(events, event(EventDependencyAdded, blocker))
+			}
+		}
+		for _, blocker := range sortedKeys(oldDeps[id]) {
+			if !newDeps[id][blocker] && !moved[blocker] {
+				events = append(events, event(EventDependencyRemoved, blocker))
+			}
+		}
+	}
+	return events
+}
+
+// sortedKeys returns the keys of a set in order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Watch calls emit for every change to the store until ctx is done or emit
+// returns an error. ready, if not nil, is called once the starting state
+// has been read, so that later changes are sure to be reported.
+func (s *Store) Watch(ctx context.Context, ready func(), emit func(Event) error) error {
+	w, err := s.NewWatcher(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if ready != nil {
+		ready()
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		events, err := w.Poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, event := range events {
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+	}
+}
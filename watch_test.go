@@ -0,0 +1,172 @@
+package beadslite
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// pollTypes polls w and returns the events as "type issue_id" strings.
+func pollTypes(t *testing.T, w *Watcher) []string {
+	t.Helper()
+	events, err := w.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+	var got []string
+	for _, event := range events {
+		s := string(event.Type) + " " + event.IssueID
+		if event.DependsOn != "" {
+			s += " " + event.DependsOn
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func expectEvents(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestWatcher_ChangesFromAnotherProcess(t *testing.T) {
+	store, dbPath := newFileStore(t)
+	existing := NewIssue("Existing")
+	store.CreateIssue(existing)
+
+	watcher, err := store.NewWatcher(context.Background())
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Close()
+	expectEvents(t, pollTypes(t, watcher))
+
+	// Another bl process has its own connection to the same file
+	other, err := NewStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	defer other.Close()
+
+	issue := NewIssue("From elsewhere")
+	other.CreateIssue(issue)
+	expectEvents(t, pollTypes(t, watcher), "created "+issue.ID)
+
+	got, _ := other.GetIssue(issue.ID)
+	got.Title = "Renamed"
+	other.UpdateIssue(got)
+	events, _ := watcher.Poll(context.Background())
+	if len(events) != 1 || events[0].Type != EventUpdated || events[0].Issue == nil || events[0].Issue.Title != "Renamed" {
+		t.Fatalf("expected an update event with the new state, got %+v", events)
+	}
+
+	other.AddDependency(issue.ID, existing.ID, DepBlocks)
+	expectEvents(t, pollTypes(t, watcher), "dependency_added "+issue.ID+" "+existing.ID)
+
+	other.RemoveDependency(issue.ID, existing.ID, DepBlocks)
+	expectEvents(t, pollTypes(t, watcher), "dependency_removed "+issue.ID+" "+existing.ID)
+
+	other.CloseIssue(issue.ID, ResolutionDone)
+	expectEvents(t, pollTypes(t, watcher), "closed "+issue.ID)
+
+	other.ReopenIssue(issue.ID)
+	expectEvents(t, pollTypes(t, watcher), "reopened "+issue.ID)
+
+	// Trashing and restoring an issue hides and shows its dependencies as
+	// part of the same event
+	other.AddDependency(issue.ID, existing.ID, DepBlocks)
+	expectEvents(t, pollTypes(t, watcher), "dependency_added "+issue.ID+" "+existing.ID)
+	other.DeleteIssue(existing.ID)
+	events, _ = watcher.Poll(context.Background())
+	if len(events) != 1 || events[0].Type != EventTrashed || events[0].IssueID != existing.ID ||
+		events[0].Issue == nil || events[0].Issue.DeletedAt == nil {
+		t.Fatalf("expected a trashed event with deleted_at, got %+v", events)
+	}
+	other.RestoreIssue(existing.ID)
+	events, _ = watcher.Poll(context.Background())
+	if len(events) != 1 || events[0].Type != EventRestored || events[0].Issue == nil || events[0].Issue.DeletedAt != nil {
+		t.Fatalf("expected a restored event, got %+v", events)
+	}
+
+	other.DeleteIssue(existing.ID)
+	expectEvents(t, pollTypes(t, watcher), "trashed "+existing.ID)
+	other.PurgeTrash(time.Time{})
+	events, _ = watcher.Poll(context.Background())
+	if len(events) != 1 || events[0].Type != EventDeleted || events[0].IssueID != existing.ID || events[0].Issue != nil {
+		t.Fatalf("expected a delete event without state, got %+v", events)
+	}
+
+	expectEvents(t, pollTypes(t, watcher))
+}
+
+func TestWatcher_ChangesFromSameStore(t *testing.T) {
+	store, _ := newFileStore(t)
+	watcher, err := store.NewWatcher(context.Background())
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer watcher.Close()
+
+	a := NewIssue("A")
+	b := NewIssue("B")
+	store.CreateIssue(a)
+	store.CreateIssue(b)
+	store.SetSetting(configCustomStatuses, "cancelled:closed")
+
+	// Events for several changes come in issue ID order
+	want := []string{"created " + a.ID, "created " + b.ID}
+	if b.ID < a.ID {
+		want = []string{"created " + b.ID, "created " + a.ID}
+	}
+	expectEvents(t, pollTypes(t, watcher), want...)
+
+	// A custom closed-like status counts as closing
+	got, _ := store.GetIssue(a.ID)
+	got.Status = "cancelled"
+	store.UpdateIssue(got)
+	expectEvents(t, pollTypes(t, watcher), "closed "+a.ID)
+
+	// Config changes commit too, but do not change any issue
+	store.SetSetting(configDefaultPriority, "1")
+	expectEvents(t, pollTypes(t, watcher))
+}
+
+func TestStoreWatch(t *testing.T) {
+	store, _ := newFileStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ready := make(chan struct{})
+	events := make(chan Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- store.Watch(ctx, func() { close(ready) }, func(e Event) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	<-ready
+	issue := NewIssue("Watched")
+	store.CreateIssue(issue)
+
+	select {
+	case event := <-events:
+		if event.Type != EventCreated || event.IssueID != issue.ID {
+			t.Errorf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event within 5s")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch should return nil when cancelled, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package beadslite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config key for the optional close rule.
+const configRequireClosedBlockers = "workflow.require_closed_blockers"
+
+// ErrInvalidTransition is returned when an update moves an issue between
+// statuses that the workflow does not connect directly.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// ErrOpenBlockers is returned when closing an issue that still has open
+// blockers while workflow.require_closed_blockers is on.
+var ErrOpenBlockers = errors.New("issue has open blockers")
+
+// allowedTransitions lists, by status category, the categories UpdateIssue
+// may move an issue to. Moving within a category (e.g. open to a custom
+// open-like status) is always allowed. Closed issues can only be reopened
+// with ReopenIssue, which also clears closed_at and resolution.
+var allowedTransitions = map[Status][]Status{
+	StatusOpen:       {StatusOpen, StatusInProgress, StatusClosed},
+	StatusInProgress: {StatusOpen, StatusInProgress, StatusClosed},
+	StatusClosed:     {StatusClosed},
+}
+
+// checkTransition reports whether an update may move an issue from one
+// status to another.
+func checkTransition(v *Vocabulary, from, to Status) error {
+	fromCategory, toCategory := v.Category(from), v.Category(to)
+	for _, allowed := range allowedTransitions[fromCategory] {
+		if allowed == toCategory {
+			return nil
+		}
+	}
+	if fromCategory == StatusClosed {
+		return fmt.Errorf("%w: %s -> %s (use reopen)", ErrInvalidTransition, from, to)
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// normalizeClosedFields keeps closed_at and resolution consistent with the
+// status: closed-like issues have a closed_at, and other issues have
+// neither a closed_at nor a resolution.
+func normalizeClosedFields(issue *Issue, v *Vocabulary, now time.Time) {
+	if v.IsClosed(issue.Status) {
+		if issue.ClosedAt == nil {
+			issue.ClosedAt = &now
+		}
+		return
+	}
+	issue.ClosedAt = nil
+	issue.Resolution = ""
+}
+
+// ReopenIssue moves a closed issue back to open and clears its closed_at and
+// resolution. It is the only way out of a closed-like status.
+func (s *Store) ReopenIssue(id string) error {
+	return s.ReopenIssueContext(context.Background(), id)
+}
+
+// ReopenIssueContext is ReopenIssue with a context.
+func (s *Store) ReopenIssueContext(ctx context.Context, id string) error {
+	return s.reopenIssue(ctx, id, 0)
+}
+
+// reopenIssue reopens an issue provided it is still at version, or whatever
+// its version if version is 0. It fails with ErrConflict otherwise.
+func (s *Store) reopenIssue(ctx context.Context, id string, version int64) error {
+	issue, err := s.GetIssueContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !vocab.IsClosed(issue.Status) {
+		return fmt.Errorf("%w: %s is %s, not closed", ErrInvalidTransition, id, issue.Status)
+	}
+
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return err
+	}
+	result, err := s.exec(ctx, `
+		UPDATE issues SET status = ?, updated_at = ?, closed_at = NULL, resolution = NULL,
+		updated_by = ?, version = version + 1
+		WHERE id = ? AND ? IN (0, version)`, StatusOpen, time.Now(), actor, id, version)
+	if err != nil {
+		return fmt.Errorf("reopen issue: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("reopen issue: %w", err)
+	} else if n == 0 {
+		return s.versionConflict(ctx, &Issue{ID: id, Version: version})
+	}
+	return nil
+}
+
+// ErrAlreadyClaimed is returned by ClaimIssue when the issue is no longer in
+// an open-like status, usually because another agent started on it first.
+var ErrAlreadyClaimed = errors.New("issue already claimed")
+
+// ClaimIssue starts work on an issue by moving it from an open-like status
+// to in_progress. The check and the update are one statement, so when two
+// agents claim the same issue exactly one of them succeeds.
+func (s *Store) ClaimIssue(id string) error {
+	return s.ClaimIssueContext(context.Background(), id)
+}
+
+// ClaimIssueContext is ClaimIssue with a context.
+func (s *Store) ClaimIssueContext(ctx context.Context, id string) error {
+	vocab, err := s.VocabularyContext(ctx)
+	if err != nil {
+		return err
+	}
+	claimable := vocab.StatusesIn(StatusOpen)
+	actor, err := s.actor(ctx)
+	if err != nil {
+		return err
+	}
+
+	args := []any{StatusInProgress, time.Now(), actor, id}
+	for _, status := range claimable {
+		args = append(args, status)
+	}
+	result, err := s.exec(ctx, `
+		UPDATE issues SET status = ?, updated_at = ?, updated_by = ?, version = version + 1
+		WHERE id = ? AND deleted_at IS NULL AND status IN (`+placeholders(len(claimable))+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("claim issue: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("claim issue: %w", err)
+	} else if n > 0 {
+		return nil
+	}
+
+	issue, err := s.GetIssueContext(ctx, id)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s is %s", ErrAlreadyClaimed, id, issue.Status)
+}
+
+// checkCanClose applies the configurable close rules to an issue about to
+// be closed.
+func (s *Store) checkCanClose(ctx context.Context, id string, vocab *Vocabulary) error {
+	settings, err := s.SettingsContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !settings.RequireClosedBlockers {
+		return nil
+	}
+
+	open, err := s.openBlockers(ctx, id, vocab)
+	if err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("%w: %s", ErrOpenBlockers, strings.Join(open, ", "))
+	}
+	return nil
+}
+
+// openBlockers returns the IDs of the issue's blockers that are not closed,
+// including blockers in other workspaces, which count as open if they
+// cannot be read.
+func (s *Store) openBlockers(ctx context.Context, id string, vocab *Vocabulary) ([]string, error) {
+	deps, err := s.GetDependenciesContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	remotes := newRemoteStores(s)
+	defer remotes.Close()
+
+	var open []string
+	for _, dep := range deps {
+		if dep.Type != DepBlocks {
+			continue
+		}
+		if workspace, _ := ParseIssueRef(dep.DependsOnID); workspace != "" {
+			if !remotes.closed(ctx, dep.DependsOnID) {
+				open = append(open, dep.DependsOnID)
+			}
+			continue
+		}
+		blocker, err := s.GetIssueContext(ctx, dep.DependsOnID)
+		if err != nil {
+			return nil, err
+		}
+		if !vocab.IsClosed(blocker.Status) {
+			open = append(open, blocker.ID)
+		}
+	}
+	return open, nil
+}
@@ -0,0 +1,223 @@
+package beadslite
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	v, err := newVocabulary("", "review:in_progress,icebox:open,cancelled:closed")
+	if err != nil {
+		t.Fatalf("newVocabulary: %v", err)
+	}
+
+	tests := []struct {
+		from, to Status
+		ok       bool
+	}{
+		{StatusOpen, StatusInProgress, true},
+		{StatusOpen, StatusClosed, true},
+		{StatusInProgress, StatusOpen, true},
+		{StatusInProgress, "review", true},
+		{"review", StatusClosed, true},
+		{"icebox", StatusOpen, true},
+		{StatusClosed, "cancelled", true},
+		{StatusClosed, StatusOpen, false},
+		{StatusClosed, StatusInProgress, false},
+		{"cancelled", "icebox", false},
+	}
+	for _, tt := range tests {
+		err := checkTransition(v, tt.from, tt.to)
+		if tt.ok && err != nil {
+			t.Errorf("%s -> %s: unexpected error %v", tt.from, tt.to, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("%s -> %s: expected ErrInvalidTransition, got %v", tt.from, tt.to, err)
+		}
+	}
+}
+
+func TestStoreUpdateIssue_ClosingSetsClosedFields(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Close via update")
+	store.CreateIssue(issue)
+
+	issue.Status = StatusClosed
+	if err := store.UpdateIssue(issue); err != nil {
+		t.Fatalf("UpdateIssue: %v", err)
+	}
+
+	got, _ := store.GetIssue(issue.ID)
+	if got.ClosedAt == nil {
+		t.Error("closing through UpdateIssue should set closed_at")
+	}
+	if got.Resolution != ResolutionDone {
+		t.Errorf("expected default resolution done, got %q", got.Resolution)
+	}
+}
+
+func TestStoreUpdateIssue_CannotLeaveClosed(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Closed")
+	store.CreateIssue(issue)
+	store.CloseIssue(issue.ID, ResolutionWontfix)
+
+	got, _ := store.GetIssue(issue.ID)
+	got.Status = StatusOpen
+	if err := store.UpdateIssue(got); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	// Other edits to a closed issue are fine and keep it closed
+	got, _ = store.GetIssue(issue.ID)
+	closedAt := *got.ClosedAt
+	got.Title = "Closed, renamed"
+	if err := store.UpdateIssue(got); err != nil {
+		t.Fatalf("UpdateIssue(title): %v", err)
+	}
+	got, _ = store.GetIssue(issue.ID)
+	if got.ClosedAt == nil || !got.ClosedAt.Equal(closedAt) || got.Resolution != ResolutionWontfix {
+		t.Errorf("closed fields should be unchanged: closed_at=%v resolution=%q", got.ClosedAt, got.Resolution)
+	}
+}
+
+func TestStoreReopenIssue(t *testing.T) {
+	store := newTestStore(t)
+	issue := NewIssue("Reopen me")
+	store.CreateIssue(issue)
+
+	if err := store.ReopenIssue(issue.ID); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("reopening an open issue should fail, got %v", err)
+	}
+
+	store.CloseIssue(issue.ID, ResolutionDuplicate)
+	if err := store.ReopenIssue(issue.ID); err != nil {
+		t.Fatalf("ReopenIssue: %v", err)
+	}
+
+	got, _ := store.GetIssue(issue.ID)
+	if got.Status != StatusOpen || got.ClosedAt != nil || got.Resolution != "" {
+		t.Errorf("reopen should clear closed fields: status=%s closed_at=%v resolution=%q", got.Status, got.ClosedAt, got.Resolution)
+	}
+
+	if err := store.ReopenIssue("bl-none"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("expected ErrIssueNotFound, got %v", err)
+	}
+}
+
+func TestStoreCreateIssue_NormalizesClosedFields(t *testing.T) {
+	store := newTestStore(t)
+
+	closed := NewIssue("Created closed")
+	closed.Status = StatusClosed
+	store.CreateIssue(closed)
+	got, _ := store.GetIssue(closed.ID)
+	if got.ClosedAt == nil {
+		t.Error("an issue created closed should get a closed_at")
+	}
+
+	open := NewIssue("Created open")
+	open.ClosedAt = &open.CreatedAt
+	open.Resolution = ResolutionDone
+	store.CreateIssue(open)
+	got, _ = store.GetIssue(open.ID)
+	if got.ClosedAt != nil || got.Resolution != "" {
+		t.Errorf("an open issue should have no closed fields: closed_at=%v resolution=%q", got.ClosedAt, got.Resolution)
+	}
+}
+
+func TestStoreCloseIssue_RequireClosedBlockers(t *testing.T) {
+	store := newTestStore(t)
+
+	blocker := NewIssue("Blocker")
+	blocked := NewIssue("Blocked")
+	store.CreateIssue(blocker)
+	store.CreateIssue(blocked)
+	store.AddDependency(blocked.ID, blocker.ID, DepBlocks)
+
+	// Off by default
+	if err := store.CloseIssue(blocked.ID, ResolutionDone); err != nil {
+		t.Fatalf("closing with open blockers should be allowed by default: %v", err)
+	}
+	store.ReopenIssue(blocked.ID)
+
+	store.SetSetting(configRequireClosedBlockers, "true")
+	if err := store.CloseIssue(blocked.ID, ResolutionDone); !errors.Is(err, ErrOpenBlockers) {
+		t.Fatalf("expected ErrOpenBlockers from CloseIssue, got %v", err)
+	}
+	got, _ := store.GetIssue(blocked.ID)
+	got.Status = StatusClosed
+	if err := store.UpdateIssue(got); !errors.Is(err, ErrOpenBlockers) {
+		t.Fatalf("expected ErrOpenBlockers from UpdateIssue, got %v", err)
+	}
+
+	store.CloseIssue(blocker.ID, ResolutionDone)
+	if err := store.CloseIssue(blocked.ID, ResolutionDone); err != nil {
+		t.Errorf("closing after blockers close should succeed: %v", err)
+	}
+}
+
+func TestImportFromJSONL_BypassesWorkflow(t *testing.T) {
+	store, cleanup := setupTestStore(t)
+	defer cleanup()
+
+	issue := NewIssue("Synced")
+	store.CreateIssue(issue)
+	store.CloseIssue(issue.ID, ResolutionDone)
+
+	// Another clone reopened the issue; importing its state is not a
+	// transition made here and must not be refused.
+	got, _ := store.GetIssue(issue.ID)
+	got.Status = StatusOpen
+	got.ClosedAt = nil
+	got.Resolution = ""
+	var buf bytes.Buffer
+	if err := WriteIssuesAsJSONL([]*Issue{got}, nil, &buf); err != nil {
+		t.Fatalf("WriteIssuesAsJSONL: %v", err)
+	}
+	if _, err := ImportFromJSONL(store, &buf); err != nil {
+		t.Fatalf("ImportFromJSONL: %v", err)
+	}
+
+	got, _ = store.GetIssue(issue.ID)
+	if got.Status != StatusOpen || got.ClosedAt != nil {
+		t.Errorf("import should apply the incoming state: status=%s closed_at=%v", got.Status, got.ClosedAt)
+	}
+}
+
+func TestStoreClaimIssue(t *testing.T) {
+	store := newTestStore(t)
+	store.SetSetting(configCustomStatuses, "icebox:open,review:in_progress")
+
+	issue := NewIssue("Claim me")
+	issue.Status = "icebox"
+	store.CreateIssue(issue)
+
+	if err := store.ClaimIssue(issue.ID); err != nil {
+		t.Fatalf("ClaimIssue from an open-like status: %v", err)
+	}
+	got, _ := store.GetIssue(issue.ID)
+	if got.Status != StatusInProgress {
+		t.Errorf("expected in_progress, got %s", got.Status)
+	}
+
+	if err := store.ClaimIssue(issue.ID); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("claiming twice should fail with ErrAlreadyClaimed, got %v", err)
+	}
+
+	got.Status = "review"
+	store.UpdateIssue(got)
+	if err := store.ClaimIssue(issue.ID); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("claiming an in_progress-like issue should fail, got %v", err)
+	}
+
+	store.CloseIssue(issue.ID, ResolutionDone)
+	if err := store.ClaimIssue(issue.ID); !errors.Is(err, ErrAlreadyClaimed) {
+		t.Errorf("claiming a closed issue should fail, got %v", err)
+	}
+
+	if err := store.ClaimIssue("bl-none"); !errors.Is(err, ErrIssueNotFound) {
+		t.Errorf("expected ErrIssueNotFound, got %v", err)
+	}
+}
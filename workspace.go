@@ -0,0 +1,308 @@
+package beadslite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// envDir names the environment variable that points bl at a specific
+// .beads-lite directory, bypassing discovery.
+const envDir = "BL_DIR"
+
+// ErrNotInitialized is returned when no workspace can be found.
+var ErrNotInitialized = errors.New("not initialized: run 'bl init' first")
+
+// Workspace identifies the .beads-lite directory and database a command uses.
+type Workspace struct {
+	Dir    string // the .beads-lite directory
+	DBPath string // the SQLite database inside (or overriding) Dir
+	Source string // how it was chosen: "--db", BL_DIR, or "discovered"
+}
+
+// ResolveWorkspace picks the workspace for a command. An explicit database
+// path (from --db) wins, then the BL_DIR environment variable, and finally the
+// nearest .beads-lite found by walking up from start. Overrides are returned
+// even if the database does not exist yet, so that init can create it.
+func ResolveWorkspace(start, dbPath string) (*Workspace, error) {
+	if dbPath != "" {
+		abs, err := filepath.Abs(dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("resolve --db: %w", err)
+		}
+		return &Workspace{Dir: filepath.Dir(abs), DBPath: abs, Source: "--db"}, nil
+	}
+
+	if dir := os.Getenv(envDir); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", envDir, err)
+		}
+		return &Workspace{Dir: abs, DBPath: filepath.Join(abs, dbName), Source: envDir}, nil
+	}
+
+	return FindWorkspace(start)
+}
+
+// FindWorkspace walks up from start looking for a directory that contains an
+// initialized .beads-lite. The search stops at the first git repository root
+// (a directory containing .git) or at the filesystem root, so a workspace in an
+// unrelated parent project is never picked up by accident. At a git root the
+// choice is made by findAtGitRoot, which shares one workspace across worktrees.
+func FindWorkspace(start string) (*Workspace, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if pathExists(filepath.Join(dir, ".git")) {
+			return findAtGitRoot(dir)
+		}
+
+		if ws := workspaceIn(dir, "discovered"); ws != nil {
+			return ws, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, ErrNotInitialized
+		}
+		dir = parent
+	}
+}
+
+// findAtGitRoot picks the workspace for a git checkout rooted at root. All
+// worktrees of a repository share one issue set, in order of preference:
+//
+//  1. the directory named by the repository's beads-lite.dir git config
+//     (set with "git config beads-lite.dir <path>"; relative paths are
+//     resolved against the main worktree), whether or not it exists yet
+//  2. the .beads-lite in the main worktree
+//  3. the .beads-lite in this linked worktree, if the main one has none
+func findAtGitRoot(root string) (*Workspace, error) {
+	repo, err := inspectGitRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if shared := gitConfigValue(filepath.Join(repo.commonDir, "config"), "beads-lite", "dir"); shared != "" {
+		if !filepath.IsAbs(shared) {
+			base := repo.mainRoot
+			if base == "" {
+				base = repo.commonDir
+			}
+			shared = filepath.Join(base, shared)
+		}
+		return &Workspace{Dir: shared, DBPath: filepath.Join(shared, dbName), Source: "git config beads-lite.dir"}, nil
+	}
+
+	if repo.mainRoot != "" {
+		source := "discovered"
+		if repo.linked {
+			source = "main worktree"
+		}
+		if ws := workspaceIn(repo.mainRoot, source); ws != nil {
+			return ws, nil
+		}
+	}
+
+	if repo.linked {
+		if ws := workspaceIn(root, "discovered"); ws != nil {
+			return ws, nil
+		}
+	}
+	return nil, ErrNotInitialized
+}
+
+// initDir returns the .beads-lite directory that init should create when no
+// workspace is found from start. In a linked worktree that is the one in the
+// main worktree, which every worktree of the repository looks in first, so
+// the new workspace is shared rather than shadowed as soon as the main
+// worktree gets one. Anywhere else it is the .beads-lite in start, returned
+// as the relative beadsDir when start is the working directory.
+func initDir(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if pathExists(filepath.Join(dir, ".git")) {
+			repo, err := inspectGitRoot(dir)
+			if err != nil {
+				return "", err
+			}
+			if repo.linked && repo.mainRoot != "" && pathExists(repo.mainRoot) {
+				return filepath.Join(repo.mainRoot, beadsDir), nil
+			}
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return filepath.Join(start, beadsDir), nil
+}
+
+// workspaceIn returns the initialized workspace directly inside dir, or nil.
+func workspaceIn(dir, source string) *Workspace {
+	candidate := filepath.Join(dir, beadsDir)
+	if !fileExists(filepath.Join(candidate, dbName)) {
+		return nil
+	}
+	return &Workspace{Dir: candidate, DBPath: filepath.Join(candidate, dbName), Source: source}
+}
+
+// gitRepo describes the repository a checkout belongs to.
+type gitRepo struct {
+	commonDir string // the .git directory shared by all worktrees
+	mainRoot  string // the main worktree's root; empty for bare repositories
+	linked    bool   // whether the checkout is a linked worktree
+}
+
+// inspectGitRoot reads the .git entry at root. In the main worktree it is a
+// directory. In a linked worktree it is a file pointing at
+// <common>/.git/worktrees/<name>, which in turn holds a "commondir" file
+// pointing back at the shared .git directory.
+func inspectGitRoot(root string) (*gitRepo, error) {
+	gitPath := filepath.Join(root, ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &gitRepo{commonDir: gitPath, mainRoot: root}, nil
+	}
+
+	data, err := os.ReadFile(gitPath)
+	if err != nil {
+		return nil, err
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return nil, fmt.Errorf("unrecognized .git file in %s", root)
+	}
+	gitDir = strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+
+	// Submodules also use a .git file, but their git dir has no commondir
+	common, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return &gitRepo{commonDir: gitDir, mainRoot: root}, nil
+	}
+	commonDir := strings.TrimSpace(string(common))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(gitDir, commonDir)
+	}
+	commonDir = filepath.Clean(commonDir)
+
+	repo := &gitRepo{commonDir: commonDir, linked: true}
+	if filepath.Base(commonDir) == ".git" {
+		repo.mainRoot = filepath.Dir(commonDir)
+	}
+	return repo, nil
+}
+
+// gitConfigValue returns the value of section.key from a git config file, or
+// "" if the file or key is missing. It understands the subset of the format
+// that "git config section.key value" writes: [section] headers, key = value
+// lines, optional double quotes, and # or ; comments (including trailing ones).
+func gitConfigValue(path, section, key string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	inSection := false
+	value := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			name := strings.TrimSpace(strings.Trim(line, "[]"))
+			inSection = strings.EqualFold(name, section)
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), key) {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if unquoted, err := strconv.Unquote(v); err == nil {
+			v = unquoted
+		} else if i := strings.IndexAny(v, "#;"); i >= 0 {
+			v = strings.TrimSpace(v[:i])
+		}
+		value = v // later entries override earlier ones, as in git
+	}
+	return value
+}
+
+// Exists reports whether the workspace database has been created.
+func (ws *Workspace) Exists() bool {
+	return fileExists(ws.DBPath)
+}
+
+// fileExists reports whether path exists and is not a directory.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// pathExists reports whether anything exists at path.
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// valueFlags are the command flags that take their value as the next
+// argument, as in "--title <text>".
+var valueFlags = map[string]bool{
+	"addr": true, "blocked-by": true, "description": true, "id": true, "id-length": true,
+	"if-version": true, "older-than": true, "parent": true, "prefix": true, "priority": true,
+	"rejects": true, "resolution": true, "since": true, "status": true, "title": true,
+	"type": true, "unblock": true,
+}
+
+// extractGlobalFlags removes flags that apply to every command (currently
+// only --db) from args, before or after the command name, and returns their
+// values. Arguments after "--" and the values of command flags are left
+// alone, so that a title can be "--db".
+func extractGlobalFlags(args []string) (rest []string, dbPath string, err error) {
+	rest = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(rest, args[i:]...), dbPath, nil
+		case arg == "--db":
+			if i+1 >= len(args) {
+				return nil, "", errors.New("flag needs an argument: --db")
+			}
+			dbPath = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--db="):
+			dbPath = strings.TrimPrefix(arg, "--db=")
+		default:
+			rest = append(rest, arg)
+			if name, ok := strings.CutPrefix(arg, "--"); ok && valueFlags[name] && i+1 < len(args) {
+				i++
+				rest = append(rest, args[i])
+			}
+		}
+	}
+	return rest, dbPath, nil
+}
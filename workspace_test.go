@@ -0,0 +1,323 @@
+package beadslite
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// initWorkspace creates an initialized .beads-lite under dir.
+func initWorkspace(t *testing.T, dir string) string {
+	t.Helper()
+	wsDir := filepath.Join(dir, beadsDir)
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	store, err := NewStore(filepath.Join(wsDir, dbName))
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	store.Close()
+	return wsDir
+}
+
+func TestFindWorkspace_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	wsDir := initWorkspace(t, root)
+	nested := filepath.Join(root, "src", "auth")
+	os.MkdirAll(nested, 0755)
+
+	ws, err := FindWorkspace(nested)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if ws.Dir != wsDir {
+		t.Errorf("Dir = %q, want %q", ws.Dir, wsDir)
+	}
+	if ws.Source != "discovered" {
+		t.Errorf("Source = %q, want discovered", ws.Source)
+	}
+}
+
+func TestFindWorkspace_NearestWins(t *testing.T) {
+	root := t.TempDir()
+	initWorkspace(t, root)
+	inner := filepath.Join(root, "service")
+	innerWs := initWorkspace(t, inner)
+
+	ws, err := FindWorkspace(inner)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if ws.Dir != innerWs {
+		t.Errorf("Dir = %q, want nearest %q", ws.Dir, innerWs)
+	}
+}
+
+func TestFindWorkspace_StopsAtGitRoot(t *testing.T) {
+	root := t.TempDir()
+	initWorkspace(t, root)
+
+	// A git repo nested below the workspace is a separate project
+	repo := filepath.Join(root, "vendor", "lib")
+	os.MkdirAll(filepath.Join(repo, ".git"), 0755)
+	nested := filepath.Join(repo, "pkg")
+	os.MkdirAll(nested, 0755)
+
+	_, err := FindWorkspace(nested)
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("expected ErrNotInitialized, got %v", err)
+	}
+}
+
+func TestFindWorkspace_IgnoresEmptyDir(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, beadsDir), 0755) // no database inside
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+
+	_, err := FindWorkspace(root)
+	if !errors.Is(err, ErrNotInitialized) {
+		t.Errorf("expected ErrNotInitialized, got %v", err)
+	}
+}
+
+func TestResolveWorkspace_Overrides(t *testing.T) {
+	root := t.TempDir()
+	initWorkspace(t, root)
+	other := t.TempDir()
+
+	t.Setenv(envDir, filepath.Join(other, "shared"))
+	ws, err := ResolveWorkspace(root, "")
+	if err != nil {
+		t.Fatalf("ResolveWorkspace: %v", err)
+	}
+	if ws.Source != envDir || ws.Dir != filepath.Join(other, "shared") {
+		t.Errorf("BL_DIR should win over discovery, got %+v", ws)
+	}
+
+	dbPath := filepath.Join(other, "explicit.db")
+	ws, err = ResolveWorkspace(root, dbPath)
+	if err != nil {
+		t.Fatalf("ResolveWorkspace: %v", err)
+	}
+	if ws.Source != "--db" || ws.DBPath != dbPath {
+		t.Errorf("--db should win over BL_DIR, got %+v", ws)
+	}
+}
+
+func TestExtractGlobalFlags(t *testing.T) {
+	rest, db, err := extractGlobalFlags([]string{"ready", "--db", "x.db", "--json"})
+	if err != nil {
+		t.Fatalf("extractGlobalFlags: %v", err)
+	}
+	if db != "x.db" || strings.Join(rest, " ") != "ready --json" {
+		t.Errorf("got rest=%v db=%q", rest, db)
+	}
+
+	rest, db, _ = extractGlobalFlags([]string{"--db=y.db", "list"})
+	if db != "y.db" || strings.Join(rest, " ") != "list" {
+		t.Errorf("got rest=%v db=%q", rest, db)
+	}
+
+	if _, _, err := extractGlobalFlags([]string{"list", "--db"}); err == nil {
+		t.Error("--db without a value should fail")
+	}
+
+	// Arguments after -- and flag values are not flags
+	rest, db, _ = extractGlobalFlags([]string{"create", "--", "--db", "x.db"})
+	if db != "" || strings.Join(rest, " ") != "create -- --db x.db" {
+		t.Errorf("got rest=%v db=%q", rest, db)
+	}
+	rest, db, _ = extractGlobalFlags([]string{"update", "bl-a1b2", "--title", "--db", "--db", "x.db"})
+	if db != "x.db" || strings.Join(rest, " ") != "update bl-a1b2 --title --db" {
+		t.Errorf("got rest=%v db=%q", rest, db)
+	}
+}
+
+func TestValueFlagsMatchHelp(t *testing.T) {
+	var help strings.Builder
+	printHelp(&help)
+
+	documented := make(map[string]bool)
+	for _, m := range regexp.MustCompile(`--([a-z-]+) <`).FindAllStringSubmatch(help.String(), -1) {
+		documented[m[1]] = true
+	}
+	delete(documented, "db")
+	for name := range documented {
+		if !valueFlags[name] {
+			t.Errorf("--%s takes a value but is missing from valueFlags", name)
+		}
+	}
+	for name := range valueFlags {
+		if !documented[name] {
+			t.Errorf("valueFlags lists --%s, which help does not document with a value", name)
+		}
+	}
+}
+
+// gitRun runs git in dir, skipping the test if git is not installed.
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+// newRepoWithWorktree creates a git repository with one commit and a linked
+// worktree, returning the main and worktree roots.
+func newRepoWithWorktree(t *testing.T) (mainRoot, worktree string) {
+	t.Helper()
+	base := t.TempDir()
+	mainRoot = filepath.Join(base, "main")
+	worktree = filepath.Join(base, "feature")
+	os.MkdirAll(mainRoot, 0755)
+
+	gitRun(t, mainRoot, "init", "-q")
+	gitRun(t, mainRoot, "commit", "-q", "--allow-empty", "-m", "initial")
+	gitRun(t, mainRoot, "worktree", "add", "-q", worktree)
+	return mainRoot, worktree
+}
+
+func TestFindWorkspace_WorktreeUsesMainRepo(t *testing.T) {
+	mainRoot, worktree := newRepoWithWorktree(t)
+	wsDir := initWorkspace(t, mainRoot)
+
+	nested := filepath.Join(worktree, "src")
+	os.MkdirAll(nested, 0755)
+
+	ws, err := FindWorkspace(nested)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if ws.Dir != wsDir {
+		t.Errorf("Dir = %q, want main repo workspace %q", ws.Dir, wsDir)
+	}
+	if ws.Source != "main worktree" {
+		t.Errorf("Source = %q, want main worktree", ws.Source)
+	}
+}
+
+func TestFindWorkspace_WorktreeFallsBackToLocal(t *testing.T) {
+	_, worktree := newRepoWithWorktree(t)
+	wsDir := initWorkspace(t, worktree)
+
+	ws, err := FindWorkspace(worktree)
+	if err != nil {
+		t.Fatalf("FindWorkspace: %v", err)
+	}
+	if ws.Dir != wsDir {
+		t.Errorf("Dir = %q, want worktree workspace %q", ws.Dir, wsDir)
+	}
+}
+
+func TestFindWorkspace_SharedDirFromGitConfig(t *testing.T) {
+	mainRoot, worktree := newRepoWithWorktree(t)
+	initWorkspace(t, mainRoot)
+	gitRun(t, mainRoot, "config", "beads-lite.dir", "../shared-beads")
+
+	want := filepath.Join(filepath.Dir(mainRoot), "shared-beads")
+	for _, dir := range []string{mainRoot, worktree} {
+		ws, err := FindWorkspace(dir)
+		if err != nil {
+			t.Fatalf("FindWorkspace(%s): %v", dir, err)
+		}
+		if ws.Dir != want {
+			t.Errorf("FindWorkspace(%s).Dir = %q, want %q", dir, ws.Dir, want)
+		}
+	}
+}
+
+func TestCLI_Worktree_SharedIssues(t *testing.T) {
+	mainRoot, worktree := newRepoWithWorktree(t)
+	oldDir, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(oldDir) })
+
+	os.Chdir(mainRoot)
+	runCLI([]string{"init"})
+	createOut, _ := runCLI([]string{"create", "Shared Task"})
+	id := extractID(createOut)
+
+	// Another agent in the worktree sees and claims the same issue
+	os.Chdir(worktree)
+	readyOut, err := runCLI([]string{"ready"})
+	if err != nil {
+		t.Fatalf("ready in worktree failed: %v", err)
+	}
+	if !strings.Contains(readyOut, "Shared Task") {
+		t.Fatalf("worktree should see main repo issues: %s", readyOut)
+	}
+	if _, err := runCLI([]string{"update", id, "--status", "in_progress"}); err != nil {
+		t.Fatalf("claim from worktree failed: %v", err)
+	}
+
+	os.Chdir(mainRoot)
+	showOut, _ := runCLI([]string{"show", id})
+	if !strings.Contains(showOut, "in_progress") {
+		t.Errorf("claim from worktree should be visible in main: %s", showOut)
+	}
+}
+
+func TestCLI_Init_InWorktree(t *testing.T) {
+	mainRoot, worktree := newRepoWithWorktree(t)
+	oldDir, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(oldDir) })
+	want := filepath.Join(mainRoot, beadsDir)
+
+	// A fresh linked worktree creates the workspace every worktree shares
+	os.Chdir(worktree)
+	out, err := runCLI([]string{"init"})
+	if err != nil {
+		t.Fatalf("init in worktree failed: %v", err)
+	}
+	if !strings.Contains(out, want) {
+		t.Errorf("init should report the main worktree's workspace %s: %s", want, out)
+	}
+	if !fileExists(filepath.Join(want, dbName)) || pathExists(filepath.Join(worktree, beadsDir)) {
+		t.Fatalf("init should create %s and nothing in the worktree", want)
+	}
+
+	// Once the main worktree has one, init reinitializes it
+	createOut, _ := runCLI([]string{"create", "Shared Task"})
+	out, err = runCLI([]string{"init", "--prefix", "wt"})
+	if err != nil {
+		t.Fatalf("second init in worktree failed: %v", err)
+	}
+	if !strings.Contains(out, want) || pathExists(filepath.Join(worktree, beadsDir)) {
+		t.Errorf("init should reuse %s: %s", want, out)
+	}
+
+	os.Chdir(mainRoot)
+	if showOut, err := runCLI([]string{"show", extractID(createOut)}); err != nil || !strings.Contains(showOut, "Shared Task") {
+		t.Errorf("issue created from the worktree should be in main: %s, %v", showOut, err)
+	}
+}
+
+func TestGitConfigValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(path, []byte(`[core]
+	dir = wrong
+[beads-lite]
+	# comment
+	Dir = "/first"
+	dir = /second ; trailing comment
+[other]
+	dir = nope
+`), 0644)
+
+	got := gitConfigValue(path, "beads-lite", "dir")
+	if got != "/second" {
+		t.Errorf("gitConfigValue = %q", got)
+	}
+	if gitConfigValue(path, "missing", "dir") != "" {
+		t.Error("missing section should give empty value")
+	}
+}